@@ -0,0 +1,115 @@
+// gazallocsim simulates allocation of items (eg, journals) across a cluster
+// of members (eg, brokers) entirely in memory, for offline capacity planning.
+// A scripted sequence of member joins and leaves is applied, and after each
+// step the allocation is converged and a report written to stdout:
+//
+//	gazallocsim -items 500 -replicas 1 -steps join:4,join:1,leave:2
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/consensus"
+)
+
+var (
+	numItems  = flag.Int("items", 100, "Number of allocated items")
+	replicas  = flag.Int("replicas", 1, "Number of required item replicas")
+	steps     = flag.String("steps", "join:3", "Comma-separated steps, each of which joins (join:N) or leaves (leave:N) N members")
+	seed      = flag.Int64("seed", 1, "Seed of random allocation choices")
+	maxRounds = flag.Int("maxRounds", 100000, "Maximum rounds of allocation per step")
+)
+
+type step struct {
+	join bool
+	n    int
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+	flag.Parse()
+
+	var script, err = parseSteps(*steps)
+	if err != nil {
+		log.WithField("err", err).Fatal("invalid -steps")
+	}
+
+	var items []string
+	for i := 0; i != *numItems; i++ {
+		items = append(items, fmt.Sprintf("item-%06d", i))
+	}
+	var sim = consensus.NewSimulation(items, *replicas, *seed)
+	var members []string // Current members, in join order.
+	var joined int
+
+	for i, s := range script {
+		if s.join {
+			for j := 0; j != s.n; j++ {
+				var key = fmt.Sprintf("member-%04d", joined)
+				if err = sim.Join(key); err != nil {
+					log.WithField("err", err).Fatal("join failed")
+				}
+				members, joined = append(members, key), joined+1
+			}
+		} else {
+			if s.n > len(members) {
+				log.WithFields(log.Fields{"step": i, "members": len(members)}).
+					Fatal("leaving more members than have joined")
+			}
+			// Members leave in the order they joined.
+			if err = sim.Leave(members[:s.n]...); err != nil {
+				log.WithField("err", err).Fatal("leave failed")
+			}
+			members = members[s.n:]
+		}
+
+		report, err := sim.Converge(*maxRounds)
+		fmt.Printf("step %d (%s): members=%d rounds=%d churn=%d maxLoadRatio=%.4f "+
+			"unmastered=%d underReplicated=%d actions=%v\n",
+			i, s, report.Members, report.Rounds, report.Churn, report.MaxLoadRatio,
+			report.Unmastered, report.UnderReplicated, report.Actions)
+
+		if err != nil {
+			log.WithFields(log.Fields{"step": i, "err": err}).Fatal("simulation failed")
+		}
+	}
+}
+
+func (s step) String() string {
+	if s.join {
+		return "join:" + strconv.Itoa(s.n)
+	}
+	return "leave:" + strconv.Itoa(s.n)
+}
+
+// parseSteps parses a comma-separated script of steps.
+func parseSteps(script string) ([]step, error) {
+	var out []step
+
+	for _, field := range strings.Split(script, ",") {
+		var parts = strings.Split(strings.TrimSpace(field), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected join:N or leave:N (got %q)", field)
+		}
+		var n, err = strconv.Atoi(parts[1])
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid member count %q", parts[1])
+		}
+
+		switch parts[0] {
+		case "join":
+			out = append(out, step{join: true, n: n})
+		case "leave":
+			out = append(out, step{join: false, n: n})
+		default:
+			return nil, fmt.Errorf("unknown step %q", parts[0])
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	gc "github.com/go-check/check"
+)
+
+type StepsSuite struct{}
+
+func (s *StepsSuite) TestParsing(c *gc.C) {
+	var out, err = parseSteps("join:3, leave:1,join:2")
+	c.Check(err, gc.IsNil)
+	c.Check(out, gc.DeepEquals, []step{{true, 3}, {false, 1}, {true, 2}})
+
+	for _, tc := range []struct{ script, expect string }{
+		{"join", `expected join:N or leave:N \(got "join"\)`},
+		{"join:0", `invalid member count "0"`},
+		{"join:x", `invalid member count "x"`},
+		{"grow:2", `unknown step "grow"`},
+	} {
+		_, err = parseSteps(tc.script)
+		c.Check(err, gc.ErrorMatches, tc.expect)
+	}
+}
+
+var _ = gc.Suite(&StepsSuite{})
+
+func Test(t *testing.T) { gc.TestingT(t) }
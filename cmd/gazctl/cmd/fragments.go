@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+var fragmentsCmd = &cobra.Command{
+	Use:   "fragments [journal name] [journal name] ...",
+	Short: "List persisted fragments of gazette journals",
+	Long: `List fragments of gazette journals which have been persisted to cloud
+storage, as JSON. Fragments covering the byte-offset range [--offset, --end)
+are listed, where an --end of -1 (the default) lists all persisted fragments.
+
+Example: gazctl fragments examples/a-journal/one --offset 1234`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			log.Fatal("invalid arguments")
+		}
+
+		var out = json.NewEncoder(os.Stdout)
+
+		for _, name := range args {
+			var fragments, err = gazetteClient().FragmentsInRange(
+				journal.Name(name), fragmentsOffset, fragmentsEnd)
+			if err != nil {
+				log.WithFields(log.Fields{"err": err, "name": name}).Fatal("failed to list fragments")
+			}
+
+			for _, f := range fragments {
+				if err := out.Encode(struct {
+					Journal       journal.Name
+					Begin, End    int64
+					ContentName   string
+					RemoteModTime time.Time
+				}{journal.Name(name), f.Begin, f.End, f.ContentName(), f.RemoteModTime}); err != nil {
+					log.WithFields(log.Fields{"err": err, "name": name}).Fatal("failed to write fragment output")
+				}
+			}
+		}
+	},
+}
+
+var fragmentsOffset, fragmentsEnd int64
+
+func init() {
+	rootCmd.AddCommand(fragmentsCmd)
+
+	fragmentsCmd.Flags().Int64VarP(&fragmentsOffset, "offset", "c", 0,
+		"Byte offset to begin listing fragments from")
+	fragmentsCmd.Flags().Int64VarP(&fragmentsEnd, "end", "e", -1,
+		"Byte offset to end listing fragments at, or -1 to list all persisted fragments")
+}
@@ -25,7 +25,11 @@ This reads journal content from byte-offset zero, through to the current write h
 
 Example: gazctl cat examples/a-journal --offset 1234 --block 1m
 This reads journal content from byte-offset 1234, and blocks one minute to read
-new content as it is appended.`,
+new content as it is appended.
+
+Example: gazctl cat examples/a-journal --since 2h
+This reads journal content beginning with the first fragment persisted within
+the last two hours.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			cmd.Usage()
@@ -37,6 +41,15 @@ new content as it is appended.`,
 			Offset:  readOffset,
 		}
 
+		if readSince != 0 {
+			var fragment, err = gazetteClient().FragmentBeforeTime(mark.Journal, time.Now().Add(-readSince))
+			if err != nil {
+				log.WithField("err", err).Fatal("failed to locate fragment by time")
+			}
+			// |fragment| (if any) was persisted prior to the desired time.
+			mark.Offset = fragment.End
+		}
+
 		var ctx = context.Background()
 		if readTimeout != 0 {
 			ctx, _ = context.WithTimeout(ctx, readTimeout)
@@ -58,6 +71,7 @@ new content as it is appended.`,
 var (
 	readOffset  int64
 	readTimeout time.Duration
+	readSince   time.Duration
 )
 
 func init() {
@@ -67,4 +81,6 @@ func init() {
 		"Byte offset to begin reading from, or -1 for the current write-head")
 	readCmd.Flags().DurationVarP(&readTimeout, "block", "t", 0,
 		"Total duration to block for, reading ongoing journal appends. Zero (default) does not block")
+	readCmd.Flags().DurationVarP(&readSince, "since", "s", 0,
+		"Begin reading from content persisted within this duration of now, rather than --offset")
 }
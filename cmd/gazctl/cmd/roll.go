@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+var rollCmd = &cobra.Command{
+	Use:   "roll [journal name] [journal name] ...",
+	Short: "Roll the spools of gazette journals",
+	Long: `Roll the spools of gazette journals, causing content written to date to be
+persisted to the fragment store. This is useful to ensure written content is
+in cloud storage prior to a planned broker decommission.
+
+Example: gazctl roll examples/a-journal/one examples/a-journal/two`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			log.Fatal("invalid arguments")
+		}
+
+		for _, name := range args {
+			var result = gazetteClient().Roll(journal.Name(name))
+
+			// The journal broker is now cached by the client. Retry against it.
+			if journal.RetryabilityOf(result.Error) == journal.RetryAfterReroute {
+				result = gazetteClient().Roll(journal.Name(name))
+			}
+			if result.Error != nil {
+				log.WithFields(log.Fields{"err": result.Error, "name": name}).Fatal("failed to roll journal")
+			}
+			log.WithFields(log.Fields{"name": name, "writeHead": result.WriteHead}).Info("rolled journal")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollCmd)
+}
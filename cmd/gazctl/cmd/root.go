@@ -101,6 +101,9 @@ func gazetteClient() *gazette.Client {
 		if err != nil {
 			log.WithField("err", err).Fatal("building gazette client")
 		}
+		// Optionally verify fragment content read via the client (see
+		// gazette.Client.SetVerifySums).
+		lazyGazetteClient.SetVerifySums(viper.GetBool("gazette.verifySums"))
 	}
 	return lazyGazetteClient
 }
@@ -2,8 +2,10 @@ package main
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"flag"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -21,6 +23,7 @@ import (
 	"google.golang.org/api/gensupport"
 
 	"github.com/LiveRamp/gazette/pkg/cloudstore"
+	"github.com/LiveRamp/gazette/pkg/envflag"
 	"github.com/LiveRamp/gazette/pkg/envflagfactory"
 	"github.com/LiveRamp/gazette/pkg/gazette"
 	"github.com/LiveRamp/gazette/pkg/journal"
@@ -33,7 +36,68 @@ var (
 	spoolDirectory = flag.String("spoolDir", "/var/tmp/gazette",
 		"Local directory for journal spools")
 
+	etcdRoot = flag.String("etcdRoot", gazette.ServiceRoot, "Etcd root of the Gazette "+
+		"cluster. Independent clusters sharing an Etcd must use distinct, non-nesting roots")
 	replicaCount = flag.Int("replicaCount", 2, "Number of required journal replicas")
+
+	tlsCert = flag.String("tlsCert", "",
+		"Path to PEM-encoded server certificate. If set, the broker serves HTTPS")
+	tlsKey = flag.String("tlsKey", "", "Path to PEM-encoded server private key")
+	tlsCA  = flag.String("tlsCA", "", "Path to PEM-encoded CA bundle. If set, "+
+		"clients and peers must present a certificate signed by it (mutual TLS), "+
+		"and peers are verified against it")
+
+	readTokenKey = flag.String("readTokenKey", "", "Path to a secret key for signing "+
+		"read tokens. If set, readers not presenting a client certificate must "+
+		"present a read token, and clients presenting one may issue tokens")
+	readTokenTTL = flag.Duration("readTokenTTL", time.Hour, "Maximum lifetime of issued read tokens")
+
+	replicaShutdownGrace = flag.Duration("replicaShutdownGrace", 30*time.Second, "Period for "+
+		"which a replica removed from its journal's route is retained, in case it's restored")
+
+	maxInFlightAppends = flag.Int("maxInFlightAppends", 0, "Maximum number of appends of a "+
+		"journal which may be in flight at once. Zero is unbounded")
+
+	appendIdleTimeout = flag.Duration("appendIdleTimeout", time.Minute, "Maximum duration an "+
+		"append may await further content from its client before it's aborted. Zero is unbounded")
+
+	appendDedupWindow = flag.Int("appendDedupWindow", 0, "Number of recent committed appends "+
+		"remembered by producer and checksum, so that a client's retry of an append which "+
+		"already committed is not appended twice. Zero disables")
+
+	spoolDiskBudget = flag.Int64("spoolDiskBudget", 0, "Bytes of completed spools which may "+
+		"await persistence. When exceeded, persistence is expedited and appends are delayed "+
+		"by up to a second, increasingly as the backlog nears -spoolDiskLimit. Zero is unbounded")
+	spoolDiskLimit = flag.Int64("spoolDiskLimit", 0, "Maximum bytes of completed spools "+
+		"which may await persistence. When exceeded, the broker is read-only (rejecting appends) "+
+		"until the backlog drains to -spoolDiskBudget. Zero never rejects appends")
+
+	replicateCompression = flag.Bool("replicateCompression", false, "Compress transaction "+
+		"content replicated to peer brokers, reducing inter-zone bandwidth at some CPU cost. "+
+		"Enable only once all brokers of the cluster support it")
+
+	profileCaptureURL = flag.String("profileCaptureURL", "", "Cloud filesystem URL to which "+
+		"profiles captured via POST "+gazette.ProfileCapturePath+" (on the metrics port) are "+
+		"written, beneath a directory of the broker's route. It must be outside of the "+
+		"fragment store. Empty disables captures")
+
+	maxIndexedFragments = flag.Int("maxIndexedFragments", 0, "Maximum number of fragments "+
+		"indexed in memory for each journal. Beyond it, the oldest persisted fragments are "+
+		"dropped from the index, and their reads list the fragment store. Zero is unbounded")
+	maxConcurrentIndexRefreshes = flag.Int("maxConcurrentIndexRefreshes", 16, "Maximum number "+
+		"of journal indexes which may be refreshed from the fragment store at once. Zero is unbounded")
+
+	idleJournalThreshold = flag.Duration("idleJournalThreshold", time.Hour, "Duration after "+
+		"which a brokered journal which hasn't been appended to is reported as idle")
+
+	unixSocket = flag.String("unixSocket", "", "If set, path of a unix socket on which to "+
+		"additionally serve, for co-located clients using a unix:// endpoint")
+
+	accessLogSampleRate = flag.Float64("accessLogSampleRate", 0, "Probability with which "+
+		"a served request is logged. Failed requests are always logged")
+	slowRequestThreshold = flag.Duration("slowRequestThreshold", 0, "Duration after which "+
+		"a served request is always logged. As blocking reads are long-lived, this is "+
+		"generally useful only with readers which don't block. Zero disables")
 )
 
 // In order for a brokered Journal to be handed off, it must have regular
@@ -47,26 +111,48 @@ func main() {
 
 	var etcdEndpoint = envflagfactory.NewEtcdServiceEndpoint()
 	var cloudFSURL = envflagfactory.NewCloudFSURL()
+	// POD_IP is conventionally populated by the Kubernetes downward API.
+	var advertiseHost = envflag.CommandLine.String("advertiseHost", "POD_IP", "",
+		"Host or IP advertised in journal routes, by which peers and clients reach this broker. "+
+			"If empty, the first non-loopback interface IP is used")
 
 	mainboilerplate.Initialize()
 
 	prometheus.MustRegister(metrics.GazetteCollectors()...)
 	gensupport.RegisterHook(traceRequests)
 
+	var scheme = "http"
+	if *tlsCert != "" {
+		scheme = "https"
+	}
+
 	var localRoute string
-	if ip, err := routableIP(); err != nil {
+	if *advertiseHost != "" {
+		localRoute = url.QueryEscape(scheme + "://" + net.JoinHostPort(*advertiseHost, "8081"))
+	} else if ip, err := routableIP(); err != nil {
 		log.WithField("err", err).Fatal("failed to acquire routable IP")
 	} else {
-		localRoute = url.QueryEscape("http://" + ip.String() + ":8081")
+		localRoute = url.QueryEscape(scheme + "://" + ip.String() + ":8081")
 	}
 
 	log.WithFields(log.Fields{
 		"spoolDir":     *spoolDirectory,
 		"replicaCount": *replicaCount,
 		"etcdEndpoint": *etcdEndpoint,
+		"etcdRoot":     *etcdRoot,
 		"localRoute":   localRoute,
 	}).Info("flag configuration")
 
+	if err := gazette.ValidateServiceRoot(*etcdRoot); err != nil {
+		log.WithField("err", err).Fatal("invalid etcdRoot")
+	}
+	// Read tokens are required only of readers lacking a verified client
+	// certificate. Without mutual TLS, no reader (including peers and trusted
+	// clients) could present one.
+	if *readTokenKey != "" && *tlsCA == "" {
+		log.Fatal("readTokenKey requires tlsCA")
+	}
+
 	// Fail fast if spool directory cannot be created.
 	if err := os.MkdirAll(filepath.Dir(*spoolDirectory), 0700); err != nil {
 		log.WithField("err", err).Fatal("failed to create spool directory")
@@ -83,12 +169,40 @@ func main() {
 	if err != nil {
 		log.WithField("err", err).Fatal("failed to initialize cloudstore")
 	}
+	if *profileCaptureURL != "" {
+		profileCFS, err := cloudstore.NewFileSystem(nil, *profileCaptureURL)
+		if err != nil {
+			log.WithField("err", err).Fatal("failed to initialize profile cloudstore")
+		}
+		// Served with net/http/pprof handlers on the metrics port.
+		http.Handle(gazette.ProfileCapturePath, gazette.NewProfileAPI(profileCFS, localRoute))
+	}
 	listener, err := net.Listen("tcp", ":8081")
 	if err != nil {
 		log.WithField("err", err).Fatal("failed to bind listener")
 	}
+	// Appends are bounded in the time they may await further content, through
+	// read deadlines of their underlying connections.
+	var idleConns = gazette.NewDeadlineListener(keepalive.TCPListener{listener.(*net.TCPListener)})
+	var serveListener net.Listener = idleConns
+	var peerTLSConfig *tls.Config
+
+	if *tlsCert != "" {
+		serverCfg, err := gazette.NewServerTLSConfig(*tlsCert, *tlsKey, *tlsCA)
+		if err != nil {
+			log.WithField("err", err).Fatal("failed to load server TLS config")
+		}
+		serveListener = tls.NewListener(serveListener, serverCfg)
+
+		// Present our server certificate to peers, and verify theirs.
+		if peerTLSConfig, err = gazette.NewClientTLSConfig(*tlsCert, *tlsKey, *tlsCA); err != nil {
+			log.WithField("err", err).Fatal("failed to load peer TLS config")
+		}
+	}
 
 	persister := gazette.NewPersister(*spoolDirectory, cfs, keysAPI, localRoute)
+	persister.SetServiceRoot(*etcdRoot)
+	persister.SetDiskBudget(*spoolDiskBudget, *spoolDiskLimit)
 	persister.StartPersisting()
 
 	for _, fragment := range journal.LocalFragments(*spoolDirectory, "") {
@@ -96,11 +210,18 @@ func main() {
 		persister.Persist(fragment)
 	}
 
+	// Index refreshes of all journals share a bound on concurrent listings.
+	var indexRefreshes = journal.NewIndexRefreshLimiter(*maxConcurrentIndexRefreshes)
+
 	var router = gazette.NewRouter(
 		func(n journal.Name) gazette.JournalReplica {
-			return journal.NewReplica(n, *spoolDirectory, persister, cfs)
+			return journal.NewReplica(n, *spoolDirectory, persister, cfs, *maxIndexedFragments, indexRefreshes)
 		},
 	)
+	router.SetShutdownGracePeriod(*replicaShutdownGrace)
+	router.SetIdleJournalThreshold(*idleJournalThreshold)
+	router.SetPeerTLSConfig(peerTLSConfig)
+	router.SetPeerCompression(*replicateCompression)
 
 	// Run regular broker commit "pulses".
 	go func() {
@@ -125,14 +246,44 @@ func main() {
 		}
 	}()
 
+	var runner = gazette.NewRunner(etcdClient, localRoute, *replicaCount, router)
+	runner.SetServiceRoot(*etcdRoot)
+
 	var m = mux.NewRouter()
-	gazette.NewCreateAPI(cfs, keysAPI, *replicaCount).Register(m)
-	gazette.NewReadAPI(router, cfs).Register(m)
+	var readAPI = gazette.NewReadAPI(router, cfs)
+
+	gazette.NewDebugAPI(runner).Register(m)
+
+	if *readTokenKey != "" {
+		key, err := ioutil.ReadFile(*readTokenKey)
+		if err != nil {
+			log.WithField("err", err).Fatal("failed to read token key")
+		}
+		var signer = gazette.NewReadTokenSigner(key)
+
+		gazette.NewReadTokenAPI(signer, *readTokenTTL).Register(m)
+		readAPI.RequireReadTokens(signer)
+	}
+	var createAPI = gazette.NewCreateAPI(cfs, keysAPI, *replicaCount)
+	createAPI.SetServiceRoot(*etcdRoot)
+	createAPI.Register(m)
+	readAPI.Register(m)
 	gazette.NewReplicateAPI(router).Register(m)
-	gazette.NewWriteAPI(router).Register(m)
+	gazette.NewRollAPI(router).Register(m)
+
+	var writeAPI = gazette.NewWriteAPI(router)
+	writeAPI.SetIdleTimeout(*appendIdleTimeout, idleConns)
+	writeAPI.SetMaxInFlight(*maxInFlightAppends)
+	writeAPI.SetDedupWindow(*appendDedupWindow)
+	writeAPI.SetAdmitter(persister.AppendAdmitted)
+	writeAPI.Register(m)
+
+	var accessLog = gazette.NewAccessLog(m)
+	accessLog.SetSampleRate(*accessLogSampleRate)
+	accessLog.SetSlowThreshold(*slowRequestThreshold)
 
 	go func() {
-		err := http.Serve(keepalive.TCPListener{listener.(*net.TCPListener)}, m)
+		err := http.Serve(serveListener, accessLog)
 
 		if _, ok := err.(net.Error); ok {
 			return // Don't log on listener.Close.
@@ -140,7 +291,25 @@ func main() {
 		log.WithField("err", err).Error("http.Serve failed")
 	}()
 
-	var runner = gazette.NewRunner(etcdClient, localRoute, *replicaCount, router)
+	if *unixSocket != "" {
+		os.Remove(*unixSocket) // Remove a socket left by a prior process, if any.
+
+		unixListener, err := net.Listen("unix", *unixSocket)
+		if err != nil {
+			log.WithField("err", err).Fatal("failed to bind unix socket listener")
+		}
+		defer unixListener.Close()
+
+		go func() {
+			err := http.Serve(unixListener, accessLog)
+
+			if _, ok := err.(net.Error); ok {
+				return // Don't log on listener.Close.
+			}
+			log.WithField("err", err).Error("http.Serve of unix socket failed")
+		}()
+	}
+
 	if err := runner.Run(); err != nil {
 		log.WithField("err", err).Error("runner.Run() failed")
 	}
@@ -0,0 +1,171 @@
+// gazloadgen drives a load of concurrent appenders and readers against a
+// Gazette cluster for a fixed duration, and reports append throughput and
+// latency, read throughput, and client allocations to stdout:
+//
+//	gazloadgen -journals 4 -appenders 16 -readers 4 -size 4096 -duration 1m
+//
+// Journals are named by -prefix, and are created if they don't exist.
+// Appenders are assigned to journals round-robin, as are readers, which tail
+// journals from their write head as of startup. See also the benchmarks of
+// package gazette, which apply a similar load to an in-process broker.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/envflag"
+	"github.com/LiveRamp/gazette/pkg/envflagfactory"
+	"github.com/LiveRamp/gazette/pkg/gazette"
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+var (
+	prefix      = flag.String("prefix", "loadgen/journal", "Prefix of generated journal names")
+	numJournals = flag.Int("journals", 4, "Number of journals to load")
+	appenders   = flag.Int("appenders", 8, "Number of concurrent appenders")
+	readers     = flag.Int("readers", 4, "Number of concurrent readers")
+	size        = flag.Int("size", 4096, "Size of each append, in bytes")
+	duration    = flag.Duration("duration", 30*time.Second, "Duration of the load")
+)
+
+// Delay of an appender after a failed append.
+const failureCoolOff = 100 * time.Millisecond
+
+func main() {
+	var gazetteEndpoint = envflagfactory.NewGazetteServiceEndpoint()
+
+	log.SetOutput(os.Stderr)
+	envflag.CommandLine.Parse()
+	flag.Parse()
+
+	if *numJournals <= 0 || *appenders < 0 || *readers < 0 || *size <= 0 {
+		log.Fatal("-journals and -size must be positive, and -appenders and -readers non-negative")
+	}
+
+	client, err := gazette.NewClient(*gazetteEndpoint)
+	if err != nil {
+		log.WithField("err", err).Fatal("failed to connect to gazette")
+	}
+
+	var journals []journal.Name
+	for i := 0; i != *numJournals; i++ {
+		var name = journal.Name(fmt.Sprintf("%s-%03d", *prefix, i))
+
+		if err := client.Create(name); err != nil && err != journal.ErrExists {
+			log.WithFields(log.Fields{"journal": name, "err": err}).Fatal("failed to create journal")
+		}
+		journals = append(journals, name)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		lat      = make([][]time.Duration, *appenders)
+		failed   int64
+		read     int64
+		content  = bytes.Repeat([]byte("x"), *size)
+		memStart runtime.MemStats
+		memEnd   runtime.MemStats
+	)
+	runtime.ReadMemStats(&memStart)
+	var started = time.Now()
+
+	for i := 0; i != *readers; i++ {
+		wg.Add(1)
+
+		go func(name journal.Name) {
+			defer wg.Done()
+
+			var rr = journal.NewRetryReaderContext(ctx, journal.Mark{Journal: name, Offset: -1}, client)
+			defer rr.Close()
+
+			io.Copy(countingWriter{&read}, rr)
+		}(journals[i%len(journals)])
+	}
+	for i := 0; i != *appenders; i++ {
+		wg.Add(1)
+
+		go func(i int, name journal.Name) {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				var begin = time.Now()
+				var result = client.Put(journal.AppendArgs{
+					Journal: name,
+					Content: bytes.NewReader(content),
+					Context: ctx,
+				})
+
+				if result.Error != nil {
+					if ctx.Err() == nil {
+						log.WithFields(log.Fields{"journal": name, "err": result.Error}).Warn("append failed")
+						atomic.AddInt64(&failed, 1)
+						time.Sleep(failureCoolOff)
+					}
+					continue
+				}
+				lat[i] = append(lat[i], time.Since(begin))
+			}
+		}(i, journals[i%len(journals)])
+	}
+	wg.Wait()
+
+	var elapsed = time.Since(started)
+	runtime.ReadMemStats(&memEnd)
+
+	var all []time.Duration
+	for _, l := range lat {
+		all = append(all, l...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	var seconds = elapsed.Seconds()
+	var appended = float64(len(all) * *size)
+
+	fmt.Printf("elapsed:        %s\n", elapsed)
+	fmt.Printf("appends:        %d (%.1f/s, %d failed)\n", len(all), float64(len(all))/seconds, failed)
+	fmt.Printf("append rate:    %.2f MB/s\n", appended/seconds/(1<<20))
+	fmt.Printf("append latency: p50 %s, p99 %s, max %s\n",
+		percentile(all, 50), percentile(all, 99), percentile(all, 100))
+	fmt.Printf("read rate:      %.2f MB/s\n", float64(read)/seconds/(1<<20))
+
+	if n := uint64(len(all)); n != 0 {
+		fmt.Printf("allocs/append:  %d (%d B)\n",
+			(memEnd.Mallocs-memStart.Mallocs)/n, (memEnd.TotalAlloc-memStart.TotalAlloc)/n)
+	}
+}
+
+// percentile returns the |p|th percentile of ascending |sorted|, or zero if
+// |sorted| is empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	var ind = len(sorted) * p / 100
+	if ind == len(sorted) {
+		ind--
+	}
+	return sorted[ind]
+}
+
+// countingWriter discards written content, while counting its bytes.
+type countingWriter struct{ n *int64 }
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	atomic.AddInt64(w.n, int64(len(p)))
+	return len(p), nil
+}
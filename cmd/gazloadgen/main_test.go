@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	gc "github.com/go-check/check"
+)
+
+type LoadGenSuite struct{}
+
+func (s *LoadGenSuite) TestPercentile(c *gc.C) {
+	var sorted []time.Duration
+	for i := 1; i <= 200; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	c.Check(percentile(sorted, 0), gc.Equals, time.Duration(1))
+	c.Check(percentile(sorted, 50), gc.Equals, time.Duration(101))
+	c.Check(percentile(sorted, 99), gc.Equals, time.Duration(199))
+	c.Check(percentile(sorted, 100), gc.Equals, time.Duration(200))
+
+	c.Check(percentile(nil, 99), gc.Equals, time.Duration(0))
+}
+
+var _ = gc.Suite(&LoadGenSuite{})
+
+func Test(t *testing.T) { gc.TestingT(t) }
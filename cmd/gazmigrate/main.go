@@ -0,0 +1,168 @@
+// gazmigrate copies journal fragments from one backing cloud filesystem to
+// another (eg, from S3 to GCS), verifying the content of each fragment against
+// its SHA1 sum. Fragments already present in the destination are skipped, so
+// migrations are incremental and may be resumed after interruption.
+//
+// Journals may remain live while being migrated. Brokers build journal indexes
+// by listing their cloud filesystem, so a typical migration:
+//
+//   - Runs gazmigrate with -interval, which repeats migration passes to pick up
+//     fragments which brokers continue to persist to the source.
+//   - Restarts brokers with a -cloudFS of the destination. Their indexes now
+//     list fragments of the destination.
+//   - Runs a final gazmigrate pass, to copy any fragments persisted to the
+//     source while brokers were restarting.
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/cloudstore"
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+var (
+	fromURL  = flag.String("from", "", "URL of the source cloud filesystem")
+	toURL    = flag.String("to", "", "URL of the destination cloud filesystem")
+	prefix   = flag.String("prefix", "", "Journal prefix to migrate (eg, \"logs/app1/\"). All journals are migrated if empty")
+	interval = flag.Duration("interval", 0, "If non-zero, repeat migration passes "+
+		"at this interval until interrupted. Otherwise, exit after a single pass")
+)
+
+// migrator copies fragments from |src| to |dst|.
+type migrator struct {
+	src, dst cloudstore.FileSystem
+}
+
+// passStats summarizes a migration pass.
+type passStats struct {
+	copied, copiedBytes, skipped, failed int
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+	flag.Parse()
+
+	if *fromURL == "" || *toURL == "" {
+		log.Fatal("-from and -to are required")
+	} else if *fromURL == *toURL {
+		log.Fatal("-from and -to must differ")
+	} else if err := journal.Prefix(*prefix).Validate(); err != nil {
+		log.WithField("err", err).Fatal("invalid -prefix")
+	}
+
+	src, err := cloudstore.NewFileSystem(nil, *fromURL)
+	if err != nil {
+		log.WithField("err", err).Fatal("failed to initialize source cloudstore")
+	}
+	dst, err := cloudstore.NewFileSystem(nil, *toURL)
+	if err != nil {
+		log.WithField("err", err).Fatal("failed to initialize destination cloudstore")
+	}
+	var m = &migrator{src: src, dst: dst}
+
+	for {
+		var stats, err = m.pass(journal.Prefix(*prefix))
+
+		var fields = log.Fields{
+			"copied":      stats.copied,
+			"copiedBytes": stats.copiedBytes,
+			"skipped":     stats.skipped,
+			"failed":      stats.failed,
+		}
+		if err != nil {
+			log.WithFields(fields).WithField("err", err).Fatal("migration pass failed")
+		}
+		log.WithFields(fields).Info("migration pass complete")
+
+		if *interval == 0 {
+			if stats.failed != 0 {
+				os.Exit(1)
+			}
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// pass copies each fragment of journals under |prefix| which is not already
+// present in the destination. An error is returned only if the source cannot
+// be listed. Fragments which fail to copy are logged, and counted as failed.
+func (m *migrator) pass(prefix journal.Prefix) (passStats, error) {
+	var fragments []journal.Fragment
+
+	if err := m.src.Walk(prefix.String(), journal.NewWalkFuncAdapter(func(f journal.Fragment) error {
+		if prefix.Matches(f.Journal) {
+			fragments = append(fragments, f)
+		}
+		return nil
+	})); err != nil {
+		return passStats{}, err
+	}
+
+	var stats passStats
+	for _, fragment := range fragments {
+		switch copied, err := m.copy(fragment); {
+		case err != nil:
+			log.WithFields(log.Fields{"err": err, "path": fragment.ContentPath()}).
+				Error("failed to migrate fragment")
+			stats.failed++
+		case copied:
+			log.WithField("path", fragment.ContentPath()).Debug("migrated fragment")
+			stats.copied++
+			stats.copiedBytes += int(fragment.Size())
+		default:
+			stats.skipped++
+		}
+	}
+	return stats, nil
+}
+
+// copy copies |fragment| from the source to the destination, verifying its
+// content against its SHA1 sum. It returns false if the fragment is already
+// present in the destination. Partial or unverified content is never made
+// visible in the destination.
+func (m *migrator) copy(fragment journal.Fragment) (bool, error) {
+	if err := m.dst.MkdirAll(fragment.Journal.String(), 0750); err != nil {
+		return false, err
+	}
+	var w, err = m.dst.OpenFile(fragment.ContentPath(),
+		os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+
+	if os.IsExist(err) {
+		return false, nil // Already migrated.
+	} else if err != nil {
+		return false, err
+	}
+
+	r, err := m.src.Open(fragment.ContentPath())
+	if err != nil {
+		// Invalidate |w| without making it visible.
+		m.dst.CopyAtomic(w, errReader{err})
+		return false, err
+	}
+	defer r.Close()
+
+	var content io.Reader = r
+	if fragment.Size() != 0 {
+		content = journal.NewSumVerifyingReader(fragment, r)
+	}
+	// Verification fails if the source is truncated. Limit reads to the
+	// fragment's extent, should the source instead have trailing content.
+	content = io.LimitReader(content, fragment.Size())
+
+	if _, err = m.dst.CopyAtomic(w, content); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// errReader is an io.Reader which returns a fixed error.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha1"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/cloudstore"
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type MigrateSuite struct{}
+
+func (s *MigrateSuite) TestIncrementalMigration(c *gc.C) {
+	var src, dst = cloudstore.NewTmpFileSystem(), cloudstore.NewTmpFileSystem()
+	defer src.Close()
+	defer dst.Close()
+
+	var one = writeFragment(c, src, "logs/app1/part-000", 0, "hello, ", "")
+	var two = writeFragment(c, src, "logs/app1/part-000", 7, "world", "")
+	var other = writeFragment(c, src, "logs/app2/part-000", 0, "other", "")
+	var corrupt = writeFragment(c, src, "logs/app1/part-000", 12, "!!!", "???")
+
+	// |one| was previously migrated.
+	writeFragment(c, dst, "logs/app1/part-000", 0, "hello, ", "")
+
+	var m = &migrator{src: src, dst: dst}
+	var stats, err = m.pass("logs/app1/")
+	c.Check(err, gc.IsNil)
+	c.Check(stats, gc.Equals, passStats{copied: 1, copiedBytes: 5, skipped: 1, failed: 1})
+
+	checkContent(c, dst, one, "hello, ")
+	checkContent(c, dst, two, "world")
+	// Neither the corrupt fragment nor journals outside the prefix are copied.
+	checkContent(c, dst, corrupt, "")
+	checkContent(c, dst, other, "")
+
+	// A further pass of all journals copies only |other|.
+	stats, err = m.pass("")
+	c.Check(err, gc.IsNil)
+	c.Check(stats, gc.Equals, passStats{copied: 1, copiedBytes: 5, skipped: 2, failed: 1})
+
+	checkContent(c, dst, other, "other")
+	checkContent(c, dst, corrupt, "")
+}
+
+// writeFragment writes a fragment of |content| to |cfs|, having |actual|
+// content instead if non-empty.
+func writeFragment(c *gc.C, cfs cloudstore.FileSystem, name journal.Name,
+	begin int64, content, actual string) journal.Fragment {
+
+	var fragment = journal.Fragment{
+		Journal: name,
+		Begin:   begin,
+		End:     begin + int64(len(content)),
+		Sum:     sha1.Sum([]byte(content)),
+	}
+	if actual == "" {
+		actual = content
+	}
+	c.Assert(cfs.MkdirAll(name.String(), 0750), gc.IsNil)
+
+	var w, err = cfs.OpenFile(fragment.ContentPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	c.Assert(err, gc.IsNil)
+	_, err = w.Write([]byte(actual))
+	c.Assert(err, gc.IsNil)
+	c.Assert(w.Close(), gc.IsNil)
+
+	return fragment
+}
+
+// checkContent verifies |fragment| of |cfs| has |expect| content, or doesn't
+// exist if |expect| is empty.
+func checkContent(c *gc.C, cfs cloudstore.FileSystem, fragment journal.Fragment, expect string) {
+	var f, err = cfs.Open(fragment.ContentPath())
+	if expect == "" {
+		c.Check(os.IsNotExist(err), gc.Equals, true)
+		return
+	}
+	c.Assert(err, gc.IsNil)
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	c.Check(err, gc.IsNil)
+	c.Check(string(b), gc.Equals, expect)
+}
+
+var _ = gc.Suite(&MigrateSuite{})
+
+func Test(t *testing.T) { gc.TestingT(t) }
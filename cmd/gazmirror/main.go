@@ -0,0 +1,255 @@
+// gazmirror mirrors a source journal, possibly of another Gazette cluster, to
+// a destination journal. Content is appended to the destination byte-for-byte,
+// such that each destination offset holds the same byte as the source offset.
+//
+// The destination write head serves as the mirroring checkpoint: on startup,
+// gazmirror resumes reading the source at the destination's write head. Gaps
+// in the source (eg, due to expired fragments) cannot be mirrored exactly, and
+// cause gazmirror to exit with an error, as do appends to the destination by
+// other writers. Where a source fragment is mirrored in full, it's first
+// spooled locally and verified against the fragment's SHA1 sum, such that
+// corrupt content is never mirrored.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/envflag"
+	"github.com/LiveRamp/gazette/pkg/envflagfactory"
+	"github.com/LiveRamp/gazette/pkg/gazette"
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+var (
+	sourceEndpoint = flag.String("sourceEndpoint", "",
+		"Gazette endpoint of the source journal (defaults to the destination endpoint)")
+	sourceJournal = flag.String("source", "", "Name of the source journal")
+	destJournal   = flag.String("dest", "",
+		"Name of the destination journal (defaults to the source journal name)")
+	chunkSize = flag.Int("chunkSize", 1<<20, "Maximum size of each destination append")
+	rateLimit = flag.Int64("rateLimit", 0,
+		"Maximum rate of mirroring, in bytes per second (zero is unlimited)")
+)
+
+// Time to wait in between source read or destination append errors.
+var mirrorCoolOffTimeout = time.Second * 5
+
+type appender interface {
+	journal.Header
+	Put(journal.AppendArgs) journal.AppendResult
+}
+
+type mirror struct {
+	src     journal.Getter
+	srcName journal.Name
+	dst     appender
+	dstName journal.Name
+
+	chunkSize int
+	rateLimit int64 // Bytes per second, or zero if unlimited.
+}
+
+func main() {
+	var destEndpoint = envflagfactory.NewGazetteServiceEndpoint()
+
+	log.SetOutput(os.Stderr)
+	envflag.CommandLine.Parse()
+	flag.Parse()
+
+	if *sourceJournal == "" {
+		log.Fatal("source journal required")
+	}
+	if *destJournal == "" {
+		*destJournal = *sourceJournal
+	}
+	if *sourceEndpoint == "" {
+		*sourceEndpoint = *destEndpoint
+	}
+
+	srcClient, err := gazette.NewClient(*sourceEndpoint)
+	if err != nil {
+		log.WithField("err", err).Fatal("failed to create source client")
+	}
+	dstClient, err := gazette.NewClient(*destEndpoint)
+	if err != nil {
+		log.WithField("err", err).Fatal("failed to create destination client")
+	}
+	if err := dstClient.Create(journal.Name(*destJournal)); err != nil && err != journal.ErrExists {
+		log.WithField("err", err).Fatal("failed to create destination journal")
+	}
+
+	var m = &mirror{
+		src:       srcClient,
+		srcName:   journal.Name(*sourceJournal),
+		dst:       dstClient,
+		dstName:   journal.Name(*destJournal),
+		chunkSize: *chunkSize,
+		rateLimit: *rateLimit,
+	}
+	if err := m.run(context.Background()); err != nil {
+		log.WithField("err", err).Fatal("mirroring failed")
+	}
+}
+
+// run mirrors until |ctx| is cancelled, or an unrecoverable error occurs.
+func (m *mirror) run(ctx context.Context) error {
+	var offset, err = m.checkpoint()
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"source": m.srcName, "dest": m.dstName, "offset": offset}).
+		Info("mirroring from checkpoint")
+
+	var started, mirrored = time.Now(), int64(0)
+	var buf = make([]byte, m.chunkSize)
+
+	for ctx.Err() == nil {
+		var result, body = m.src.Get(journal.ReadArgs{
+			Journal:  m.srcName,
+			Offset:   offset,
+			Blocking: true,
+			Context:  ctx,
+		})
+		if result.Error != nil {
+			if ctx.Err() == nil {
+				log.WithFields(log.Fields{"err": result.Error, "offset": offset}).
+					Warn("source read failed")
+				time.Sleep(mirrorCoolOffTimeout)
+			}
+			continue
+		} else if result.Offset != offset {
+			body.Close()
+			return fmt.Errorf("gap in source content: expected offset %d, but next "+
+				"available offset is %d", offset, result.Offset)
+		}
+
+		if offset == result.Fragment.Begin && result.Fragment.Size() != 0 {
+			// The fragment is read in full. Spool and verify it against its SHA1
+			// sum before any of it is mirrored.
+			var spool, err = spoolFragment(result.Fragment, body)
+			body.Close()
+
+			if err == journal.ErrSumMismatch {
+				return fmt.Errorf("SHA1 mismatch of fragment %s", result.Fragment.ContentName())
+			} else if err != nil {
+				if ctx.Err() == nil {
+					log.WithFields(log.Fields{"err": err, "offset": offset}).
+						Warn("source read failed")
+					time.Sleep(mirrorCoolOffTimeout)
+				}
+				continue
+			}
+			body = spool
+		}
+
+		for {
+			var n, readErr = readChunk(body, buf)
+
+			if n != 0 {
+				if err = m.append(ctx, offset, buf[:n]); err != nil {
+					body.Close()
+					return err
+				}
+				offset += int64(n)
+				mirrored += int64(n)
+
+				m.throttle(started, mirrored)
+			}
+			if readErr != nil {
+				if readErr != io.EOF && ctx.Err() == nil {
+					log.WithFields(log.Fields{"err": readErr, "offset": offset}).
+						Warn("source read failed")
+				}
+				break
+			}
+		}
+		body.Close()
+	}
+	return nil
+}
+
+// checkpoint returns the offset from which mirroring should resume, which is
+// the current write head of the destination.
+func (m *mirror) checkpoint() (int64, error) {
+	var result, _ = m.dst.Head(journal.ReadArgs{Journal: m.dstName, Offset: -1})
+	if result.Error != nil && result.Error != journal.ErrNotYetAvailable {
+		return 0, result.Error
+	}
+	return result.WriteHead, nil
+}
+
+// append |chunk| at |offset| of the destination, retrying until success.
+func (m *mirror) append(ctx context.Context, offset int64, chunk []byte) error {
+	for {
+		var result = m.dst.Put(journal.AppendArgs{
+			Journal: m.dstName,
+			Content: bytes.NewReader(chunk),
+			Context: ctx,
+		})
+		if result.Error == nil {
+			if result.WriteHead != offset+int64(len(chunk)) {
+				return fmt.Errorf("destination diverged: expected write head %d "+
+					"after append, but it's %d", offset+int64(len(chunk)), result.WriteHead)
+			}
+			return nil
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.WithFields(log.Fields{"err": result.Error, "offset": offset}).
+			Warn("destination append failed")
+		time.Sleep(mirrorCoolOffTimeout)
+	}
+}
+
+// throttle sleeps as required to hold the mirroring rate to |rateLimit|.
+func (m *mirror) throttle(started time.Time, mirrored int64) {
+	if m.rateLimit == 0 {
+		return
+	}
+	var expect = time.Duration(mirrored * int64(time.Second) / m.rateLimit)
+
+	if delta := expect - time.Since(started); delta > 0 {
+		time.Sleep(delta)
+	}
+}
+
+// readChunk reads into |buf| until it's full, or until an error is returned
+// by |r| (which may be returned alongside a partial read).
+func readChunk(r io.Reader, buf []byte) (int, error) {
+	var n, err = io.ReadFull(r, buf)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// spoolFragment reads all of |fragment| from |body|, which begins at the
+// fragment's Begin offset, into an unlinked temporary file. Content is verified
+// against the fragment's SHA1 sum. The returned file holds only the fragment,
+// and is positioned at its beginning.
+func spoolFragment(fragment journal.Fragment, body io.ReadCloser) (*os.File, error) {
+	var file, err = ioutil.TempFile("", "gazmirror-")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(file.Name()) // Collected once |file| is closed.
+
+	var r = journal.NewSumVerifyingReader(fragment, body)
+	if _, err = io.CopyN(file, r, fragment.Size()); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type MirrorSuite struct{}
+
+// sourceFixture serves reads of |content| from fragments split at |splits|.
+// Once content is exhausted, it calls |onExhausted| and returns
+// ErrNotYetAvailable.
+type sourceFixture struct {
+	content     string
+	splits      []int64
+	corrupt     bool
+	onExhausted func()
+}
+
+func (s *sourceFixture) Get(args journal.ReadArgs) (journal.ReadResult, io.ReadCloser) {
+	if args.Offset >= int64(len(s.content)) {
+		s.onExhausted()
+		return journal.ReadResult{Error: journal.ErrNotYetAvailable}, nil
+	}
+	var fragment = journal.Fragment{Journal: args.Journal}
+	for _, split := range append(s.splits, int64(len(s.content))) {
+		if args.Offset < split {
+			fragment.End = split
+			break
+		}
+		fragment.Begin = split
+	}
+	var body = s.content[fragment.Begin:fragment.End]
+	fragment.Sum = sha1.Sum([]byte(body))
+
+	if s.corrupt {
+		body = strings.ToUpper(body)
+	}
+	return journal.ReadResult{Offset: args.Offset, Fragment: fragment},
+		ioutil.NopCloser(strings.NewReader(body[args.Offset-fragment.Begin:]))
+}
+
+// destFixture accumulates appended content.
+type destFixture struct {
+	content   string
+	writeHead int64
+}
+
+func (d *destFixture) Head(args journal.ReadArgs) (journal.ReadResult, *url.URL) {
+	return journal.ReadResult{WriteHead: d.writeHead}, nil
+}
+
+func (d *destFixture) Put(args journal.AppendArgs) journal.AppendResult {
+	var b, _ = ioutil.ReadAll(args.Content)
+	d.content += string(b)
+	d.writeHead += int64(len(b))
+	return journal.AppendResult{WriteHead: d.writeHead}
+}
+
+func (s *MirrorSuite) TestMirrorFromCheckpoint(c *gc.C) {
+	var ctx, cancel = context.WithCancel(context.Background())
+
+	var src = &sourceFixture{
+		content:     "0123456789abcdefghijklmnopqrstuvwxyz",
+		splits:      []int64{10, 20, 30},
+		onExhausted: cancel,
+	}
+	// The destination has already mirrored through offset 15.
+	var dst = &destFixture{content: src.content[:15], writeHead: 15}
+
+	var m = &mirror{
+		src:       src,
+		srcName:   "source/journal",
+		dst:       dst,
+		dstName:   "dest/journal",
+		chunkSize: 4,
+	}
+	c.Check(m.run(ctx), gc.IsNil)
+	c.Check(dst.content, gc.Equals, src.content)
+}
+
+func (s *MirrorSuite) TestGapIsAnError(c *gc.C) {
+	var src = &sourceFixture{content: "0123456789", onExhausted: func() {}}
+	var dst = &destFixture{writeHead: 20} // Ahead of the source.
+
+	var m = &mirror{src: gapGetter{src}, dst: dst, chunkSize: 4}
+	c.Check(m.run(context.Background()), gc.ErrorMatches,
+		"gap in source content: expected offset 20, but next available offset is 25")
+}
+
+func (s *MirrorSuite) TestDivergedDestinationIsAnError(c *gc.C) {
+	var src = &sourceFixture{content: "0123456789", onExhausted: func() {}}
+	var dst = &destFixture{}
+
+	var m = &mirror{src: src, dst: divergingDest{dst}, chunkSize: 4}
+	c.Check(m.run(context.Background()), gc.ErrorMatches,
+		"destination diverged: expected write head 4 after append, but it's 5")
+}
+
+func (s *MirrorSuite) TestSumMismatchIsAnError(c *gc.C) {
+	var src = &sourceFixture{content: "abcdefghij", splits: []int64{5}, corrupt: true}
+	var dst = &destFixture{}
+
+	var m = &mirror{src: src, dst: dst, chunkSize: 3}
+	c.Check(m.run(context.Background()), gc.ErrorMatches,
+		"SHA1 mismatch of fragment 0000000000000000-0000000000000005-.*")
+	c.Check(dst.content, gc.Equals, "") // Nothing was mirrored.
+}
+
+// gapGetter returns reads at an offset 5 bytes beyond that requested.
+type gapGetter struct{ *sourceFixture }
+
+func (g gapGetter) Get(args journal.ReadArgs) (journal.ReadResult, io.ReadCloser) {
+	var result = journal.ReadResult{Offset: args.Offset + 5}
+	return result, ioutil.NopCloser(strings.NewReader(""))
+}
+
+// divergingDest reports a write head one byte beyond that of each append.
+type divergingDest struct{ *destFixture }
+
+func (d divergingDest) Put(args journal.AppendArgs) journal.AppendResult {
+	var result = d.destFixture.Put(args)
+	result.WriteHead += 1
+	return result
+}
+
+var _ = gc.Suite(&MirrorSuite{})
+
+func Test(t *testing.T) { gc.TestingT(t) }
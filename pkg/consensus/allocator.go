@@ -11,6 +11,8 @@ import (
 
 	etcd "github.com/coreos/etcd/client"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/metrics"
 )
 
 //go:generate mockery -inpkg -name=Allocator
@@ -25,6 +27,8 @@ const (
 	// for lock resets, to ensure that ItemStates are polled and updated
 	// into Etcd with sufficient frequency.
 	allocMaxSleepInterval = time.Second * 5
+	// Interval at which the progress of a drain is logged.
+	drainLogInterval = time.Second * 15
 )
 
 var ErrAllocatorInstanceExists = errors.New("Allocator member key exists")
@@ -72,6 +76,44 @@ type Inspector interface {
 	InspectChan() chan func(tree *etcd.Node)
 }
 
+// ActionObserver is an optional interface of an Allocator which is notified
+// of each allocation action applied to Etcd, allowing for an audit log of
+// allocation decisions (eg, why an item moved between members).
+type ActionObserver interface {
+	// ObserveAction is invoked from the Allocator's goroutine, and must not block.
+	ObserveAction(Action)
+}
+
+// ActionKind enumerates kinds of allocation actions.
+type ActionKind string
+
+const (
+	ActionRefreshMember  ActionKind = "refresh-member"
+	ActionRefreshMaster  ActionKind = "refresh-master"
+	ActionRefreshReplica ActionKind = "refresh-replica"
+	ActionReleaseExtra   ActionKind = "release-extra"
+	ActionReleaseMaster  ActionKind = "release-master"
+	ActionReleaseReplica ActionKind = "release-replica"
+	ActionAcquireMaster  ActionKind = "acquire-master"
+	ActionAcquireReplica ActionKind = "acquire-replica"
+)
+
+// Action describes an allocation action applied by an Allocator.
+type Action struct {
+	Kind ActionKind
+	// Etcd key of the acted-upon member or item entry.
+	Key string
+	// Reason the action was selected.
+	Reason string
+	// Etcd ModifiedIndex at which the action was applied.
+	Index uint64
+	// Allocator target counts, and current counts, at the time of the action.
+	DesiredMaster, DesiredTotal int
+	Master, Replica             int
+	// Number of allocator members and items.
+	Members, Items int
+}
+
 // Create attempts to create an Allocator member lock reflecting instance
 // |alloc|. If the member lock already exists, returns
 // ErrAllocatorInstanceExists. An Allocator member lock should be obtained
@@ -153,6 +195,7 @@ func Allocate(alloc Allocator) error {
 	if inspector, ok := alloc.(Inspector); ok {
 		inspectCh = inspector.InspectChan()
 	}
+	var observer, _ = alloc.(ActionObserver)
 
 	// When idle, manages deadline at which we must wake for next lock refresh.
 	var deadlineTimer = time.NewTimer(0)
@@ -208,6 +251,7 @@ func Allocate(alloc Allocator) error {
 
 		if response, err := allocAction(&params, desiredMaster, desiredTotal); err != nil {
 			log.WithField("err", err).Warn("failed to apply allocation action")
+			metrics.AllocatorActionFailuresTotal.WithLabelValues(actionFailureReason(err)).Inc()
 
 			// Action is implicitly retried the next iteration, which will occur
 			// on the next watch update or after cool-off.
@@ -218,7 +262,17 @@ func Allocate(alloc Allocator) error {
 			// Action was applied. We expect to see |response| again via our Etcd
 			// watch, and defer further processing or actions until we do.
 			modifiedIndex = response.Node.ModifiedIndex
+			metrics.AllocatorActionsTotal.WithLabelValues(string(params.Output.Action.Kind)).Inc()
 
+			if observer != nil {
+				var action = params.Output.Action
+				action.Index = modifiedIndex
+				action.DesiredMaster, action.DesiredTotal = desiredMaster, desiredTotal
+				action.Master, action.Replica = len(params.Item.Master), len(params.Item.Replica)
+				action.Members, action.Items = params.Member.Count, params.Item.Count
+
+				observer.ObserveAction(action)
+			}
 			if testNotifier != nil {
 				testNotifier.ActedAt(modifiedIndex)
 			}
@@ -302,18 +356,59 @@ func CreateAndAllocateWithSignalHandling(alloc Allocator) error {
 		}
 	}
 
-	// Arrange to Cancel Allocate on signal, allowing it to gracefully tear down.
-	go func() {
-		<-shutdownCh
+	// Arrange to drain Allocate on signal, allowing it to gracefully tear down.
+	var allocCtx, allocDone = context.WithCancel(context.Background())
+	defer allocDone()
 
-		if err := Cancel(alloc); err != nil {
-			log.WithField("err", err).Error("allocator cancel failed")
+	go func() {
+		select {
+		case <-shutdownCh:
+		case <-allocCtx.Done():
+			return
 		}
+		drainWithLogging(allocCtx, alloc)
 	}()
 
 	return Allocate(alloc)
 }
 
+// drainWithLogging drains |alloc|, periodically logging its progress until
+// |ctx| is done (as Allocate exits). Allocators which are not Inspectors are
+// instead Cancelled.
+func drainWithLogging(ctx context.Context, alloc Allocator) {
+	var d, err = BeginDrain(ctx, alloc)
+
+	if err == ErrNotInspector {
+		err = Cancel(alloc)
+	}
+	if err != nil {
+		log.WithField("err", err).Error("allocator cancel failed")
+	}
+	if d == nil {
+		return
+	}
+
+	var ticker = time.NewTicker(drainLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		if p, err := d.Progress(ctx); err == nil {
+			log.WithFields(log.Fields{
+				"master":    p.Master,
+				"replica":   p.Replica,
+				"released":  p.Released,
+				"elapsed":   p.Elapsed,
+				"remaining": p.Remaining,
+			}).Info("draining allocator")
+		}
+	}
+}
+
 // memberKey returns the member announcement key for |alloc|.
 // Ex: /path/root/members/my-alloc-key
 func memberKey(alloc Allocator) string {
@@ -357,6 +452,21 @@ type allocParams struct {
 		Entry *etcd.Node // Our member entry.
 		Count int        // Total number of allocator members.
 	}
+	Output struct {
+		Action Action // Action selected by allocAction.
+	}
+	// Source of random choices, or nil to use the global source.
+	rand *rand.Rand
+	// Skip the deadlock-avoidance delay (eg, because allocation is simulated).
+	noDelay bool
+}
+
+// randIndex returns a random index of a slice of length |n|.
+func (p *allocParams) randIndex(n int) int {
+	if p.rand != nil {
+		return p.rand.Intn(n)
+	}
+	return rand.Int() % n
 }
 
 // WalkItems performs a zipped, outer-join iteration of items under ItemsPrefix
@@ -428,32 +538,46 @@ func allocExtract(p *allocParams) {
 }
 
 // allocAction selects and attempts an action (state transition) given the
-// current parameters, as an Etcd operation. Etcd response and error code are
-// passed through. If both are nil, no action was available to be attempted.
+// current parameters, as an Etcd operation, and describes it in
+// |p.Output.Action|. Etcd response and error code are passed through. If both
+// are nil, no action was available to be attempted.
 func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Response, error) {
 	// Locks are refreshed when less than 1/2 of their TTL remains.
 	var horizon = p.Input.Time.Add(lockDuration / 2)
 
 	// Helper which CASs |node| to |value| with TTL.
 	var compareAndSet = func(node *etcd.Node, value string) (*etcd.Response, error) {
-		return p.KeysAPI().Set(context.Background(), node.Key, value,
+		var resp, err = p.KeysAPI().Set(context.Background(), node.Key, value,
 			&etcd.SetOptions{PrevIndex: node.ModifiedIndex, TTL: lockDuration})
+		if err == nil {
+			metrics.AllocatorWrittenBytesTotal.Add(float64(len(node.Key) + len(value)))
+		}
+		return resp, err
 	}
 	// Helper which CADs |node|.
 	var compareAndDelete = func(node *etcd.Node) (*etcd.Response, error) {
 		return p.KeysAPI().Delete(context.Background(), node.Key,
 			&etcd.DeleteOptions{PrevIndex: node.ModifiedIndex})
 	}
+	// Helper which records the selected action.
+	var selected = func(kind ActionKind, key, reason string) {
+		p.Output.Action = Action{Kind: kind, Key: key, Reason: reason}
+	}
 	// Helper which creates |key| with TTL.
 	var create = func(key string) (*etcd.Response, error) {
-		return p.KeysAPI().Set(context.Background(), key, "",
+		var resp, err = p.KeysAPI().Set(context.Background(), key, "",
 			&etcd.SetOptions{PrevExist: etcd.PrevNoExist, TTL: lockDuration})
+		if err == nil {
+			metrics.AllocatorWrittenBytesTotal.Add(float64(len(key)))
+		}
+		return resp, err
 	}
 
 	// 1) Refresh the member lock.
 	if p.Member.Entry != nil {
 		if p.Member.Entry.Expiration.Before(horizon) {
 			log.WithField("key", p.Member.Entry.Key).Debug("refreshing member lock")
+			selected(ActionRefreshMember, p.Member.Entry.Key, "lock expiring")
 
 			return compareAndSet(p.Member.Entry, "")
 		}
@@ -470,6 +594,7 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 		if entry.Expiration.Before(horizon) || value != entry.Value {
 			log.WithFields(log.Fields{"key": entry.Key, "value": value}).
 				Debug("refreshing allocated master lock")
+			selected(ActionRefreshMaster, entry.Key, refreshReason(entry, value))
 
 			return compareAndSet(entry, value)
 		}
@@ -481,6 +606,7 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 		if entry.Expiration.Before(horizon) || value != entry.Value {
 			log.WithFields(log.Fields{"key": entry.Key, "value": value}).
 				Debug("refreshing allocated replica lock")
+			selected(ActionRefreshReplica, entry.Key, refreshReason(entry, value))
 
 			return compareAndSet(entry, value)
 		}
@@ -488,6 +614,7 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 	// 4) Release a spurious lock from a lost acquisition race.
 	for _, entry := range p.Item.Extra {
 		log.WithField("key", entry.Key).Debug("deleting lost-race item lock")
+		selected(ActionReleaseExtra, entry.Key, "lost acquisition race")
 
 		return compareAndDelete(entry)
 	}
@@ -496,8 +623,9 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 	//  * The item has the required number of ready replicas.
 	//  * We'd like to release a mastered item.
 	if len(p.Item.Master) > desiredMaster && len(p.Item.Releaseable) != 0 {
-		entry := p.Item.Releaseable[rand.Int()%len(p.Item.Releaseable)]
+		entry := p.Item.Releaseable[p.randIndex(len(p.Item.Releaseable))]
 		log.WithField("key", entry.Key).Debug("releasing mastered item lock")
+		selected(ActionReleaseMaster, entry.Key, "mastered items exceed load target")
 
 		return compareAndDelete(entry)
 	}
@@ -505,8 +633,9 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 	// release a replica we hold. However, iff we have no member lock (we're in
 	// the process of shutdown), then we may release held replicas (not masters).
 	if p.Member.Entry == nil && len(p.Item.Replica) != 0 {
-		entry := p.Item.Replica[rand.Int()%len(p.Item.Replica)]
+		entry := p.Item.Replica[p.randIndex(len(p.Item.Replica))]
 		log.WithField("key", entry.Key).Debug("releasing replica item lock")
+		selected(ActionReleaseReplica, entry.Key, "member exiting")
 
 		return compareAndDelete(entry)
 	}
@@ -515,9 +644,10 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 	//  * The item has an open master slot.
 	//  * We'd like to have another master.
 	if len(p.Item.Master) < desiredMaster && len(p.Item.OpenMasters) != 0 {
-		name := p.Item.OpenMasters[rand.Int()%len(p.Item.OpenMasters)]
+		name := p.Item.OpenMasters[p.randIndex(len(p.Item.OpenMasters))]
 		key := itemKey(p, name)
 		log.WithField("key", key).Debug("aquiring item master lock")
+		selected(ActionAcquireMaster, key, "open master slot within load target")
 
 		return create(key)
 	}
@@ -526,9 +656,10 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 	//  * The item has an open replica slot.
 	//  * We'd like to have another replica.
 	if len(p.Item.Master)+len(p.Item.Replica) < desiredTotal && len(p.Item.OpenReplicas) != 0 {
-		name := p.Item.OpenReplicas[rand.Int()%len(p.Item.OpenReplicas)]
+		name := p.Item.OpenReplicas[p.randIndex(len(p.Item.OpenReplicas))]
 		key := itemKey(p, name)
 		log.WithField("key", key).Debug("aquiring item replica lock")
+		selected(ActionAcquireReplica, key, "open replica slot within load target")
 
 		return create(key)
 	}
@@ -541,8 +672,9 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 		len(p.Item.Master)+len(p.Item.Replica) > desiredTotal &&
 		len(p.Item.Releaseable) != 0 {
 
-		var entry = p.Item.Releaseable[rand.Int()%len(p.Item.Releaseable)]
+		var entry = p.Item.Releaseable[p.randIndex(len(p.Item.Releaseable))]
 		log.WithField("key", entry.Key).Debug("releasing EXTRA mastered item lock")
+		selected(ActionReleaseMaster, entry.Key, "deadlock avoidance: total items exceed load target")
 		return compareAndDelete(entry)
 	}
 	// 10) Deadlock avoidance: Select a random item to replicate with delay, iff:
@@ -561,16 +693,38 @@ func allocAction(p *allocParams, desiredMaster, desiredTotal int) (*etcd.Respons
 		len(p.Item.OpenReplicas) != 0 &&
 		p.Member.Entry != nil {
 
-		var name = p.Item.OpenReplicas[rand.Int()%len(p.Item.OpenReplicas)]
+		var name = p.Item.OpenReplicas[p.randIndex(len(p.Item.OpenReplicas))]
 		var key = itemKey(p, name)
 
-		time.Sleep(100 * time.Millisecond)
+		if !p.noDelay {
+			time.Sleep(100 * time.Millisecond)
+		}
 		log.WithField("key", key).Debug("aquiring EXTRA item replica lock")
+		selected(ActionAcquireReplica, key, "deadlock avoidance: open replica slot at load target")
 		return create(key)
 	}
 	return nil, nil
 }
 
+// actionFailureReason classifies |err| of a failed allocation action. Actions
+// conflict if the compared member or item entry was concurrently modified or
+// expired, or if a created entry already exists (eg, a lost acquisition race).
+func actionFailureReason(err error) string {
+	if err, ok := err.(etcd.Error); ok && (err.Code == etcd.ErrorCodeTestFailed ||
+		err.Code == etcd.ErrorCodeNodeExist || err.Code == etcd.ErrorCodeKeyNotFound) {
+		return "conflict"
+	}
+	return "error"
+}
+
+// refreshReason returns the reason an item |entry| lock is refreshed.
+func refreshReason(entry *etcd.Node, value string) string {
+	if value != entry.Value {
+		return "item state changed"
+	}
+	return "lock expiring"
+}
+
 // targetCounts returns the desired number of mastered and total (mastered +
 // replica) items.
 func targetCounts(p *allocParams) (desiredMaster, desiredTotal int) {
@@ -184,19 +184,20 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 
 	var underTest = model
 
-	verify := func(desiredMaster, desiredTotal int, expectAct bool) {
-		if response, err := allocAction(&underTest, desiredMaster, desiredTotal); expectAct {
+	verify := func(desiredMaster, desiredTotal int, expectAct ActionKind) {
+		if response, err := allocAction(&underTest, desiredMaster, desiredTotal); expectAct != "" {
 			c.Check(response, gc.Equals, respFixture)
 			c.Check(err, gc.Equals, errFixture)
 		} else {
 			c.Check(response, gc.IsNil)
 			c.Check(err, gc.IsNil)
 		}
+		c.Check(underTest.Output.Action.Kind, gc.Equals, expectAct)
 		underTest = model // Reset.
 	}
 
 	// Default member entry is up to date. No refresh.
-	verify(0, 0, false)
+	verify(0, 0, "")
 
 	// Entry is refreshed if horizon has elapsed.
 	underTest.Member.Entry = &etcd.Node{
@@ -205,7 +206,7 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 	mockKV.On("Set", mock.Anything, "/foo/members/my-key", "",
 		&etcd.SetOptions{PrevIndex: 123, TTL: lockDuration}).
 		Return(respFixture, errFixture).Once()
-	verify(0, 0, true)
+	verify(0, 0, ActionRefreshMember)
 
 	// Expect |upToDate| isn't refreshed as a Master or Replica.
 	upToDate := []*etcd.Node{{
@@ -215,9 +216,9 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 	}}
 
 	underTest.Item.Master = upToDate
-	verify(0, 0, false)
+	verify(0, 0, "")
 	underTest.Item.Replica = upToDate
-	verify(0, 0, false)
+	verify(0, 0, "")
 
 	// Refresh is required because updated value needs to be persisted.
 	valueChanged := []*etcd.Node{{
@@ -232,9 +233,9 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 		Return(respFixture, errFixture).Times(4)
 
 	underTest.Item.Master = valueChanged
-	verify(0, 0, true)
+	verify(0, 0, ActionRefreshMaster)
 	underTest.Item.Replica = valueChanged
-	verify(0, 0, true)
+	verify(0, 0, ActionRefreshReplica)
 
 	// Refresh is required because TTL refresh horizon has elapsed.
 	outOfDate := []*etcd.Node{{
@@ -245,9 +246,9 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 	}}
 
 	underTest.Item.Master = outOfDate
-	verify(0, 0, true)
+	verify(0, 0, ActionRefreshMaster)
 	underTest.Item.Replica = outOfDate
-	verify(0, 0, true)
+	verify(0, 0, ActionRefreshReplica)
 
 	// Release of extra item lock.
 	underTest.Item.Extra = []*etcd.Node{{
@@ -257,7 +258,7 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 
 	mockKV.On("Delete", mock.Anything, "/foo/items/extra-item/my-key",
 		&etcd.DeleteOptions{PrevIndex: 345}).Return(respFixture, errFixture).Once()
-	verify(0, 0, true)
+	verify(0, 0, ActionReleaseExtra)
 
 	// Release of mastered item entry.
 	underTest.Item.Releaseable = []*etcd.Node{{
@@ -270,7 +271,7 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 		&etcd.DeleteOptions{PrevIndex: 456}).Return(respFixture, errFixture).Twice()
 
 	underTest.Item.Master = underTest.Item.Releaseable
-	verify(0, 0, true)
+	verify(0, 0, ActionReleaseMaster)
 
 	// If member lock is present, replicas are never released.
 	underTest.Item.Replica = []*etcd.Node{{
@@ -279,7 +280,7 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 		Value:         "a-value",
 		ModifiedIndex: 567,
 	}}
-	verify(0, 0, false)
+	verify(0, 0, "")
 
 	// However, they will be if the lock is missing.
 	underTest.Member.Entry = nil
@@ -289,7 +290,7 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 		Value:         "a-value",
 		ModifiedIndex: 456,
 	}}
-	verify(0, 0, true)
+	verify(0, 0, ActionReleaseReplica)
 
 	// Acquire of master entry.
 	mockKV.On("Set", mock.Anything, "/foo/items/new-item/my-key", "",
@@ -297,11 +298,11 @@ func (s *AllocSuite) TestAllocationActions(c *gc.C) {
 		Return(respFixture, errFixture).Twice()
 
 	underTest.Item.OpenMasters = []string{"new-item"}
-	verify(1, 0, true)
+	verify(1, 0, ActionAcquireMaster)
 
 	// Aquire of replica entry.
 	underTest.Item.OpenReplicas = []string{"new-item"}
-	verify(0, 1, true)
+	verify(0, 1, ActionAcquireReplica)
 }
 
 func (s *AllocSuite) TestNextDeadline(c *gc.C) {
@@ -320,6 +321,14 @@ func (s *AllocSuite) TestNextDeadline(c *gc.C) {
 	c.Check(nextDeadline(&p), gc.Equals, now.Add(lockDuration/2-2))
 }
 
+func (s *AllocSuite) TestActionFailureReason(c *gc.C) {
+	c.Check(actionFailureReason(etcd.Error{Code: etcd.ErrorCodeTestFailed}), gc.Equals, "conflict")
+	c.Check(actionFailureReason(etcd.Error{Code: etcd.ErrorCodeNodeExist}), gc.Equals, "conflict")
+	c.Check(actionFailureReason(etcd.Error{Code: etcd.ErrorCodeKeyNotFound}), gc.Equals, "conflict")
+	c.Check(actionFailureReason(etcd.Error{Code: etcd.ErrorCodeRaftInternal}), gc.Equals, "error")
+	c.Check(actionFailureReason(errors.New("connection refused")), gc.Equals, "error")
+}
+
 func buildTree(c *gc.C, nodes []etcd.Node) *etcd.Node {
 	tree := &etcd.Node{Dir: true}
 
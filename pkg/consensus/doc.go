@@ -1,3 +1,9 @@
 // Package consensus provides building blocks for solving difficult distributed
 // consensus-related problems atop Etcd, both within and across processes.
+//
+// It targets the Etcd v2 keys API, and its contracts expose the v2 data model:
+// an Allocator provides an etcd.KeysAPI, and is notified of item Routes with
+// the *etcd.Node tree from which they were derived. Allocator locks are TTL'd
+// keys which are compare-and-swapped on their ModifiedIndex, and a Route
+// orders item entries by their CreatedIndex.
 package consensus
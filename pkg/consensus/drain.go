@@ -0,0 +1,106 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+)
+
+// ErrNotInspector is returned by BeginDrain if the Allocator is not an Inspector.
+var ErrNotInspector = errors.New("Allocator does not implement Inspector")
+
+// Drain is an in-progress, orderly shutdown of an Allocator, begun by
+// BeginDrain. The drain completes when the Allocator's Allocate call exits,
+// having handed off all of its items.
+type Drain struct {
+	inspector Inspector
+	key       string
+	initial   int       // Item locks held as the drain began.
+	started   time.Time // Time at which the drain began.
+	timeNow   func() time.Time
+}
+
+// DrainProgress describes the progress of a Drain.
+type DrainProgress struct {
+	// Items for which the Allocator still holds master or replica locks.
+	Master, Replica int
+	// Item locks released since the drain began.
+	Released int
+	// Elapsed time since the drain began.
+	Elapsed time.Duration
+	// Estimated time until the drain completes, extrapolated from the rate of
+	// released locks. Zero if no locks have yet been released.
+	Remaining time.Duration
+}
+
+// BeginDrain begins an orderly shutdown of |alloc|, which must be an Inspector
+// for which Allocate is running. Like Cancel, it deletes the |alloc| member
+// announcement, and Allocate then releases held items as they're ready for
+// hand-off. The returned Drain reports progress as it does so.
+func BeginDrain(ctx context.Context, alloc Allocator) (*Drain, error) {
+	var inspector, ok = alloc.(Inspector)
+	if !ok {
+		return nil, ErrNotInspector
+	}
+	var d = &Drain{
+		inspector: inspector,
+		key:       alloc.InstanceKey(),
+		timeNow:   time.Now,
+	}
+
+	if master, replica, err := d.heldLocks(ctx); err != nil {
+		return nil, err
+	} else {
+		d.initial = master + replica
+	}
+	d.started = d.timeNow()
+
+	if err := Cancel(alloc); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Progress returns the current DrainProgress. Progress is determined by the
+// running Allocate call, and Progress fails if |ctx| is done before Allocate
+// services the request (eg, because Allocate has since exited).
+func (d *Drain) Progress(ctx context.Context) (DrainProgress, error) {
+	var out DrainProgress
+	var err error
+
+	if out.Master, out.Replica, err = d.heldLocks(ctx); err != nil {
+		return out, err
+	}
+	out.Elapsed = d.timeNow().Sub(d.started)
+
+	if out.Released = d.initial - out.Master - out.Replica; out.Released > 0 {
+		var perLock = out.Elapsed / time.Duration(out.Released)
+		out.Remaining = perLock * time.Duration(out.Master+out.Replica)
+	}
+	return out, nil
+}
+
+// heldLocks returns the number of master and replica locks held by the Allocator.
+func (d *Drain) heldLocks(ctx context.Context) (master, replica int, err error) {
+	var done = make(chan struct{})
+	var cb = func(tree *etcd.Node) {
+		WalkItems(tree, nil, func(name string, route Route) {
+			if ind := route.Index(d.key); ind == 0 {
+				master++
+			} else if ind > 0 {
+				replica++
+			}
+		})
+		close(done)
+	}
+
+	select {
+	case d.inspector.InspectChan() <- cb:
+		<-done
+		return
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	}
+}
@@ -0,0 +1,98 @@
+package consensus
+
+import (
+	"context"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+	gc "github.com/go-check/check"
+	"github.com/stretchr/testify/mock"
+)
+
+type DrainSuite struct{}
+
+func (s *DrainSuite) TestProgress(c *gc.C) {
+	var entry = func(item, member string, index uint64) *etcd.Node {
+		return &etcd.Node{Key: "/foo/items/" + item + "/" + member, CreatedIndex: index}
+	}
+	var item = func(name string, entries ...*etcd.Node) *etcd.Node {
+		return &etcd.Node{Key: "/foo/items/" + name, Dir: true, Nodes: entries}
+	}
+	var tree = &etcd.Node{Key: "/foo", Dir: true, Nodes: etcd.Nodes{
+		{Key: "/foo/items", Dir: true, Nodes: etcd.Nodes{
+			item("a", entry("a", "my-key", 1), entry("a", "other-key", 2)),
+			item("b", entry("b", "other-key", 3), entry("b", "my-key", 4)),
+			item("c", entry("c", "my-key", 5)),
+			item("d", entry("d", "other-key", 6)),
+		}},
+	}}
+
+	var kv MockKeysAPI
+	kv.On("Delete", mock.Anything, "/foo/members/my-key", (*etcd.DeleteOptions)(nil)).
+		Return(&etcd.Response{}, nil).Once()
+
+	var alloc = &inspectingAllocator{MockAllocator: new(MockAllocator), ch: make(chan func(*etcd.Node))}
+	alloc.On("InstanceKey").Return("my-key")
+	alloc.On("PathRoot").Return("/foo")
+	alloc.On("KeysAPI").Return(&kv)
+
+	// Service inspections of |tree|, as Allocate would.
+	go func() {
+		for cb := range alloc.ch {
+			cb(tree)
+		}
+	}()
+	defer close(alloc.ch)
+
+	var d, err = BeginDrain(context.Background(), alloc)
+	c.Assert(err, gc.IsNil)
+	c.Check(d.initial, gc.Equals, 3)
+	kv.AssertExpectations(c)
+
+	var now = d.started
+	d.timeNow = func() time.Time { return now }
+
+	// No locks have yet been released.
+	now = now.Add(time.Minute)
+	var p, _ = d.Progress(context.Background())
+	c.Check(p, gc.DeepEquals, DrainProgress{Master: 2, Replica: 1, Elapsed: time.Minute})
+
+	// Two locks are released. Time remaining is extrapolated.
+	tree.Nodes[0].Nodes = etcd.Nodes{
+		item("a", entry("a", "other-key", 2)),
+		item("b", entry("b", "other-key", 3), entry("b", "my-key", 4)),
+		item("c", entry("c", "other-key", 7)),
+		item("d", entry("d", "other-key", 6)),
+	}
+	now = now.Add(time.Minute)
+	p, _ = d.Progress(context.Background())
+	c.Check(p, gc.DeepEquals, DrainProgress{
+		Replica:   1,
+		Released:  2,
+		Elapsed:   2 * time.Minute,
+		Remaining: time.Minute,
+	})
+
+	// Progress fails if the Allocator doesn't service it.
+	var ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	d.inspector = &inspectingAllocator{ch: make(chan func(*etcd.Node))}
+
+	_, err = d.Progress(ctx)
+	c.Check(err, gc.Equals, context.Canceled)
+}
+
+func (s *DrainSuite) TestRequiresInspector(c *gc.C) {
+	var _, err = BeginDrain(context.Background(), new(MockAllocator))
+	c.Check(err, gc.Equals, ErrNotInspector)
+}
+
+// inspectingAllocator is an Allocator which is also an Inspector.
+type inspectingAllocator struct {
+	*MockAllocator
+	ch chan func(*etcd.Node)
+}
+
+func (a *inspectingAllocator) InspectChan() chan func(*etcd.Node) { return a.ch }
+
+var _ = gc.Suite(&DrainSuite{})
@@ -15,6 +15,39 @@ func (s *RouteSuite) TestOrdering(c *gc.C) {
 	c.Check(rt.Entries[2].Key, gc.Equals, "/foo/bar/bbb")
 }
 
+func (s *RouteSuite) TestOrderingIsStableAcrossRounds(c *gc.C) {
+	var item = &etcd.Node{Key: "/foo/bar"}
+	var keys = func() (out []string) {
+		for _, e := range NewRoute(nil, item).Entries {
+			out = append(out, e.Key[len(item.Key)+1:])
+		}
+		return
+	}
+	item.Nodes = etcd.Nodes{
+		{Key: "/foo/bar/bbb", CreatedIndex: 20},
+		{Key: "/foo/bar/aaa", CreatedIndex: 10},
+	}
+	c.Check(keys(), gc.DeepEquals, []string{"aaa", "bbb"})
+
+	// A newly acquired entry is ordered after current entries, regardless of name.
+	item.Nodes = append(item.Nodes, &etcd.Node{Key: "/foo/bar/000", CreatedIndex: 30})
+	c.Check(keys(), gc.DeepEquals, []string{"aaa", "bbb", "000"})
+
+	// Refreshing an entry (a compare-and-set) updates its ModifiedIndex but
+	// not its CreatedIndex, and doesn't re-order it.
+	item.Nodes[1].ModifiedIndex = 40
+	c.Check(keys(), gc.DeepEquals, []string{"aaa", "bbb", "000"})
+
+	// Removing a replica retains the order of remaining entries.
+	item.Nodes = etcd.Nodes{item.Nodes[1], item.Nodes[2]}
+	c.Check(keys(), gc.DeepEquals, []string{"aaa", "000"})
+
+	// Only removal of the master promotes the next entry.
+	item.Nodes = append(item.Nodes, &etcd.Node{Key: "/foo/bar/ccc", CreatedIndex: 50})
+	item.Nodes = item.Nodes[1:]
+	c.Check(keys(), gc.DeepEquals, []string{"000", "ccc"})
+}
+
 func (s *RouteSuite) TestIndex(c *gc.C) {
 	rt := s.fixture()
 
@@ -0,0 +1,291 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+	"github.com/coreos/etcd/store"
+)
+
+// Simulation runs the allocation protocol over a scripted set of members,
+// entirely in memory, to model how items would be assigned by a cluster of a
+// given size (eg, for offline capacity planning). Members apply the same
+// actions that Allocate would, against a simulated Etcd keyspace. Rather than
+// racing concurrently, members take turns in rounds, each applying at most one
+// action per round, and a Simulation is thus deterministic for a given seed.
+//
+// All simulated items are always ready for promotion, and simulated time does
+// not advance (so locks are never refreshed due to expiry).
+type Simulation struct {
+	replicas int
+	items    []string
+
+	tree    *etcd.Node
+	index   uint64
+	now     time.Time
+	members []*simMember
+	rand    *rand.Rand
+}
+
+// SimulationReport summarizes a Simulation through convergence.
+type SimulationReport struct {
+	// Number of rounds taken to converge, where no member had an action.
+	Rounds int
+	// Number of applied actions, by ActionKind.
+	Actions map[ActionKind]int
+	// Number of acquired or released master or replica item locks, which
+	// represent changes of item assignment.
+	Churn int
+	// Members, including those which have left but still hold item locks.
+	Members int
+	// Items lacking a master, and having fewer than the required replicas.
+	Unmastered, UnderReplicated int
+	// Maximum, over members, of the member's fraction of all item locks
+	// (masters and replicas).
+	MaxLoadRatio float64
+}
+
+// NewSimulation returns a Simulation of |items| having |replicas|, which
+// makes random allocation choices seeded by |seed|.
+func NewSimulation(items []string, replicas int, seed int64) *Simulation {
+	var sorted = append([]string(nil), items...)
+	sort.Strings(sorted)
+
+	return &Simulation{
+		replicas: replicas,
+		items:    sorted,
+		tree:     &etcd.Node{Key: simulationRoot, Dir: true},
+		now:      time.Unix(0, 0),
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Join adds members having |keys| to the Simulation.
+func (s *Simulation) Join(keys ...string) error {
+	for _, key := range keys {
+		var m = &simMember{Simulation: s, key: key}
+
+		if err := Create(m); err != nil {
+			return fmt.Errorf("joining %s: %s", key, err)
+		}
+		s.members = append(s.members, m)
+	}
+	return nil
+}
+
+// Leave removes members having |keys| from the Simulation. As with Cancel,
+// members continue to participate until they've released held item locks.
+func (s *Simulation) Leave(keys ...string) error {
+	for _, key := range keys {
+		if err := Cancel(&simMember{Simulation: s, key: key}); err != nil {
+			return fmt.Errorf("leaving %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+// Converge runs rounds of allocation until members have no further actions,
+// or until |maxRounds| have run, in which case an error is returned alongside
+// a report of the current state.
+func (s *Simulation) Converge(maxRounds int) (SimulationReport, error) {
+	var report = SimulationReport{Actions: make(map[ActionKind]int)}
+
+	for report.Rounds = 1; report.Rounds <= maxRounds; report.Rounds++ {
+		var acted bool
+		var remaining = s.members[:0]
+
+		for _, m := range s.members {
+			var action, ok, err = s.step(m)
+			if err != nil {
+				return report, err
+			} else if ok {
+				acted = true
+				report.Actions[action.Kind]++
+
+				switch action.Kind {
+				case ActionAcquireMaster, ActionAcquireReplica, ActionReleaseMaster, ActionReleaseReplica:
+					report.Churn++
+				}
+			}
+			if ok || !m.exited {
+				remaining = append(remaining, m)
+			}
+		}
+		s.members = remaining
+
+		if !acted {
+			s.summarize(&report)
+			return report, nil
+		}
+	}
+	report.Rounds = maxRounds
+	s.summarize(&report)
+
+	return report, errors.New("allocation did not converge")
+}
+
+// step runs one iteration of allocation by |m|, as would Allocate. It
+// returns the applied Action, if any.
+func (s *Simulation) step(m *simMember) (Action, bool, error) {
+	var params = allocParams{Allocator: m, rand: s.rand, noDelay: true}
+	params.Input.Time = s.now
+	params.Input.Tree = s.tree
+	params.Input.Index = s.index
+
+	allocExtract(&params)
+	desiredMaster, desiredTotal := targetCounts(&params)
+
+	if response, err := allocAction(&params, desiredMaster, desiredTotal); err != nil {
+		return Action{}, false, fmt.Errorf("%s: %s", m.key, err)
+	} else if response == nil {
+		m.exited = nextDeadline(&params).IsZero()
+		return Action{}, false, nil
+	}
+	return params.Output.Action, true, nil
+}
+
+// summarize populates |report| from the current simulated keyspace.
+func (s *Simulation) summarize(report *SimulationReport) {
+	var locks = make(map[string]int)
+	var total int
+
+	WalkItems(s.tree, s.items, func(name string, route Route) {
+		if len(route.Entries) == 0 {
+			report.Unmastered++
+		}
+		if len(route.Entries) < s.replicas+1 {
+			report.UnderReplicated++
+		}
+		for i, entry := range route.Entries {
+			if i == s.replicas+1 {
+				break // Extra (lost-race) locks don't count towards load.
+			}
+			locks[entry.Key[len(route.Item.Key)+1:]]++
+			total++
+		}
+	})
+
+	report.Members = len(s.members)
+	for _, n := range locks {
+		if r := float64(n) / float64(total); r > report.MaxLoadRatio {
+			report.MaxLoadRatio = r
+		}
+	}
+}
+
+// apply applies a simulated Etcd mutation of |key|, and returns its Response.
+func (s *Simulation) apply(action, key, value string, ttl time.Duration) (*etcd.Response, error) {
+	var parent, ind = FindNode(s.tree, key)
+	var prev *etcd.Node
+
+	if ind < len(parent.Nodes) && parent.Nodes[ind].Key == key {
+		prev = parent.Nodes[ind]
+	}
+	s.index++
+
+	var node = &etcd.Node{Key: key, Value: value, CreatedIndex: s.index, ModifiedIndex: s.index}
+	if prev != nil {
+		node.CreatedIndex = prev.CreatedIndex
+	}
+	if ttl != 0 {
+		var expiration = s.now.Add(ttl)
+		node.Expiration, node.TTL = &expiration, int64(ttl.Seconds())
+	}
+
+	var response = &etcd.Response{Action: action, Node: node, PrevNode: prev, Index: s.index}
+	var err error
+
+	s.tree, err = PatchTree(s.tree, response)
+	return response, err
+}
+
+// simMember is an Allocator participating in a Simulation.
+type simMember struct {
+	*Simulation
+	key    string
+	exited bool // Whether the member has left, and released all items.
+}
+
+func (m *simMember) KeysAPI() etcd.KeysAPI                           { return simKeysAPI{m.Simulation} }
+func (m *simMember) PathRoot() string                                { return simulationRoot }
+func (m *simMember) InstanceKey() string                             { return m.key }
+func (m *simMember) Replicas() int                                   { return m.replicas }
+func (m *simMember) FixedItems() []string                            { return m.items }
+func (m *simMember) ItemState(item string) string                    { return "ready" }
+func (m *simMember) ItemIsReadyForPromotion(item, state string) bool { return state == "ready" }
+func (m *simMember) ItemRoute(string, Route, int, *etcd.Node)        {}
+
+// simKeysAPI is an etcd.KeysAPI of a Simulation's keyspace. It implements the
+// subset of the API used by the allocation protocol.
+type simKeysAPI struct{ *Simulation }
+
+func (api simKeysAPI) Set(ctx context.Context, key, value string, opts *etcd.SetOptions) (*etcd.Response, error) {
+	var cur = api.lookup(key)
+	var action = store.Set
+
+	if opts == nil {
+		opts = new(etcd.SetOptions)
+	}
+	if opts.PrevExist == etcd.PrevNoExist {
+		if cur != nil {
+			return nil, etcd.Error{Code: etcd.ErrorCodeNodeExist, Message: "Key already exists", Cause: key}
+		}
+		action = store.Create
+	}
+	if opts.PrevIndex != 0 {
+		if cur == nil {
+			return nil, etcd.Error{Code: etcd.ErrorCodeKeyNotFound, Message: "Key not found", Cause: key}
+		} else if cur.ModifiedIndex != opts.PrevIndex {
+			return nil, etcd.Error{Code: etcd.ErrorCodeTestFailed, Message: "Compare failed", Cause: key}
+		}
+		action = store.CompareAndSwap
+	}
+	return api.apply(action, key, value, opts.TTL)
+}
+
+func (api simKeysAPI) Delete(ctx context.Context, key string, opts *etcd.DeleteOptions) (*etcd.Response, error) {
+	var cur = api.lookup(key)
+	var action = store.Delete
+
+	if cur == nil {
+		return nil, etcd.Error{Code: etcd.ErrorCodeKeyNotFound, Message: "Key not found", Cause: key}
+	} else if opts != nil && opts.PrevIndex != 0 {
+		if cur.ModifiedIndex != opts.PrevIndex {
+			return nil, etcd.Error{Code: etcd.ErrorCodeTestFailed, Message: "Compare failed", Cause: key}
+		}
+		action = store.CompareAndDelete
+	}
+	return api.apply(action, key, "", 0)
+}
+
+func (api simKeysAPI) Get(context.Context, string, *etcd.GetOptions) (*etcd.Response, error) {
+	return nil, errSimulationUnsupported
+}
+func (api simKeysAPI) Create(context.Context, string, string) (*etcd.Response, error) {
+	return nil, errSimulationUnsupported
+}
+func (api simKeysAPI) CreateInOrder(context.Context, string, string, *etcd.CreateInOrderOptions) (*etcd.Response, error) {
+	return nil, errSimulationUnsupported
+}
+func (api simKeysAPI) Update(context.Context, string, string) (*etcd.Response, error) {
+	return nil, errSimulationUnsupported
+}
+func (api simKeysAPI) Watcher(string, *etcd.WatcherOptions) etcd.Watcher { return nil }
+
+// lookup returns the current node of |key|, or nil.
+func (api simKeysAPI) lookup(key string) *etcd.Node {
+	var parent, ind = FindNode(api.tree, key)
+	if ind < len(parent.Nodes) && parent.Nodes[ind].Key == key {
+		return parent.Nodes[ind]
+	}
+	return nil
+}
+
+const simulationRoot = "/simulation"
+
+var errSimulationUnsupported = errors.New("not supported by simulation")
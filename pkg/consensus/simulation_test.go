@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"fmt"
+
+	gc "github.com/go-check/check"
+)
+
+type SimulationSuite struct{}
+
+func (s *SimulationSuite) TestJoinAndLeave(c *gc.C) {
+	var items []string
+	for i := 0; i != 12; i++ {
+		items = append(items, fmt.Sprintf("item-%02d", i))
+	}
+	var sim = NewSimulation(items, 1, 42)
+
+	// A single member masters all items, but cannot replicate them.
+	c.Assert(sim.Join("m-1"), gc.IsNil)
+	var report, err = sim.Converge(1000)
+	c.Check(err, gc.IsNil)
+	c.Check(report.Actions[ActionAcquireMaster], gc.Equals, 12)
+	c.Check(report.Unmastered, gc.Equals, 0)
+	c.Check(report.UnderReplicated, gc.Equals, 12)
+	c.Check(report.MaxLoadRatio, gc.Equals, 1.0)
+
+	// Further members balance masters and replicas.
+	c.Assert(sim.Join("m-2", "m-3"), gc.IsNil)
+	report, err = sim.Converge(1000)
+	c.Check(err, gc.IsNil)
+	c.Check(report.Members, gc.Equals, 3)
+	c.Check(report.Churn > 12, gc.Equals, true)
+	c.Check(report.Unmastered, gc.Equals, 0)
+	c.Check(report.UnderReplicated, gc.Equals, 0)
+	c.Check(report.MaxLoadRatio, gc.Equals, 8.0/24.0)
+
+	// A member leaves, handing off its items. Once it's released all items,
+	// it exits the simulation.
+	c.Assert(sim.Leave("m-2"), gc.IsNil)
+	report, err = sim.Converge(1000)
+	c.Check(err, gc.IsNil)
+	c.Check(report.Members, gc.Equals, 2)
+	c.Check(report.Unmastered, gc.Equals, 0)
+	c.Check(report.UnderReplicated, gc.Equals, 0)
+	c.Check(report.MaxLoadRatio, gc.Equals, 0.5)
+
+	// Converging again is a no-op.
+	report, err = sim.Converge(1000)
+	c.Check(err, gc.IsNil)
+	c.Check(report.Rounds, gc.Equals, 1)
+	c.Check(report.Churn, gc.Equals, 0)
+}
+
+func (s *SimulationSuite) TestDeterminism(c *gc.C) {
+	var run = func(seed int64) SimulationReport {
+		var sim = NewSimulation([]string{"a", "b", "c", "d", "e"}, 1, seed)
+		c.Assert(sim.Join("m-1", "m-2", "m-3"), gc.IsNil)
+
+		var report, err = sim.Converge(1000)
+		c.Check(err, gc.IsNil)
+		return report
+	}
+	c.Check(run(7), gc.DeepEquals, run(7))
+}
+
+func (s *SimulationSuite) TestNonConvergence(c *gc.C) {
+	var sim = NewSimulation([]string{"a", "b"}, 0, 1)
+	c.Assert(sim.Join("m-1", "m-2"), gc.IsNil)
+
+	var report, err = sim.Converge(1)
+	c.Check(err, gc.ErrorMatches, "allocation did not converge")
+	c.Check(report.Rounds, gc.Equals, 1)
+}
+
+var _ = gc.Suite(&SimulationSuite{})
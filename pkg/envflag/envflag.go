@@ -10,25 +10,26 @@ import (
 )
 
 type parseableFromEnv interface {
-	parseFromEnv()
+	// parseFromEnv sets the flag of |fs| from the environment, unless the
+	// flag is among those already |set|.
+	parseFromEnv(fs *flag.FlagSet, set map[string]bool)
 }
 
 // stringFlag holds the metadata for an envflag representing a simple string. A
 // simple string envflag attempts to parse its value from a single environment
 // variable.
 type stringFlag struct {
+	// flagName is the name of the underlying flag.
+	flagName string
 	// envvarName is the name of the environment variable.
 	envvarName string
-	// ptr is the address of the string variable that stores the value of the
-	// flag. This typically is the return value of a flag.String call.
-	ptr *string
 }
 
 // parseFromEnv implements parseableFromEnv
-func (f stringFlag) parseFromEnv() {
+func (f stringFlag) parseFromEnv(fs *flag.FlagSet, set map[string]bool) {
 	var val = os.Getenv(f.envvarName)
-	if val != "" {
-		*f.ptr = val
+	if val != "" && !set[f.flagName] {
+		fs.Set(f.flagName, val) // A string flag cannot fail to Set.
 	}
 }
 
@@ -53,7 +54,7 @@ func NewFlagSet(fs *flag.FlagSet) *FlagSet {
 func (fs *FlagSet) String(flagName, envvarName, value, usage string) *string {
 	var ptr = fs.fs.String(flagName, value, fmt.Sprintf("%s (%s)", usage, envvarName))
 
-	fs.addFlag(envvarName, stringFlag{envvarName, ptr})
+	fs.addFlag(envvarName, stringFlag{flagName, envvarName})
 	return ptr
 }
 
@@ -65,11 +66,15 @@ func (fs *FlagSet) addFlag(name string, flag parseableFromEnv) {
 }
 
 // Parse parses configuration from environment variables and stores non-empty
-// values. It is recommended to call this after all flags are defined and
-// before flag.FlagSet.Parse.
+// values. Flags which were already set (eg, by a prior flag.FlagSet.Parse of
+// the command line) are left unchanged, so that the command line takes
+// precedence over the environment regardless of the order of parsing.
 func (fs *FlagSet) Parse() {
+	var set = make(map[string]bool)
+	fs.fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
 	for _, f := range fs.formal {
-		f.parseFromEnv()
+		f.parseFromEnv(fs.fs, set)
 	}
 }
 
@@ -34,6 +34,11 @@ func (s *envflagSuite) TestString(c *check.C) {
 	c.Check(*sut, check.Equals, "http://default.example/hello")
 	efs.Parse()
 	c.Check(*sut, check.Equals, "https://api.example/hello")
+
+	// A flag already set on the command line isn't overridden.
+	c.Check(fs.Parse([]string{"-dummyName", "http://cli.example/hello"}), check.IsNil)
+	efs.Parse()
+	c.Check(*sut, check.Equals, "http://cli.example/hello")
 }
 
 // unsetterFunc is a callback to unset an environment variable.
@@ -0,0 +1,123 @@
+package gazette
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AccessLog is middleware which logs structured entries of requests served
+// by the broker: the method, journal, peer, bytes received and sent, duration,
+// and response status. Requests are logged with a sampled probability, while
+// requests slower than a threshold, or which fail with a server error, are
+// always logged.
+type AccessLog struct {
+	handler       http.Handler
+	sampleRate    float64
+	slowThreshold time.Duration
+
+	logger    *log.Logger
+	randFloat func() float64
+	timeNow   func() time.Time
+}
+
+// NewAccessLog returns an AccessLog of |handler|. By default, no requests are
+// sampled, and only failed requests are logged.
+func NewAccessLog(handler http.Handler) *AccessLog {
+	return &AccessLog{
+		handler:   handler,
+		logger:    log.StandardLogger(),
+		randFloat: rand.Float64,
+		timeNow:   time.Now,
+	}
+}
+
+// SetSampleRate sets the probability with which a request is logged.
+func (l *AccessLog) SetSampleRate(rate float64) {
+	l.sampleRate = rate
+}
+
+// SetSlowThreshold sets the duration after which a request is always logged.
+// Zero disables logging of slow requests. Note that blocking reads are
+// expected to be long-lived.
+func (l *AccessLog) SetSlowThreshold(threshold time.Duration) {
+	l.slowThreshold = threshold
+}
+
+func (l *AccessLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var started = l.timeNow()
+	var rw = &accessLogWriter{ResponseWriter: w, status: http.StatusOK}
+	var body = &countingReadCloser{ReadCloser: r.Body}
+	r.Body = body
+
+	l.handler.ServeHTTP(rw, r)
+
+	var duration = l.timeNow().Sub(started)
+	var slow = l.slowThreshold != 0 && duration >= l.slowThreshold
+	var failed = rw.status >= http.StatusInternalServerError
+
+	if !slow && !failed && (l.sampleRate == 0 || l.randFloat() >= l.sampleRate) {
+		return
+	}
+	var entry = l.logger.WithFields(log.Fields{
+		"method":   r.Method,
+		"journal":  strings.TrimPrefix(r.URL.Path, "/"),
+		"peer":     r.RemoteAddr,
+		"bytesIn":  atomic.LoadInt64(&body.n),
+		"bytesOut": rw.n,
+		"duration": duration,
+		"status":   rw.status,
+	})
+
+	if failed {
+		entry.Warn("request failed")
+	} else if slow {
+		entry.Warn("slow request")
+	} else {
+		entry.Info("request")
+	}
+}
+
+// accessLogWriter is an http.ResponseWriter which captures the response
+// status and bytes written.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	var n, err = w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Flush flushes the wrapped ResponseWriter, if it's an http.Flusher.
+func (w *accessLogWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// countingReadCloser counts bytes read from the wrapped ReadCloser. Reads
+// may continue after the handler returns (eg, of a timed-out append).
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64 // Accessed atomically.
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	var n, err = r.ReadCloser.Read(p)
+	atomic.AddInt64(&r.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,115 @@
+package gazette
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	gc "github.com/go-check/check"
+	log "github.com/sirupsen/logrus"
+)
+
+type AccessLogSuite struct{}
+
+func (s *AccessLogSuite) TestLogging(c *gc.C) {
+	var delay time.Duration
+	var status int
+
+	var al = NewAccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+		w.Write([]byte("response"))
+	}))
+	// Capture entries of a private Logger.
+	var hook = new(captureHook)
+	al.logger = log.New()
+	al.logger.Out = ioutil.Discard
+	al.logger.Hooks.Add(hook)
+
+	var now = time.Unix(1500000000, 0)
+	al.timeNow = func() time.Time {
+		now = now.Add(delay)
+		return now
+	}
+	var sample float64
+	al.randFloat = func() float64 { return sample }
+
+	// serve returns the AccessLog entry of a request, if any.
+	var serve = func() *log.Entry {
+		hook.entries = nil
+		var r = httptest.NewRequest("PUT", "/a/journal", strings.NewReader("content"))
+		r.RemoteAddr = "1.2.3.4:5678"
+		al.ServeHTTP(httptest.NewRecorder(), r)
+
+		if len(hook.entries) == 0 {
+			return nil
+		}
+		c.Check(hook.entries, gc.HasLen, 1)
+		return hook.entries[0]
+	}
+
+	// Successful requests aren't logged by default.
+	status = http.StatusNoContent
+	c.Check(serve(), gc.IsNil)
+
+	// Requests failing with a server error are.
+	status = http.StatusServiceUnavailable
+	var e = serve()
+	c.Check(e.Level, gc.Equals, log.WarnLevel)
+	c.Check(e.Message, gc.Equals, "request failed")
+	c.Check(e.Data, gc.DeepEquals, log.Fields{
+		"method":   "PUT",
+		"journal":  "a/journal",
+		"peer":     "1.2.3.4:5678",
+		"bytesIn":  int64(7),
+		"bytesOut": int64(8),
+		"duration": time.Duration(0),
+		"status":   503,
+	})
+
+	// Requests are sampled.
+	status = http.StatusNoContent
+	al.SetSampleRate(0.5)
+	sample = 0.6
+	c.Check(serve(), gc.IsNil)
+	sample = 0.4
+	e = serve()
+	c.Check(e.Level, gc.Equals, log.InfoLevel)
+	c.Check(e.Message, gc.Equals, "request")
+
+	// Slow requests are always logged.
+	sample = 0.6
+	al.SetSlowThreshold(time.Second)
+	delay = time.Second
+	e = serve()
+	c.Check(e.Level, gc.Equals, log.WarnLevel)
+	c.Check(e.Message, gc.Equals, "slow request")
+	c.Check(e.Data["duration"], gc.Equals, time.Second)
+}
+
+func (s *AccessLogSuite) TestFlusherIsPreserved(c *gc.C) {
+	var al = NewAccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var _, ok = w.(http.Flusher)
+		c.Check(ok, gc.Equals, true)
+		w.(http.Flusher).Flush()
+	}))
+	var w = httptest.NewRecorder()
+	al.ServeHTTP(w, httptest.NewRequest("GET", "/a/journal", nil))
+	c.Check(w.Flushed, gc.Equals, true)
+}
+
+// captureHook is a log.Hook which captures fired entries.
+type captureHook struct {
+	entries []*log.Entry
+}
+
+func (h *captureHook) Levels() []log.Level { return log.AllLevels }
+
+func (h *captureHook) Fire(e *log.Entry) error {
+	h.entries = append(h.entries, e)
+	return nil
+}
+
+var _ = gc.Suite(&AccessLogSuite{})
@@ -0,0 +1,249 @@
+package gazette
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/LiveRamp/gazette/pkg/cloudstore"
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+// Benchmarks of append and read throughput through the HTTP APIs of an
+// in-process broker. Run with:
+//   go test ./pkg/gazette -run NONE -bench . -benchmem
+
+func BenchmarkAppend(b *testing.B) {
+	for _, size := range []int{128, 4096, 65536} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			var cluster = newBenchCluster(b, 4)
+			defer cluster.close()
+
+			var content = bytes.Repeat([]byte("x"), size)
+			var next int64
+			var lat latencies
+
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				var name = cluster.journals[int(atomic.AddInt64(&next, 1)-1)%len(cluster.journals)]
+
+				for pb.Next() {
+					var started = time.Now()
+					var result = cluster.client.Put(journal.AppendArgs{
+						Journal: name,
+						Content: bytes.NewReader(content),
+						Context: context.Background(),
+					})
+					if result.Error != nil {
+						b.Error(result.Error)
+						return
+					}
+					lat.add(time.Since(started))
+				}
+			})
+			lat.report(b)
+		})
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	var cluster = newBenchCluster(b, 1)
+	defer cluster.close()
+
+	// Write a journal fixture of 64MB, to be read by each iteration.
+	const chunk, chunks = 1 << 20, 64
+	var content = bytes.Repeat([]byte("x"), chunk)
+
+	for i := 0; i != chunks; i++ {
+		if result := cluster.client.Put(journal.AppendArgs{
+			Journal: cluster.journals[0],
+			Content: bytes.NewReader(content),
+			Context: context.Background(),
+		}); result.Error != nil {
+			b.Fatal(result.Error)
+		}
+	}
+
+	b.SetBytes(chunk * chunks)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var rr = journal.NewRetryReaderContext(context.Background(),
+				journal.Mark{Journal: cluster.journals[0]}, cluster.client)
+			rr.Blocking = false
+
+			var n, err = io.Copy(ioutil.Discard, rr)
+			rr.Close()
+
+			if err != journal.ErrNotYetAvailable {
+				b.Error(err)
+				return
+			} else if n != chunk*chunks {
+				b.Errorf("read %d bytes", n)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkAppendWithReaders measures append throughput while concurrent
+// readers tail each journal.
+func BenchmarkAppendWithReaders(b *testing.B) {
+	for _, readers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("readers=%d", readers), func(b *testing.B) {
+			var cluster = newBenchCluster(b, 4)
+			defer cluster.close()
+
+			var ctx, cancel = context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			var read int64
+
+			for i := 0; i != readers; i++ {
+				wg.Add(1)
+
+				go func(name journal.Name) {
+					defer wg.Done()
+
+					var rr = journal.NewRetryReaderContext(ctx, journal.Mark{Journal: name}, cluster.client)
+					defer rr.Close()
+
+					var n, _ = io.Copy(ioutil.Discard, rr)
+					atomic.AddInt64(&read, n)
+				}(cluster.journals[i%len(cluster.journals)])
+			}
+
+			var content = bytes.Repeat([]byte("x"), 4096)
+			var next int64
+			var lat latencies
+
+			b.SetBytes(int64(len(content)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			var began = time.Now()
+
+			b.RunParallel(func(pb *testing.PB) {
+				var name = cluster.journals[int(atomic.AddInt64(&next, 1)-1)%len(cluster.journals)]
+
+				for pb.Next() {
+					var started = time.Now()
+					var result = cluster.client.Put(journal.AppendArgs{
+						Journal: name,
+						Content: bytes.NewReader(content),
+						Context: context.Background(),
+					})
+					if result.Error != nil {
+						b.Error(result.Error)
+						return
+					}
+					lat.add(time.Since(started))
+				}
+			})
+			b.StopTimer()
+			var elapsed = time.Since(began)
+
+			cancel()
+			wg.Wait()
+
+			lat.report(b)
+			b.Logf("read: %.2f MB/s", float64(atomic.LoadInt64(&read))/elapsed.Seconds()/(1<<20))
+		})
+	}
+}
+
+// benchCluster is an in-process broker of |journals|, served over HTTP.
+type benchCluster struct {
+	dir      string
+	cfs      cloudstore.FileSystem
+	router   *Router
+	server   *httptest.Server
+	client   *Client
+	journals []journal.Name
+}
+
+func newBenchCluster(b *testing.B, numJournals int) *benchCluster {
+	var dir, err = ioutil.TempDir("", "broker-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var c = &benchCluster{
+		dir: dir,
+		cfs: cloudstore.NewTmpFileSystem(),
+	}
+	c.router = NewRouter(func(name journal.Name) JournalReplica {
+		return journal.NewReplica(name, dir, discardPersister{}, c.cfs, 0, nil)
+	})
+
+	var m = mux.NewRouter()
+	NewReadAPI(c.router, c.cfs).Register(m)
+	NewWriteAPI(c.router).Register(m)
+	c.server = httptest.NewServer(m)
+
+	if c.client, err = NewClient(c.server.URL); err != nil {
+		b.Fatal(err)
+	}
+	// Broker each journal locally, without replication.
+	for i := 0; i != numJournals; i++ {
+		var name = journal.Name(fmt.Sprintf("bench/journal-%03d", i))
+
+		// The fragment index of each journal must exist, to be listed.
+		if err = c.cfs.MkdirAll(name.String(), 0750); err != nil {
+			b.Fatal(err)
+		}
+		c.router.transition(name, journal.RouteToken(c.server.URL), 0, 0)
+		c.journals = append(c.journals, name)
+	}
+	return c
+}
+
+func (c *benchCluster) close() {
+	// Shut down replicas first, which fails blocked reads still being served.
+	for _, name := range c.journals {
+		c.router.transition(name, "", -1, 0)
+	}
+	c.server.Close()
+	c.cfs.Close()
+	os.RemoveAll(c.dir)
+}
+
+// discardPersister retains fragments locally, rather than persisting them.
+type discardPersister struct{}
+
+func (discardPersister) Persist(journal.Fragment) {}
+
+// latencies collects operation latencies of concurrent benchmark workers.
+type latencies struct {
+	mu sync.Mutex
+	d  []time.Duration
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	l.d = append(l.d, d)
+	l.mu.Unlock()
+}
+
+// report logs p50 and p99 latencies of the benchmark.
+func (l *latencies) report(b *testing.B) {
+	if len(l.d) == 0 {
+		return
+	}
+	sort.Slice(l.d, func(i, j int) bool { return l.d[i] < l.d[j] })
+
+	b.Logf("latency (N=%d): p50 %s, p99 %s", b.N, l.d[len(l.d)*50/100], l.d[len(l.d)*99/100])
+}
@@ -1,6 +1,12 @@
 package gazette
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"expvar"
 	"fmt"
@@ -10,6 +16,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,7 +26,6 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/LiveRamp/gazette/pkg/journal"
-	"github.com/LiveRamp/gazette/pkg/keepalive"
 	"github.com/LiveRamp/gazette/pkg/metrics"
 )
 
@@ -33,15 +39,28 @@ const (
 	// discover direct, responsible endpoints for journals it uses.
 	kClientRouteCacheSize = 1024
 
+	// Maximum number of redirects followed by a request. A request is normally
+	// redirected at most once, to the responsible broker of its journal. Longer
+	// chains indicate brokers having diverged views of journal routes.
+	kClientMaxRedirects = 3
+
 	statsJournalBytes = "bytes"
 	statsJournalHead  = "head"
 )
 
+// Cool-off applied by WaitForHead between retries of failed or unsatisfied reads.
+var waitForHeadCoolOff = time.Second
+
 type httpClient interface {
 	Do(*http.Request) (*http.Response, error)
 	Get(url string) (*http.Response, error)
 }
 
+type clusterEndpoint struct {
+	prefix   journal.Prefix
+	endpoint *url.URL
+}
+
 type requestData struct {
 	Method    string
 	Timestamp time.Time
@@ -57,6 +76,9 @@ var kContentRangeRegexp = regexp.MustCompile("bytes\\s+(\\d+)-\\d+/\\d+")
 type Client struct {
 	// Endpoint which is queried by default.
 	defaultEndpoint *url.URL
+	// Endpoints of other clusters, which are queried by default for journals
+	// having a matching prefix. Ordered on descending prefix length.
+	clusterEndpoints []clusterEndpoint
 
 	// Maps request.URL.Path to previously-received "Location:" headers,,
 	// stripped of URL query arguments. Future requests of the same URL path are
@@ -73,6 +95,17 @@ type Client struct {
 	// requests.
 	requests *currentRequestList
 
+	// Whether persisted fragment content is verified against its SHA1 sum.
+	verifySums bool
+	// Policy by which failed operations are retried.
+	retryPolicy RetryPolicy
+	// Tracks consecutive request failures of broker endpoints.
+	breaker circuitBreaker
+	// Hedges slow reads to other route members, if enabled.
+	hedger *hedger
+	// Local cache of persisted fragments, if enabled.
+	fragmentCache *fragmentCache
+
 	// Underlying HTTP Client to use for all requests.
 	httpClient httpClient
 	// Test support: allow time.Now() to be swapped out.
@@ -85,13 +118,16 @@ func NewClient(endpoint string) (*Client, error) {
 	return NewClientWithHttpClient(endpoint, &http.Client{})
 }
 
-func NewClientWithHttpClient(endpoint string, hc *http.Client) (*Client, error) {
-	// Assume HTTP if no protocol is specified.
-	if strings.Index(endpoint, "://") == -1 {
-		endpoint = "http://" + endpoint
-	}
+// NewClientWithTLS returns a new Client which uses |cfg| for connections to
+// https:// endpoints. See NewClientTLSConfig.
+func NewClientWithTLS(endpoint string, cfg *tls.Config) (*Client, error) {
+	var transport = MakeHttpTransport()
+	transport.TLSClientConfig = cfg
+	return NewClientWithHttpClient(endpoint, &http.Client{Transport: transport})
+}
 
-	ep, err := url.Parse(endpoint)
+func NewClientWithHttpClient(endpoint string, hc *http.Client) (*Client, error) {
+	ep, err := ParseEndpoint(endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -106,12 +142,16 @@ func NewClientWithHttpClient(endpoint string, hc *http.Client) (*Client, error)
 	if hc.Transport == nil {
 		hc.Transport = MakeHttpTransport()
 	}
+	if hc.CheckRedirect == nil {
+		hc.CheckRedirect = checkRedirect
+	}
 
 	c := &Client{
 		defaultEndpoint: ep,
 		locationCache:   cache,
 		httpClient:      hc,
 		requests:        &currentRequestList{m: make(map[string]requestData)},
+		retryPolicy:     DefaultRetryPolicy,
 		timeNow:         time.Now,
 	}
 
@@ -126,6 +166,35 @@ func NewClientWithHttpClient(endpoint string, hc *http.Client) (*Client, error)
 	return c, nil
 }
 
+// AddClusterEndpoint routes requests of journals prefixed by |prefix| to
+// |endpoint|, rather than the Client's default endpoint. This allows a single
+// Client to transparently read and write journals of multiple Gazette
+// clusters. Where prefixes overlap, the longest matching prefix is used.
+// AddClusterEndpoint must be called before the Client is used.
+func (c *Client) AddClusterEndpoint(prefix, endpoint string) error {
+	ep, err := ParseEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+	c.clusterEndpoints = append(c.clusterEndpoints, clusterEndpoint{journal.Prefix(prefix), ep})
+
+	sort.SliceStable(c.clusterEndpoints, func(i, j int) bool {
+		return len(c.clusterEndpoints[i].prefix) > len(c.clusterEndpoints[j].prefix)
+	})
+	return nil
+}
+
+// endpointFor returns the default endpoint for the request |path|, which is
+// "/" followed by the journal name.
+func (c *Client) endpointFor(path string) *url.URL {
+	for _, ce := range c.clusterEndpoints {
+		if ce.prefix.Matches(journal.Name(strings.TrimPrefix(path, "/"))) {
+			return ce.endpoint
+		}
+	}
+	return c.defaultEndpoint
+}
+
 // If you want to use your own |http.Transport| with Gazette, start with this one.
 func MakeHttpTransport() *http.Transport {
 	// See definition of |http.DefaultTransport| here:
@@ -133,7 +202,8 @@ func MakeHttpTransport() *http.Transport {
 	// We don't use |http.DefaultTransport| itself, as it is difficult to
 	// deep-copy it.
 	var httpTransport = &http.Transport{
-		Dial: keepalive.Dialer.Dial,
+		// Dials TCP addresses, as well as unix:// endpoints. See ParseEndpoint.
+		Dial: dialEndpoint,
 		// Force cloud storage to decompress fragments. Go's standard `gzip`
 		// package is several times slower than zlib, and we additionally see a
 		// parallelism benefit when multiple fragments are fetched concurrently.
@@ -151,7 +221,19 @@ func MakeHttpTransport() *http.Transport {
 	return httpTransport
 }
 
-func (c *Client) Head(args journal.ReadArgs) (journal.ReadResult, *url.URL) {
+func (c *Client) Head(args journal.ReadArgs) (result journal.ReadResult, location *url.URL) {
+	c.withRetries(args.Context, true, func(ctx context.Context) error {
+		var attempt = args
+		attempt.Context = ctx
+
+		result, location = c.head(attempt)
+		return result.Error
+	})
+	return
+}
+
+// head performs a single attempt of Head.
+func (c *Client) head(args journal.ReadArgs) (journal.ReadResult, *url.URL) {
 	request, err := http.NewRequest("HEAD", c.buildReadURL(args).String(), nil)
 	if err != nil {
 		return journal.ReadResult{Error: err}, nil
@@ -166,10 +248,104 @@ func (c *Client) Head(args journal.ReadArgs) (journal.ReadResult, *url.URL) {
 
 	result, fragmentLocation := c.parseReadResult(args, response)
 	response.Body.Close()
+
+	if c.hedger != nil {
+		c.hedger.observeRoute(args.Journal, result.RouteToken)
+	}
 	return result, fragmentLocation
 }
 
-func (c *Client) GetDirect(args journal.ReadArgs) (journal.ReadResult, io.ReadCloser) {
+// WaitForHead blocks until the write head of journal |name| is at least
+// |offset|, and returns an observed write head of at least |offset|. This is
+// a barrier which ensures all content written through |offset| is readable,
+// and is implemented as a blocking read of the byte at |offset-1|. Failed or
+// unsatisfied reads are retried after a cool-off. WaitForHead returns early
+// with an error if |ctx| is cancelled, or if a non-retryable error is
+// encountered.
+func (c *Client) WaitForHead(ctx context.Context, name journal.Name, offset int64) (int64, error) {
+	for {
+		var head, err = c.awaitHead(ctx, name, offset)
+
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		} else if err == nil && head >= offset {
+			return head, nil
+		} else if err != nil && journal.RetryabilityOf(err) == journal.NotRetryable {
+			return 0, err
+		} else if err != nil {
+			log.WithFields(log.Fields{"journal": name, "offset": offset, "err": err}).
+				Warn("waiting for write head (will retry)")
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(waitForHeadCoolOff):
+		}
+	}
+}
+
+// awaitHead performs a single attempt of WaitForHead, returning the write
+// head it observed.
+func (c *Client) awaitHead(ctx context.Context, name journal.Name, offset int64) (int64, error) {
+	if offset <= 0 {
+		// Any write head satisfies |offset|. Query the current head without blocking.
+		var result, _ = c.Head(journal.ReadArgs{Journal: name, Offset: -1, Context: ctx})
+		if result.Error == journal.ErrNotYetAvailable {
+			result.Error = nil
+		}
+		return result.WriteHead, result.Error
+	}
+
+	var result, body = c.GetDirect(journal.ReadArgs{
+		Journal:  name,
+		Offset:   offset - 1, // Readable content at |offset-1| implies head >= |offset|.
+		Blocking: true,
+		Context:  ctx,
+	})
+	if result.Error == journal.ErrNotYetAvailable {
+		return result.WriteHead, nil
+	} else if result.Error != nil {
+		return 0, result.Error
+	}
+	defer body.Close()
+
+	if result.WriteHead >= offset {
+		return result.WriteHead, nil
+	}
+	// The broker responded before content at |offset-1| was available, and
+	// streams it once it is.
+	if _, err := io.ReadFull(body, make([]byte, 1)); err == io.EOF {
+		return result.WriteHead, nil // The read ended without content.
+	} else if err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+func (c *Client) GetDirect(args journal.ReadArgs) (result journal.ReadResult, body io.ReadCloser) {
+	c.withRetries(args.Context, false, func(ctx context.Context) error {
+		var attempt = args
+		attempt.Context = ctx
+
+		result, body = c.getDirect(attempt)
+		return result.Error
+	})
+	return
+}
+
+// getDirect performs a single attempt of GetDirect.
+func (c *Client) getDirect(args journal.ReadArgs) (journal.ReadResult, io.ReadCloser) {
+	if c.hedger != nil && !args.Blocking {
+		return c.hedgedGetDirect(args)
+	}
+	return c.getDirectVia(args, c.Do)
+}
+
+// getDirectVia performs a GetDirect request using |do|.
+func (c *Client) getDirectVia(args journal.ReadArgs,
+	do func(*http.Request) (*http.Response, error)) (journal.ReadResult, io.ReadCloser) {
+
 	request, err := http.NewRequest("GET", c.buildReadURL(args).String(), nil)
 	if err != nil {
 		return journal.ReadResult{Error: err}, nil
@@ -177,12 +353,15 @@ func (c *Client) GetDirect(args journal.ReadArgs) (journal.ReadResult, io.ReadCl
 	if args.Context != nil {
 		request = request.WithContext(args.Context)
 	}
-	response, err := c.Do(request)
+	response, err := do(request)
 	if err != nil {
 		return journal.ReadResult{Error: err}, nil
 	}
 
 	result, _ := c.parseReadResult(args, response)
+	if c.hedger != nil {
+		c.hedger.observeRoute(args.Journal, result.RouteToken)
+	}
 	if result.Error != nil {
 		response.Body.Close()
 		return result, nil
@@ -261,6 +440,20 @@ func (c *Client) makeReadStatsWrapper(stream io.ReadCloser, name journal.Name, o
 	}
 }
 
+// SetVerifySums sets whether content of persisted fragments read by the Client
+// is verified against the fragment SHA1 sum. As a fragment is always fetched
+// from its beginning, its content is verified upon reading through its end.
+// Corrupt content fails the read with journal.ErrSumMismatch.
+//
+// It also sets whether content appended by Put is sent with its SHA1 sum
+// (as it always is, if AppendArgs.Producer is set). A broker verifies appended
+// content against the sum before committing it, and fails an append of
+// content which was corrupted or truncated in transit with
+// journal.ErrAppendSumMismatch.
+func (c *Client) SetVerifySums(verify bool) {
+	c.verifySums = verify
+}
+
 // Returns a reader by reading directly from a fragment. |location| is a
 // potentially signed or authorized URL to fragment storage. The fragment is
 // opened, seek'd to the desired |result.Offset|, and returned. Note we don't
@@ -269,6 +462,14 @@ func (c *Client) makeReadStatsWrapper(stream io.ReadCloser, name journal.Name, o
 func (c *Client) openFragment(location *url.URL,
 	result journal.ReadResult) (io.ReadCloser, error) {
 
+	if c.fragmentCache != nil {
+		if file, ok := c.fragmentCache.open(result.Fragment, result.Offset); ok {
+			metrics.GazetteFragmentCacheReadsTotal.WithLabelValues("hit").Inc()
+			return file, nil
+		}
+		metrics.GazetteFragmentCacheReadsTotal.WithLabelValues("miss").Inc()
+	}
+
 	response, err := c.httpClient.Get(location.String())
 	if err != nil {
 		return nil, err
@@ -276,28 +477,45 @@ func (c *Client) openFragment(location *url.URL,
 		response.Body.Close()
 		return nil, fmt.Errorf("fetching fragment: %s", response.Status)
 	}
+
+	var body = response.Body
+	if c.verifySums {
+		body = journal.NewSumVerifyingReader(result.Fragment, body)
+	}
+	if c.fragmentCache != nil {
+		body = c.fragmentCache.fill(result.Fragment, body)
+	}
 	// Attempt to seek to |result.Offset| within the fragment.
 	delta := result.Offset - result.Fragment.Begin
-	if _, err := io.CopyN(ioutil.Discard, response.Body, delta); err != nil {
-		response.Body.Close()
+	if _, err := io.CopyN(ioutil.Discard, body, delta); err != nil {
+		body.Close()
 		return nil, fmt.Errorf("seeking fragment: %s", err)
 	}
 
 	var deltaF64 = float64(delta)
 	metrics.GazetteReadBytesTotal.Add(deltaF64)
 	metrics.GazetteDiscardBytesTotal.Add(deltaF64)
-	return response.Body, nil // Success.
+	return body, nil // Success.
 }
 
 // Creates the Journal of the given name.
 func (c *Client) Create(name journal.Name) error {
-	url := c.defaultEndpoint // Copy.
+	return c.withRetries(nil, true, func(ctx context.Context) error {
+		return c.create(ctx, name)
+	})
+}
+
+// create performs a single attempt of Create.
+func (c *Client) create(ctx context.Context, name journal.Name) error {
+	url := *c.endpointFor("/" + name.String()) // Copy.
 	url.Path = "/" + name.String()
 
 	request, err := http.NewRequest("POST", url.String(), nil)
 	if err != nil {
 		return err
 	}
+	request = request.WithContext(ctx)
+
 	// Issue the request without using or updating the Journal location cache.
 	response, err := c.httpClient.Do(request)
 	if err != nil {
@@ -307,15 +525,47 @@ func (c *Client) Create(name journal.Name) error {
 }
 
 // Performs a Gazette PUT operation, which appends content to the named journal.
-// Put panics if |args.Content| does not implement io.ReadSeeker.
-func (c *Client) Put(args journal.AppendArgs) journal.AppendResult {
+// Put panics if |args.Content| does not implement io.ReadSeeker. Failed
+// attempts are retried only if |args.Producer| is set (see RetryPolicy).
+func (c *Client) Put(args journal.AppendArgs) (result journal.AppendResult) {
+	var rs = args.Content.(io.ReadSeeker)
+
+	// Retried attempts re-send Content from its initial offset.
+	var start, err = rs.Seek(0, os.SEEK_CUR)
+	if err != nil {
+		return journal.AppendResult{Error: err}
+	}
+	c.withRetries(args.Context, true, func(ctx context.Context) error {
+		if _, err := rs.Seek(start, os.SEEK_SET); err != nil {
+			result = journal.AppendResult{Error: err}
+			return nil // Not retryable.
+		}
+		var attempt = args
+		attempt.Context = ctx
+
+		result = c.put(attempt)
+
+		if args.Producer == "" && journal.RetryabilityOf(result.Error) == journal.Retryable {
+			return nil // Not safely retryable (see RetryPolicy).
+		}
+		return result.Error
+	})
+	return
+}
+
+// put performs a single attempt of Put.
+func (c *Client) put(args journal.AppendArgs) journal.AppendResult {
 	request, err := http.NewRequest("PUT", "/"+args.Journal.String(), args.Content)
 	if err != nil {
 		return journal.AppendResult{Error: err}
 	}
+	if args.Context != nil {
+		request = request.WithContext(args.Context)
+	}
 	if _, ok := c.locationCache.Get(request.URL.Path); !ok {
 		// Speculatively issue a HEAD to fill the location cache for this path.
-		result, _ := c.Head(journal.ReadArgs{Journal: args.Journal, Blocking: false, Offset: -1})
+		result, _ := c.head(journal.ReadArgs{Journal: args.Journal, Blocking: false, Offset: -1,
+			Context: args.Context})
 		if result.Error != nil && result.Error != journal.ErrNotYetAvailable {
 			return journal.AppendResult{Error: result.Error}
 		}
@@ -330,6 +580,24 @@ func (c *Client) Put(args journal.AppendArgs) journal.AppendResult {
 		request.ContentLength = end - start
 	}
 
+	if args.Producer != "" || c.verifySums {
+		// Send a checksum of the content. A broker verifies content against it
+		// before committing, and may recognize a retry of a Producer's append
+		// which already committed.
+		var sum = sha1.New()
+		if start, err := rs.Seek(0, os.SEEK_CUR); err != nil {
+			return journal.AppendResult{Error: err}
+		} else if _, err = io.Copy(sum, rs); err != nil {
+			return journal.AppendResult{Error: err}
+		} else if _, err = rs.Seek(start, os.SEEK_SET); err != nil {
+			return journal.AppendResult{Error: err}
+		}
+		if args.Producer != "" {
+			request.Header.Set(AppendProducerHeader, args.Producer)
+		}
+		request.Header.Set(AppendSumHeader, hex.EncodeToString(sum.Sum(nil)))
+	}
+
 	response, err := c.Do(request)
 	if err != nil {
 		return journal.AppendResult{Error: err}
@@ -347,6 +615,72 @@ func (c *Client) Put(args journal.AppendArgs) journal.AppendResult {
 	return result
 }
 
+// PutRecords appends the content of |r| to journal |args.Journal| as a
+// sequence of Puts, each of at most |maxSize| bytes. Content is split only at
+// record boundaries, as determined by |split|: each |advance| returned by
+// |split| delimits a complete record (including any delimiter). For example,
+// bufio.ScanLines splits on newlines. Records larger than |maxSize| fail with
+// bufio.ErrTooLong. |args.Content| is ignored.
+//
+// PutRecords returns the AppendResult of the last Put. If an error occurs,
+// content of prior Puts has already been committed, and |written| is the
+// number of bytes of |r| committed.
+func (c *Client) PutRecords(args journal.AppendArgs, r io.Reader, maxSize int,
+	split bufio.SplitFunc) (result journal.AppendResult, written int64) {
+	return putRecords(c, args, r, maxSize, split)
+}
+
+func putRecords(putter interface {
+	Put(journal.AppendArgs) journal.AppendResult
+}, args journal.AppendArgs, r io.Reader, maxSize int,
+	split bufio.SplitFunc) (result journal.AppendResult, written int64) {
+
+	var scanner = bufio.NewScanner(r)
+	// Note the maximum record size is the larger of |maxSize| and the initial buffer.
+	var initial = 4096
+	if initial > maxSize {
+		initial = maxSize
+	}
+	scanner.Buffer(make([]byte, 0, initial), maxSize)
+
+	// Scan records, returning each as its full content (eg, with delimiter).
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		var advance, _, err = split(data, atEOF)
+		if advance == 0 {
+			return 0, nil, err // More data is required.
+		}
+		return advance, data[:advance], err
+	})
+
+	var chunk []byte
+	var flush = func() bool {
+		args.Content = bytes.NewReader(chunk)
+		if result = putter.Put(args); result.Error != nil {
+			return false
+		}
+		written += int64(len(chunk))
+		chunk = chunk[:0]
+		return true
+	}
+
+	for scanner.Scan() {
+		var record = scanner.Bytes()
+
+		if len(chunk) != 0 && len(chunk)+len(record) > maxSize && !flush() {
+			return
+		}
+		chunk = append(chunk, record...)
+	}
+	if err := scanner.Err(); err != nil {
+		result.Error = err
+		return
+	}
+	if len(chunk) != 0 {
+		flush()
+	}
+	return
+}
+
 func (c *Client) buildReadURL(args journal.ReadArgs) *url.URL {
 	v := url.Values{
 		"offset": {strconv.FormatInt(args.Offset, 10)},
@@ -402,6 +736,8 @@ func (c *Client) parseReadResult(args journal.ReadArgs,
 		}
 	}
 
+	result.RouteToken = journal.RouteToken(response.Header.Get(RouteTokenHeader))
+
 	// Attach |RemoteModTime| if possible.
 	var fragmentLastModifiedStr = response.Header.Get(FragmentLastModifiedHeader)
 	if fragmentLastModifiedStr != "" {
@@ -484,12 +820,41 @@ func (c *Client) parseAppendResponse(response *http.Response) journal.AppendResu
 	return result
 }
 
+// Roll requests that the broker of journal |name| roll the spools of its
+// replicas, causing content written to date to be persisted to the fragment
+// store. The returned AppendResult is that of the transaction which rolled.
+func (c *Client) Roll(name journal.Name) (result journal.AppendResult) {
+	c.withRetries(nil, true, func(ctx context.Context) error {
+		result = c.roll(ctx, name)
+		return result.Error
+	})
+	return
+}
+
+// roll performs a single attempt of Roll.
+func (c *Client) roll(ctx context.Context, name journal.Name) journal.AppendResult {
+	request, err := http.NewRequest("ROLL", "/"+name.String(), nil)
+	if err != nil {
+		return journal.AppendResult{Error: err}
+	}
+	request = request.WithContext(ctx)
+	response, err := c.Do(request)
+	if err != nil {
+		return journal.AppendResult{Error: err}
+	}
+	defer response.Body.Close()
+
+	return c.parseAppendResponse(response)
+}
+
 // Thin layer upon http.Do(), which manages re-writes from and update to the
 // Client.locationCache. Specifically, request.Path is mapped into a previously-
 // stored Location re-write. If none is available, the request is re-written to
 // reference the default endpoint. Cache entries are updated on successful
 // redirect or response with a Location: header. On error, cache entries are
 // expunged (eg, future requests are performed against the default endpoint).
+// If the RetryPolicy enables a circuit breaker, requests of an endpoint having
+// an open breaker fail with ErrCircuitOpen.
 func (c *Client) Do(request *http.Request) (*http.Response, error) {
 	var cacheKey = request.URL.Path // We may mutate |request| later.
 
@@ -502,17 +867,32 @@ func (c *Client) Do(request *http.Request) (*http.Response, error) {
 		request.URL.Path = location.Path
 		// Note that RawQuery is not re-written.
 	} else {
-		// Otherwise, re-write to use the default endpoint.
-		request.URL.Scheme = c.defaultEndpoint.Scheme
-		request.URL.User = c.defaultEndpoint.User
-		request.URL.Host = c.defaultEndpoint.Host
+		// Otherwise, re-write to use the default endpoint of the path.
+		var ep = c.endpointFor(cacheKey)
+		request.URL.Scheme = ep.Scheme
+		request.URL.User = ep.User
+		request.URL.Host = ep.Host
 		// Note that Path & RawQuery are not re-written.
 	}
 
+	var host = request.URL.Host
+	if c.retryPolicy.BreakerThreshold != 0 {
+		if err := c.breaker.allow(host, c.timeNow()); err != nil {
+			return nil, err
+		}
+	}
+
 	c.requests.Put(request.URL.String(), requestData{request.Method, c.timeNow()})
 	defer c.requests.Delete(request.URL.String())
 
 	response, err := c.httpClient.Do(request)
+
+	if c.retryPolicy.BreakerThreshold != 0 {
+		// Requests cancelled by the caller don't reflect on the endpoint.
+		var failed = (err != nil && request.Context().Err() == nil) ||
+			(err == nil && response.StatusCode >= http.StatusInternalServerError)
+		c.breaker.observe(host, failed, c.timeNow(), c.retryPolicy)
+	}
 	if err != nil {
 		c.locationCache.Remove(cacheKey)
 		return response, err
@@ -533,6 +913,32 @@ func (c *Client) Do(request *http.Request) (*http.Response, error) {
 	return response, err
 }
 
+// checkRedirect bounds redirect chains to kClientMaxRedirects, and fails
+// requests which loop between brokers. The visited chain is logged.
+func checkRedirect(request *http.Request, via []*http.Request) error {
+	var chain = make([]string, 0, len(via)+1)
+	var loop bool
+
+	for _, r := range via {
+		chain = append(chain, r.URL.String())
+		loop = loop || r.URL.String() == request.URL.String()
+	}
+	chain = append(chain, request.URL.String())
+
+	var err error
+	if loop {
+		err = fmt.Errorf("redirect loop: %s", strings.Join(chain, " -> "))
+	} else if len(via) > kClientMaxRedirects {
+		err = fmt.Errorf("stopped after %d redirects: %s", kClientMaxRedirects,
+			strings.Join(chain, " -> "))
+	} else {
+		return nil
+	}
+	log.WithFields(log.Fields{"chain": chain, "method": request.Method}).
+		Warn("broker redirect chain exceeded")
+	return err
+}
+
 // Returns the |Fragment| whose Modified time is closest to but prior to the
 // given |t|. Can return a zeroed Fragment structure, if no fragment matches.
 func (c *Client) FragmentBeforeTime(name journal.Name, t time.Time) (journal.Fragment, error) {
@@ -1,20 +1,29 @@
 package gazette
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha1"
 	"errors"
 	"expvar"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	gc "github.com/go-check/check"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/LiveRamp/gazette/pkg/cloudstore"
 	"github.com/LiveRamp/gazette/pkg/journal"
 )
 
@@ -116,6 +125,78 @@ func (s *ClientSuite) TestHeadRequestWithInvalidation(c *gc.C) {
 	c.Check(ok, gc.Equals, false)
 }
 
+func (s *ClientSuite) TestWaitForHead(c *gc.C) {
+	defer func(d time.Duration) { waitForHeadCoolOff = d }(waitForHeadCoolOff)
+	waitForHeadCoolOff = time.Millisecond
+
+	var dir, err = ioutil.TempDir("", "client-suite")
+	c.Assert(err, gc.IsNil)
+	defer os.RemoveAll(dir)
+
+	var cfs = cloudstore.NewTmpFileSystem()
+	defer cfs.Close()
+
+	// Serve reads of a journal Tail via a ReadAPI, counting requests.
+	var updates = make(chan journal.Fragment)
+	var tail = journal.NewTail("a/journal", updates).StartServingOps()
+	defer tail.Stop()
+
+	var m = mux.NewRouter()
+	NewReadAPI(tail, cfs).Register(m)
+
+	var requests int32
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		m.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	defer close(updates) // Fails remaining blocked reads, before the server closes.
+
+	client, err := NewClient(server.URL)
+	c.Assert(err, gc.IsNil)
+
+	spool, err := journal.NewSpool(dir, journal.Mark{Journal: "a/journal", Offset: 0})
+	c.Assert(err, gc.IsNil)
+	var commit = func(content string) {
+		var _, err = spool.Write([]byte(content))
+		c.Check(err, gc.IsNil)
+		c.Check(spool.Commit(int64(len(content))), gc.IsNil)
+		updates <- spool.Fragment
+	}
+	commit("foo")
+
+	// Offsets through the current head are satisfied immediately.
+	head, err := client.WaitForHead(context.Background(), "a/journal", 3)
+	c.Check(err, gc.IsNil)
+	c.Check(head, gc.Equals, int64(3))
+
+	head, err = client.WaitForHead(context.Background(), "a/journal", 0)
+	c.Check(err, gc.IsNil)
+	c.Check(head, gc.Equals, int64(3))
+
+	// A greater offset blocks, with a single outstanding request, until the
+	// head reaches it.
+	atomic.StoreInt32(&requests, 0)
+
+	var done = make(chan int64)
+	go func() {
+		var head, err = client.WaitForHead(context.Background(), "a/journal", 6)
+		c.Check(err, gc.IsNil)
+		done <- head
+	}()
+	commit("bar")
+
+	c.Check(<-done, gc.Equals, int64(6))
+	c.Check(atomic.LoadInt32(&requests), gc.Equals, int32(1))
+
+	// A cancelled context aborts the wait.
+	var ctx, cancel = context.WithCancel(context.Background())
+	go cancel()
+
+	_, err = client.WaitForHead(ctx, "a/journal", 10)
+	c.Check(err, gc.Equals, context.Canceled)
+}
+
 func (s *ClientSuite) TestDirectGet(c *gc.C) {
 	mockClient := &mockHttpClient{}
 
@@ -240,6 +321,50 @@ func (s *ClientSuite) TestGetWithFragmentLocation(c *gc.C) {
 	c.Check(string(data), gc.Equals, "fragment-content...")
 }
 
+func (s *ClientSuite) TestGetWithVerifiedSums(c *gc.C) {
+	var content = strings.Repeat("x", 1000)
+	var fixture = newReadResponseFixture()
+
+	// Set the fragment fixture's sum to that of |content|.
+	var fragment = fragmentFixture
+	fragment.Sum = sha1.Sum([]byte(content))
+	fixture.Header.Set(FragmentNameHeader, fragment.ContentName())
+
+	mockClient := &mockHttpClient{}
+	mockClient.On("Do", mock.Anything).Return(fixture, nil).Once()
+	mockClient.On("Get", "http://cloud/fragment/location").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(content)),
+	}, nil).Once()
+
+	s.client.httpClient = mockClient
+	s.client.SetVerifySums(true)
+
+	result, body := s.client.Get(journal.ReadArgs{Journal: "a/journal", Offset: 1005})
+	c.Check(result.Error, gc.IsNil)
+
+	data, err := ioutil.ReadAll(body)
+	c.Check(err, gc.IsNil)
+	c.Check(data, gc.HasLen, 995)
+
+	// Corrupted content fails the read.
+	fixture = newReadResponseFixture()
+	fixture.Header.Set(FragmentNameHeader, fragment.ContentName())
+
+	mockClient.On("Do", mock.Anything).Return(fixture, nil).Once()
+	mockClient.On("Get", "http://cloud/fragment/location").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(content[1:] + "y")),
+	}, nil).Once()
+
+	result, body = s.client.Get(journal.ReadArgs{Journal: "a/journal", Offset: 1005})
+	c.Check(result.Error, gc.IsNil)
+
+	_, err = ioutil.ReadAll(body)
+	c.Check(err, gc.Equals, journal.ErrSumMismatch)
+	mockClient.AssertExpectations(c)
+}
+
 func (s *ClientSuite) TestGetWithFragmentLocationFails(c *gc.C) {
 	mockClient := &mockHttpClient{}
 
@@ -330,6 +455,43 @@ func (s *ClientSuite) TestCreate(c *gc.C) {
 	mockClient.AssertExpectations(c)
 }
 
+func (s *ClientSuite) TestClusterEndpoints(c *gc.C) {
+	c.Check(s.client.AddClusterEndpoint("other/", "other-cluster"), gc.IsNil)
+	c.Check(s.client.AddClusterEndpoint("other/nested/", "https://nested-cluster"), gc.IsNil)
+
+	c.Check(s.client.endpointFor("/a/journal").String(), gc.Equals, "http://default")
+	c.Check(s.client.endpointFor("/other/journal").String(), gc.Equals, "http://other-cluster")
+	c.Check(s.client.endpointFor("/other/nested/journal").String(), gc.Equals,
+		"https://nested-cluster")
+
+	mockClient := &mockHttpClient{}
+
+	// Expect requests are routed to the endpoint of the journal's cluster.
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "POST" &&
+			request.URL.String() == "http://other-cluster/other/journal"
+	})).Return(&http.Response{
+		StatusCode: http.StatusCreated,
+		Body:       ioutil.NopCloser(nil),
+	}, nil).Once()
+
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "HEAD" &&
+			request.URL.String() == "https://nested-cluster/other/nested/journal?block=false&offset=0"
+	})).Return(newReadResponseFixture(), nil).Once()
+
+	s.client.httpClient = mockClient
+	c.Check(s.client.Create("other/journal"), gc.IsNil)
+
+	var result, _ = s.client.Head(journal.ReadArgs{Journal: "other/nested/journal"})
+	c.Check(result.Error, gc.IsNil)
+
+	// Expect the default endpoint was not modified.
+	c.Check(s.client.defaultEndpoint.String(), gc.Equals, "http://default")
+
+	mockClient.AssertExpectations(c)
+}
+
 func (s *ClientSuite) TestPut(c *gc.C) {
 	content := strings.NewReader("foobar")
 	mockClient := &mockHttpClient{}
@@ -420,6 +582,70 @@ func (s *ClientSuite) TestPut(c *gc.C) {
 	c.Check(writerMap.Get("head").(*expvar.Int).String(), gc.Equals, "12341235")
 }
 
+func (s *ClientSuite) TestPutWithProducer(c *gc.C) {
+	var content = strings.NewReader("foobar")
+	var mockClient = &mockHttpClient{}
+	s.client.httpClient = mockClient
+	s.client.locationCache.Add("/a/journal", newURL("http://broker/a/journal"))
+
+	// Expect the producer, and a checksum of the content, are sent.
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT" &&
+			request.Header.Get(AppendProducerHeader) == "a-producer" &&
+			request.Header.Get(AppendSumHeader) == "8843d7f92416211de9ebb963ff4ce28125932878"
+	})).Return(&http.Response{
+		StatusCode: http.StatusNoContent,
+		Body:       ioutil.NopCloser(nil),
+		Header:     http.Header{WriteHeadHeader: []string{"6"}},
+	}, nil).Run(func(mock.Arguments) {
+		// Content was restored to its initial offset after summing.
+		var b, _ = ioutil.ReadAll(content)
+		c.Check(string(b), gc.Equals, "foobar")
+	}).Once()
+
+	var res = s.client.Put(journal.AppendArgs{
+		Journal:  "a/journal",
+		Content:  content,
+		Producer: "a-producer",
+	})
+	c.Check(res.Error, gc.IsNil)
+	c.Check(res.WriteHead, gc.Equals, int64(6))
+	mockClient.AssertExpectations(c)
+}
+
+func (s *ClientSuite) TestPutWithVerifiedSums(c *gc.C) {
+	var mockClient = &mockHttpClient{}
+	s.client.httpClient = mockClient
+	s.client.locationCache.Add("/a/journal", newURL("http://broker/a/journal"))
+	s.client.SetVerifySums(true)
+
+	// Expect a checksum of the content is sent, without a producer.
+	var isPut = mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT" &&
+			request.Header.Get(AppendProducerHeader) == "" &&
+			request.Header.Get(AppendSumHeader) == "8843d7f92416211de9ebb963ff4ce28125932878"
+	})
+	mockClient.On("Do", isPut).Return(&http.Response{
+		StatusCode: http.StatusNoContent,
+		Body:       ioutil.NopCloser(nil),
+		Header:     http.Header{WriteHeadHeader: []string{"6"}},
+	}, nil).Once()
+
+	var res = s.client.Put(journal.AppendArgs{Journal: "a/journal", Content: strings.NewReader("foobar")})
+	c.Check(res.Error, gc.IsNil)
+	c.Check(res.WriteHead, gc.Equals, int64(6))
+
+	// The broker fails an append which doesn't match its checksum.
+	mockClient.On("Do", isPut).Return(&http.Response{
+		StatusCode: http.StatusExpectationFailed,
+		Body:       ioutil.NopCloser(strings.NewReader("append content doesn't match its SHA1 sum")),
+	}, nil).Once()
+
+	res = s.client.Put(journal.AppendArgs{Journal: "a/journal", Content: strings.NewReader("foobar")})
+	c.Check(res.Error, gc.Equals, journal.ErrAppendSumMismatch)
+	mockClient.AssertExpectations(c)
+}
+
 func (s *ClientSuite) TestReadResultParsingErrorCases(c *gc.C) {
 	args := journal.ReadArgs{Journal: "a/journal"}
 
@@ -615,6 +841,83 @@ func (s *ClientSuite) TestFragmentsInRange(c *gc.C) {
 	mockClient.AssertExpectations(c)
 }
 
+func (s *ClientSuite) TestRedirectLimits(c *gc.C) {
+	// Brokers |a| and |b| each redirect to the other.
+	var a, b *httptest.Server
+	var redirectTo = func(srv **httptest.Server) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, (*srv).URL+r.URL.Path, http.StatusTemporaryRedirect)
+		})
+	}
+	a = httptest.NewServer(redirectTo(&b))
+	defer a.Close()
+	b = httptest.NewServer(redirectTo(&a))
+	defer b.Close()
+
+	client, err := NewClient(a.URL)
+	c.Assert(err, gc.IsNil)
+
+	var result, _ = client.Head(journal.ReadArgs{Journal: "a/journal", Offset: -1})
+	c.Check(result.Error, gc.ErrorMatches, ".*redirect loop: "+a.URL+"/a/journal.* -> "+
+		b.URL+"/a/journal.* -> "+a.URL+"/a/journal.*")
+
+	// A chain which doesn't loop is also bounded.
+	var hops int
+	var c1 = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops++
+		http.Redirect(w, r, fmt.Sprintf("%s/hop-%d", r.URL.Path, hops), http.StatusTemporaryRedirect)
+	}))
+	defer c1.Close()
+
+	client, err = NewClient(c1.URL)
+	c.Assert(err, gc.IsNil)
+
+	result, _ = client.Head(journal.ReadArgs{Journal: "a/journal", Offset: -1})
+	c.Check(result.Error, gc.ErrorMatches, ".*stopped after 3 redirects: .*")
+	c.Check(hops, gc.Equals, 4)
+}
+
+func (s *ClientSuite) TestPutRecords(c *gc.C) {
+	var putter = new(recordingPutter)
+	var args = journal.AppendArgs{Journal: "a/journal"}
+	var content = "one\ntwo\nthree\nfour\nfive"
+
+	// Records are packed into Puts of at most 10 bytes.
+	var result, written = putRecords(putter, args, strings.NewReader(content), 10, bufio.ScanLines)
+	c.Check(result.Error, gc.IsNil)
+	c.Check(written, gc.Equals, int64(len(content)))
+	c.Check(putter.puts, gc.DeepEquals, []string{"one\ntwo\n", "three\n", "four\nfive"})
+
+	// A record larger than the maximum is an error.
+	putter.puts = nil
+	result, written = putRecords(putter, args, strings.NewReader("one\nthree\n"), 5, bufio.ScanLines)
+	c.Check(result.Error, gc.Equals, bufio.ErrTooLong)
+	c.Check(written, gc.Equals, int64(0))
+	c.Check(putter.puts, gc.HasLen, 0)
+
+	// A failed Put stops further appends.
+	putter.puts, putter.failAt = nil, 2
+	result, written = putRecords(putter, args, strings.NewReader(content), 10, bufio.ScanLines)
+	c.Check(result.Error, gc.Equals, journal.ErrReplicationFailed)
+	c.Check(written, gc.Equals, int64(8))
+	c.Check(putter.puts, gc.DeepEquals, []string{"one\ntwo\n"})
+}
+
+// recordingPutter records the content of each Put, failing the |failAt|'th.
+type recordingPutter struct {
+	puts   []string
+	failAt int
+}
+
+func (p *recordingPutter) Put(args journal.AppendArgs) journal.AppendResult {
+	if len(p.puts)+1 == p.failAt {
+		return journal.AppendResult{Error: journal.ErrReplicationFailed}
+	}
+	var b, _ = ioutil.ReadAll(args.Content)
+	p.puts = append(p.puts, string(b))
+	return journal.AppendResult{WriteHead: int64(len(b))}
+}
+
 func newURL(s string) *url.URL {
 	u, err := url.Parse(s)
 	if err != nil {
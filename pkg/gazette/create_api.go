@@ -23,6 +23,7 @@ type CreateAPI struct {
 	cfs              cloudstore.FileSystem
 	keysAPI          etcd.KeysAPI
 	requiredReplicas int
+	serviceRoot      string
 }
 
 func NewCreateAPI(cfs cloudstore.FileSystem, keysAPI etcd.KeysAPI,
@@ -31,9 +32,16 @@ func NewCreateAPI(cfs cloudstore.FileSystem, keysAPI etcd.KeysAPI,
 		cfs:              cfs,
 		keysAPI:          keysAPI,
 		requiredReplicas: requiredReplicas,
+		serviceRoot:      ServiceRoot,
 	}
 }
 
+// SetServiceRoot sets the Etcd root under which journals are created. It
+// defaults to ServiceRoot. See Runner.SetServiceRoot.
+func (h *CreateAPI) SetServiceRoot(root string) {
+	h.serviceRoot = root
+}
+
 func (h *CreateAPI) Register(router *mux.Router) {
 	router.NewRoute().Methods("POST").HandlerFunc(h.Create)
 }
@@ -41,6 +49,11 @@ func (h *CreateAPI) Register(router *mux.Router) {
 func (h *CreateAPI) Create(w http.ResponseWriter, r *http.Request) {
 	var name = path.Clean(r.URL.Path[1:])
 
+	if err := journal.Name(name).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Create the fragment directory. Add a trailing slash to unambiguously
 	// represent it as a directory: some cloudstore implementations (eg, GCS)
 	// require this if no subordinate files are present.
@@ -50,7 +63,7 @@ func (h *CreateAPI) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create an allocated item entry in Etcd.
-	var itemPath = path.Join(ServiceRoot, consensus.ItemsPrefix, url.QueryEscape(name))
+	var itemPath = path.Join(h.serviceRoot, consensus.ItemsPrefix, url.QueryEscape(name))
 	var response, err = h.keysAPI.Set(context.Background(), itemPath, "",
 		&etcd.SetOptions{
 			Dir:       true,
@@ -71,6 +71,38 @@ func (s *CreateAPISuite) TestCreateSuccess(c *gc.C) {
 	}
 }
 
+func (s *CreateAPISuite) TestServiceRoot(c *gc.C) {
+	var m = mux.NewRouter()
+	var api = NewCreateAPI(s.cfs, s.keys, 2)
+	api.SetServiceRoot("/tenant/gazette")
+	api.Register(m)
+
+	// Expect the item is created beneath the configured root.
+	s.keys.On("Set", mock.Anything, "/tenant/gazette/items/journal%2Fname", "",
+		&etcd.SetOptions{
+			Dir:       true,
+			PrevExist: etcd.PrevNoExist}).
+		Return(nil, etcd.Error{Code: etcd.ErrorCodeNodeExist})
+
+	req, _ := http.NewRequest("POST", "/journal/name", nil)
+	w := httptest.NewRecorder()
+
+	m.ServeHTTP(w, req)
+	c.Check(w.Code, gc.Equals, http.StatusConflict)
+	s.keys.AssertExpectations(c)
+}
+
+func (s *CreateAPISuite) TestInvalidName(c *gc.C) {
+	req, _ := http.NewRequest("POST", "/journal/bad%20name", nil)
+	w := httptest.NewRecorder()
+
+	s.mux.ServeHTTP(w, req)
+	c.Check(w.Code, gc.Equals, http.StatusBadRequest)
+	c.Check(w.Body.String(), gc.Equals,
+		"journal name \"journal/bad name\" has invalid character ' '\n")
+	s.keys.AssertExpectations(c)
+}
+
 func (s *CreateAPISuite) TestEtcdConflict(c *gc.C) {
 	s.keys.On("Set", mock.Anything, ServiceRoot+"/items/journal%2Fname", "",
 		&etcd.SetOptions{
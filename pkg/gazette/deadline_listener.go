@@ -0,0 +1,95 @@
+package gazette
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+// DeadlineListener is a net.Listener which indexes its accepted connections
+// by remote address. This allows a handler to set read deadlines of the
+// connection underlying a request it's serving: for example, WriteAPI bounds
+// the time an append may await further content of its request body (see
+// WriteAPI.SetIdleTimeout). A DeadlineListener must wrap the listener of
+// plain connections, beneath any TLS listener.
+type DeadlineListener struct {
+	net.Listener
+
+	mu    sync.Mutex
+	conns map[string]net.Conn
+}
+
+func NewDeadlineListener(l net.Listener) *DeadlineListener {
+	return &DeadlineListener{
+		Listener: l,
+		conns:    make(map[string]net.Conn),
+	}
+}
+
+func (l *DeadlineListener) Accept() (net.Conn, error) {
+	var conn, err = l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	var tc = &deadlineConn{Conn: conn, listener: l, key: conn.RemoteAddr().String()}
+
+	l.mu.Lock()
+	l.conns[tc.key] = tc
+	l.mu.Unlock()
+
+	return tc, nil
+}
+
+// connOf returns the accepted connection of request |r|, if there is one.
+func (l *DeadlineListener) connOf(r *http.Request) (net.Conn, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var conn, ok = l.conns[r.RemoteAddr]
+	return conn, ok
+}
+
+// deadlineConn is a net.Conn which is removed from its DeadlineListener's
+// index upon Close.
+type deadlineConn struct {
+	net.Conn
+	listener *DeadlineListener
+	key      string
+	once     sync.Once
+}
+
+func (c *deadlineConn) Close() error {
+	c.once.Do(func() {
+		c.listener.mu.Lock()
+		if c.listener.conns[c.key] == net.Conn(c) {
+			delete(c.listener.conns, c.key)
+		}
+		c.listener.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+// idleTimeoutReader reads a request body, failing with journal.ErrIdleTimeout
+// if a Read doesn't complete within |timeout|. It extends the read deadline of
+// the underlying connection |conn| prior to each Read, which must be cleared
+// once the request body is no longer being read. A timed-out connection
+// cannot be re-used, and the HTTP server closes it upon the response.
+type idleTimeoutReader struct {
+	body    io.Reader
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	r.conn.SetReadDeadline(time.Now().Add(r.timeout))
+
+	var n, err = r.body.Read(p)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		err = journal.ErrIdleTimeout
+	}
+	return n, err
+}
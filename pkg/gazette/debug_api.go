@@ -0,0 +1,185 @@
+package gazette
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+	"github.com/gorilla/mux"
+
+	"github.com/LiveRamp/gazette/pkg/consensus"
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+// AllocatorPath is the request path of the DebugAPI.
+const AllocatorPath = "/debug/allocator"
+
+// Maximum time to wait for the Allocator to service an inspection request.
+var debugInspectTimeout = time.Second * 5
+
+// AllocatorState is a snapshot of the cluster's shared allocator state.
+type AllocatorState struct {
+	Members  []AllocatorMember
+	Journals []JournalAssignment
+	// Items which cannot be decoded, and are not brokered.
+	Quarantined []QuarantinedItem
+	// Number of required journal replicas.
+	Replicas int
+}
+
+// AllocatorMember describes an allocator member and its assigned load.
+type AllocatorMember struct {
+	Route           string
+	Master, Replica int
+	// Ratio of the member's assigned items (masters and replicas) to its
+	// balanced share of all assignment slots. A ratio of 1.0 is fully balanced.
+	LoadRatio float64
+}
+
+// JournalAssignment describes the current route of a journal.
+type JournalAssignment struct {
+	Journal journal.Name
+	Route   journal.RouteToken
+	// Whether the journal has its required number of replicas.
+	Ready bool
+}
+
+// DebugAPI serves a human-readable view of the AllocatorState, as HTML or (if
+// the "format=json" query argument is given) JSON.
+type DebugAPI struct {
+	inspector consensus.Inspector
+	replicas  int
+}
+
+func NewDebugAPI(runner *Runner) *DebugAPI {
+	return &DebugAPI{inspector: runner, replicas: runner.replicaCount}
+}
+
+// Register the DebugAPI. It must be registered before other APIs, as their
+// routes match all paths.
+func (h *DebugAPI) Register(router *mux.Router) {
+	router.Path(AllocatorPath).Methods("GET").HandlerFunc(h.Allocator)
+}
+
+func (h *DebugAPI) Allocator(w http.ResponseWriter, r *http.Request) {
+	var ctx, cancel = context.WithTimeout(r.Context(), debugInspectTimeout)
+	defer cancel()
+
+	var stateCh = make(chan AllocatorState, 1)
+	var callback = func(tree *etcd.Node) { stateCh <- buildAllocatorState(tree, h.replicas) }
+
+	select {
+	case h.inspector.InspectChan() <- callback:
+	case <-ctx.Done():
+		http.Error(w, "allocator is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var state = <-stateCh
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	allocatorTemplate.Execute(w, state)
+}
+
+// buildAllocatorState builds an AllocatorState from the allocator |tree|.
+// It must not retain |tree|.
+func buildAllocatorState(tree *etcd.Node, replicas int) AllocatorState {
+	var state = AllocatorState{Replicas: replicas, Quarantined: quarantinedItems(tree)}
+	var members = make(map[string]*AllocatorMember)
+
+	if dir := consensus.Child(tree, consensus.MemberPrefix); dir != nil {
+		for _, node := range dir.Nodes {
+			var route = path.Base(node.Key)
+			if s, err := url.QueryUnescape(route); err == nil {
+				route = s
+			}
+			members[route] = &AllocatorMember{Route: route}
+		}
+	}
+
+	consensus.WalkItems(tree, nil, func(item string, route consensus.Route) {
+		var assignment = JournalAssignment{
+			Ready: len(route.Entries) >= replicas+1,
+		}
+		var err error
+
+		if assignment.Journal, err = itemToJournal(item); err != nil {
+			assignment.Journal = journal.Name(item)
+		}
+		if assignment.Route, err = routeToToken(route); err != nil {
+			assignment.Route = journal.RouteToken(err.Error())
+		}
+		state.Journals = append(state.Journals, assignment)
+
+		for i, peer := range strings.Split(string(assignment.Route), "|") {
+			if peer == "" {
+				break // Empty route.
+			}
+			var member, ok = members[peer]
+			if !ok {
+				// Entry of a member which has since exited.
+				member = &AllocatorMember{Route: peer}
+				members[peer] = member
+			}
+			if i == 0 {
+				member.Master++
+			} else {
+				member.Replica++
+			}
+		}
+	})
+
+	for _, member := range members {
+		state.Members = append(state.Members, *member)
+	}
+	sort.Slice(state.Members, func(i, j int) bool {
+		return state.Members[i].Route < state.Members[j].Route
+	})
+
+	// Each journal has (replicas + 1) assignment slots, spread over members.
+	if len(state.Members) != 0 {
+		var share = float64(len(state.Journals)*(replicas+1)) / float64(len(state.Members))
+
+		for i := range state.Members {
+			if share != 0 {
+				var m = &state.Members[i]
+				m.LoadRatio = float64(m.Master+m.Replica) / share
+			}
+		}
+	}
+	return state
+}
+
+var allocatorTemplate = template.Must(template.New("allocator").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Gazette Allocator</title></head>
+<body>
+<h2>Members</h2>
+<table border="1">
+<tr><th>Route</th><th>Master</th><th>Replica</th><th>Load Ratio</th></tr>
+{{range .Members}}<tr><td>{{.Route}}</td><td>{{.Master}}</td><td>{{.Replica}}</td><td>{{printf "%.2f" .LoadRatio}}</td></tr>
+{{end}}</table>
+<h2>Journals (required replicas: {{.Replicas}})</h2>
+<table border="1">
+<tr><th>Journal</th><th>Route</th><th>Ready</th></tr>
+{{range .Journals}}<tr><td>{{.Journal}}</td><td>{{.Route}}</td><td>{{.Ready}}</td></tr>
+{{end}}</table>
+{{if .Quarantined}}<h2>Quarantined Items</h2>
+<table border="1">
+<tr><th>Item</th><th>Error</th><th>Revision</th></tr>
+{{range .Quarantined}}<tr><td>{{.Item}}</td><td>{{.Error}}</td><td>{{.Revision}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
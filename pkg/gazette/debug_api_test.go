@@ -0,0 +1,99 @@
+package gazette
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+	gc "github.com/go-check/check"
+	"github.com/gorilla/mux"
+)
+
+type DebugAPISuite struct {
+	tree *etcd.Node
+}
+
+func (s *DebugAPISuite) SetUpTest(c *gc.C) {
+	var entry = func(item, member string, index uint64) *etcd.Node {
+		return &etcd.Node{
+			Key:          ServiceRoot + "/items/" + item + "/" + url.QueryEscape(member),
+			CreatedIndex: index,
+		}
+	}
+	s.tree = &etcd.Node{Key: ServiceRoot, Dir: true, Nodes: etcd.Nodes{
+		{Key: ServiceRoot + "/items", Dir: true, Nodes: etcd.Nodes{
+			{Key: ServiceRoot + "/items/a%2Fone", Dir: true, Nodes: etcd.Nodes{
+				entry("a%2Fone", "http://bar", 3), // Ordered on CreatedIndex.
+				entry("a%2Fone", "http://foo", 2),
+			}},
+			{Key: ServiceRoot + "/items/a%2Fthree", Dir: true},
+			{Key: ServiceRoot + "/items/a%2Ftwo", Dir: true, Nodes: etcd.Nodes{
+				entry("a%2Ftwo", "http://bar", 4),
+			}},
+		}},
+		{Key: ServiceRoot + "/members", Dir: true, Nodes: etcd.Nodes{
+			{Key: ServiceRoot + "/members/" + url.QueryEscape("http://bar")},
+			{Key: ServiceRoot + "/members/" + url.QueryEscape("http://foo")},
+		}},
+	}}
+}
+
+func (s *DebugAPISuite) TestStateBuilding(c *gc.C) {
+	c.Check(buildAllocatorState(s.tree, 1), gc.DeepEquals, AllocatorState{
+		Members: []AllocatorMember{
+			{Route: "http://bar", Master: 1, Replica: 1, LoadRatio: 2.0 / 3.0},
+			{Route: "http://foo", Master: 1, Replica: 0, LoadRatio: 1.0 / 3.0},
+		},
+		Journals: []JournalAssignment{
+			{Journal: "a/one", Route: "http://foo|http://bar", Ready: true},
+			{Journal: "a/three", Route: "", Ready: false},
+			{Journal: "a/two", Route: "http://bar", Ready: false},
+		},
+		Replicas: 1,
+	})
+}
+
+func (s *DebugAPISuite) TestServing(c *gc.C) {
+	var runner = NewRunner(nil, "http://foo", 1, nil)
+	var m = mux.NewRouter()
+	NewDebugAPI(runner).Register(m)
+
+	// Service inspections of the fixture, as Allocate would.
+	go func() {
+		for cb := range runner.InspectChan() {
+			cb(s.tree)
+		}
+	}()
+	defer close(runner.inspectCh)
+
+	var w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "http://host"+AllocatorPath+"?format=json", nil))
+	c.Check(w.Code, gc.Equals, http.StatusOK)
+
+	var state AllocatorState
+	c.Check(json.Unmarshal(w.Body.Bytes(), &state), gc.IsNil)
+	c.Check(state, gc.DeepEquals, buildAllocatorState(s.tree, 1))
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "http://host"+AllocatorPath, nil))
+	c.Check(w.Code, gc.Equals, http.StatusOK)
+	c.Check(strings.Contains(w.Body.String(), "<td>http://foo|http://bar</td>"), gc.Equals, true)
+}
+
+func (s *DebugAPISuite) TestAllocatorUnavailable(c *gc.C) {
+	defer func(d time.Duration) { debugInspectTimeout = d }(debugInspectTimeout)
+	debugInspectTimeout = time.Millisecond
+
+	var m = mux.NewRouter()
+	NewDebugAPI(NewRunner(nil, "http://foo", 1, nil)).Register(m)
+
+	var w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "http://host"+AllocatorPath, nil))
+	c.Check(w.Code, gc.Equals, http.StatusServiceUnavailable)
+}
+
+var _ = gc.Suite(&DebugAPISuite{})
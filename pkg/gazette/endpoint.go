@@ -0,0 +1,81 @@
+package gazette
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/LiveRamp/gazette/pkg/keepalive"
+)
+
+// ParseEndpoint parses and validates a Gazette service |endpoint|. Endpoints
+// lacking a scheme are assumed to be http://. Supported schemes are:
+//
+//  * http:// and https://, addressing a host and optional port. A host name
+//    resolving to multiple addresses (eg, a DNS-balanced pool of brokers) is
+//    resolved by each dialed connection.
+//  * unix://, addressing the path of a local unix socket on which a broker
+//    is listening (eg, "unix:///var/run/gazette.sock"). This is useful for
+//    co-located processes.
+//
+// A unix:// endpoint is returned as an http:// URL having a pseudo-host,
+// which transports built by MakeHttpTransport dial as the socket path.
+func ParseEndpoint(endpoint string) (*url.URL, error) {
+	// Assume HTTP if no protocol is specified.
+	if strings.Index(endpoint, "://") == -1 {
+		endpoint = "http://" + endpoint
+	}
+	ep, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ep.Scheme {
+	case "http", "https":
+		if ep.Host == "" {
+			return nil, fmt.Errorf("endpoint %q has no host", endpoint)
+		}
+		return ep, nil
+	case "unix":
+		if ep.Host != "" || ep.Path == "" {
+			return nil, fmt.Errorf("endpoint %q must have the form unix:///path/to/socket", endpoint)
+		}
+		return &url.URL{Scheme: "http", Host: registerUnixSocket(ep.Path)}, nil
+	default:
+		return nil, fmt.Errorf("endpoint %q has unsupported scheme %q", endpoint, ep.Scheme)
+	}
+}
+
+// registerUnixSocket returns the pseudo-host of unix socket |path|, which is
+// dialed as |path| by dialEndpoint.
+func registerUnixSocket(path string) string {
+	var sum = sha1.Sum([]byte(path))
+	var host = fmt.Sprintf("%x.unix", sum[:8])
+
+	unixSockets.mu.Lock()
+	unixSockets.m[host+":80"] = path
+	unixSockets.mu.Unlock()
+
+	return host
+}
+
+// dialEndpoint dials |addr|, or the unix socket it's a pseudo-host of.
+func dialEndpoint(network, addr string) (net.Conn, error) {
+	unixSockets.mu.Lock()
+	var path, ok = unixSockets.m[addr]
+	unixSockets.mu.Unlock()
+
+	if ok {
+		return keepalive.Dialer.Dial("unix", path)
+	}
+	return keepalive.Dialer.Dial(network, addr)
+}
+
+// Pseudo-host "host:port" addresses of registered unix sockets, and their paths.
+var unixSockets = struct {
+	mu sync.Mutex
+	m  map[string]string
+}{m: make(map[string]string)}
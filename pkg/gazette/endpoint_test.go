@@ -0,0 +1,76 @@
+package gazette
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type EndpointSuite struct{}
+
+func (s *EndpointSuite) TestParsing(c *gc.C) {
+	for _, tc := range []struct {
+		endpoint, expect string
+	}{
+		{"host:8081", "http://host:8081"},
+		{"http://host:8081", "http://host:8081"},
+		{"https://host/", "https://host/"},
+	} {
+		var ep, err = ParseEndpoint(tc.endpoint)
+		c.Check(err, gc.IsNil)
+		c.Check(ep.String(), gc.Equals, tc.expect)
+	}
+
+	// unix:// endpoints map to a stable http:// pseudo-host.
+	var ep1, err = ParseEndpoint("unix:///var/run/gazette.sock")
+	c.Check(err, gc.IsNil)
+	c.Check(ep1.Scheme, gc.Equals, "http")
+	c.Check(ep1.Host, gc.Matches, "[0-9a-f]{16}.unix")
+
+	ep2, _ := ParseEndpoint("unix:///var/run/gazette.sock")
+	c.Check(ep2.Host, gc.Equals, ep1.Host)
+	ep2, _ = ParseEndpoint("unix:///var/run/other.sock")
+	c.Check(ep2.Host, gc.Not(gc.Equals), ep1.Host)
+
+	for _, tc := range []struct {
+		endpoint, expect string
+	}{
+		{"ftp://host", `endpoint "ftp://host" has unsupported scheme "ftp"`},
+		{"http://", `endpoint "http://" has no host`},
+		{"unix://host/gazette.sock", `endpoint .* must have the form unix:///path/to/socket`},
+		{"unix://", `endpoint .* must have the form unix:///path/to/socket`},
+	} {
+		var _, err = ParseEndpoint(tc.endpoint)
+		c.Check(err, gc.ErrorMatches, tc.expect)
+	}
+}
+
+func (s *EndpointSuite) TestUnixSocketEndpoint(c *gc.C) {
+	var dir, err = ioutil.TempDir("", "endpoint-suite")
+	c.Assert(err, gc.IsNil)
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "gazette.sock")
+	listener, err := net.Listen("unix", path)
+	c.Assert(err, gc.IsNil)
+	defer listener.Close()
+
+	var requests = make(chan string, 1)
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	client, err := NewClient("unix://" + path)
+	c.Assert(err, gc.IsNil)
+	c.Check(client.Create(journal.Name("a/journal")), gc.IsNil)
+	c.Check(<-requests, gc.Equals, "POST /a/journal")
+}
+
+var _ = gc.Suite(&EndpointSuite{})
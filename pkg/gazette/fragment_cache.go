@@ -0,0 +1,220 @@
+package gazette
+
+import (
+	"container/list"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+// Prefix of fragment cache files which are still being filled.
+const fragmentCacheTempPrefix = ".fill-"
+
+// fragmentCache is a bounded, least-recently-used cache of persisted fragment
+// content, held as files of a local directory.
+type fragmentCache struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	size  int64                    // Total bytes of cached fragments.
+	order *list.List               // Of *fragmentCacheEntry, most recently used first.
+	index map[string]*list.Element // Maps a file name to its |order| element.
+}
+
+type fragmentCacheEntry struct {
+	name string
+	size int64
+}
+
+// SetFragmentCache enables a read-through cache of persisted fragments read by
+// the Client, held in local directory |dir| and bounded to |maxBytes| of
+// fragment content. Fragments already cached in |dir| (eg, by a previous
+// process) are retained. Reads of cached fragments are served from local disk
+// rather than the backing store, which greatly speeds jobs which repeatedly
+// read the same historical range of a journal. SetFragmentCache must be
+// called before the Client is used.
+func (c *Client) SetFragmentCache(dir string, maxBytes int64) error {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var fc = &fragmentCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		index:    make(map[string]*list.Element),
+	}
+	// Add fragments from least to most recently modified.
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ModTime().Before(infos[j].ModTime()) })
+
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), fragmentCacheTempPrefix) {
+			os.Remove(filepath.Join(dir, info.Name())) // Abandoned by a prior process.
+		} else if info.Mode().IsRegular() {
+			fc.insert(info.Name(), info.Size())
+		}
+	}
+	fc.mu.Lock()
+	fc.evict()
+	fc.mu.Unlock()
+
+	c.fragmentCache = fc
+	return nil
+}
+
+// fileName returns the cache file name of |fragment|.
+func (fc *fragmentCache) fileName(fragment journal.Fragment) string {
+	return url.QueryEscape(fragment.Journal.String()) + "." + fragment.ContentName()
+}
+
+// open returns cached content of |fragment|, positioned at |offset|.
+func (fc *fragmentCache) open(fragment journal.Fragment, offset int64) (*os.File, bool) {
+	var name = fc.fileName(fragment)
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	var elem, ok = fc.index[name]
+	if !ok {
+		return nil, false
+	}
+	var file, err = os.Open(filepath.Join(fc.dir, name))
+	if err == nil {
+		if _, err = file.Seek(offset-fragment.Begin, io.SeekStart); err != nil {
+			file.Close()
+		}
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"name": name, "err": err}).Warn("failed to open cached fragment")
+		fc.remove(elem)
+		return nil, false
+	}
+	fc.order.MoveToFront(elem)
+	return file, true
+}
+
+// fill returns a ReadCloser of |body|, which is content of |fragment| read
+// from its beginning. If |body| is read through the fragment end, the content
+// is added to the cache.
+func (fc *fragmentCache) fill(fragment journal.Fragment, body io.ReadCloser) io.ReadCloser {
+	if fragment.Size() > fc.maxBytes {
+		return body
+	}
+	var file, err = ioutil.TempFile(fc.dir, fragmentCacheTempPrefix)
+	if err != nil {
+		log.WithField("err", err).Warn("failed to create fragment cache file")
+		return body
+	}
+	return &fragmentCacheFiller{
+		ReadCloser: body,
+		cache:      fc,
+		fragment:   fragment,
+		file:       file,
+	}
+}
+
+// commit adds the filled fragment |file| to the cache as |name|.
+func (fc *fragmentCache) commit(file string, name string, size int64) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := os.Rename(file, filepath.Join(fc.dir, name)); err != nil {
+		log.WithFields(log.Fields{"name": name, "err": err}).Warn("failed to add cached fragment")
+		os.Remove(file)
+		return
+	}
+	if elem, ok := fc.index[name]; ok {
+		fc.order.MoveToFront(elem) // Filled concurrently by another read.
+	} else {
+		fc.index[name] = fc.order.PushFront(&fragmentCacheEntry{name: name, size: size})
+		fc.size += size
+	}
+	fc.evict()
+}
+
+// insert adds an existing file |name| of |size| as the most recently used.
+func (fc *fragmentCache) insert(name string, size int64) {
+	fc.index[name] = fc.order.PushFront(&fragmentCacheEntry{name: name, size: size})
+	fc.size += size
+}
+
+// remove removes |elem| and its file from the cache. |mu| must be held.
+func (fc *fragmentCache) remove(elem *list.Element) {
+	var entry = fc.order.Remove(elem).(*fragmentCacheEntry)
+	delete(fc.index, entry.name)
+	fc.size -= entry.size
+
+	// Readers of the file are unaffected by its removal.
+	if err := os.Remove(filepath.Join(fc.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{"name": entry.name, "err": err}).Warn("failed to remove cached fragment")
+	}
+}
+
+// evict removes least recently used fragments until the cache is within its
+// size bound. |mu| must be held.
+func (fc *fragmentCache) evict() {
+	for fc.size > fc.maxBytes {
+		fc.remove(fc.order.Back())
+	}
+}
+
+// fragmentCacheFiller copies content read from the wrapped ReadCloser to a
+// cache file, which is committed once the fragment has been completely read.
+type fragmentCacheFiller struct {
+	io.ReadCloser
+	cache    *fragmentCache
+	fragment journal.Fragment
+	file     *os.File // Nil once committed or abandoned.
+	n        int64
+}
+
+func (f *fragmentCacheFiller) Read(p []byte) (int, error) {
+	var n, err = f.ReadCloser.Read(p)
+
+	if f.file != nil && n != 0 {
+		if _, werr := f.file.Write(p[:n]); werr != nil {
+			log.WithField("err", werr).Warn("failed to write fragment cache file")
+			f.abandon()
+		} else {
+			f.n += int64(n)
+		}
+	}
+	if f.file != nil && err == io.EOF {
+		if f.n != f.fragment.Size() {
+			f.abandon()
+		} else if cerr := f.file.Close(); cerr != nil {
+			os.Remove(f.file.Name())
+			f.file = nil
+		} else {
+			f.cache.commit(f.file.Name(), f.cache.fileName(f.fragment), f.n)
+			f.file = nil
+		}
+	}
+	return n, err
+}
+
+func (f *fragmentCacheFiller) Close() error {
+	if f.file != nil {
+		f.abandon() // Fragment wasn't completely read.
+	}
+	return f.ReadCloser.Close()
+}
+
+func (f *fragmentCacheFiller) abandon() {
+	f.file.Close()
+	os.Remove(f.file.Name())
+	f.file = nil
+}
@@ -0,0 +1,89 @@
+package gazette
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type FragmentCacheSuite struct{}
+
+func (s *FragmentCacheSuite) TestReadThrough(c *gc.C) {
+	var dir, err = ioutil.TempDir("", "fragment-cache-suite")
+	c.Assert(err, gc.IsNil)
+	defer os.RemoveAll(dir)
+
+	var mockClient = &mockHttpClient{}
+	var client, _ = NewClient("http://default")
+	client.httpClient = mockClient
+	c.Assert(client.SetFragmentCache(dir, 20), gc.IsNil)
+
+	var fragment = func(begin int64) journal.Fragment {
+		return journal.Fragment{Journal: "a/journal", Begin: begin, End: begin + 10}
+	}
+	var location = newURL("http://store/fragment")
+
+	// expectFetch expects a fetch of fragment content from the store.
+	var expectFetch = func() {
+		mockClient.On("Get", location.String()).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("0123456789")),
+		}, nil).Once()
+	}
+	// read reads up to |n| bytes of |f| from |offset|.
+	var read = func(f journal.Fragment, offset int64, n int) string {
+		var body, err = client.openFragment(location, journal.ReadResult{Offset: offset, Fragment: f})
+		c.Assert(err, gc.IsNil)
+
+		var b = make([]byte, n)
+		n, _ = body.Read(b)
+		for n != len(b) {
+			var nn, err = body.Read(b[n:])
+			if n += nn; err != nil {
+				break
+			}
+		}
+		c.Check(body.Close(), gc.IsNil)
+		return string(b[:n])
+	}
+
+	// A fragment read through its end is cached.
+	expectFetch()
+	c.Check(read(fragment(100), 103, 100), gc.Equals, "3456789")
+	c.Check(read(fragment(100), 105, 100), gc.Equals, "56789")
+
+	// A partially read fragment isn't.
+	expectFetch()
+	c.Check(read(fragment(110), 110, 2), gc.Equals, "01")
+	expectFetch()
+	c.Check(read(fragment(110), 110, 100), gc.Equals, "0123456789")
+	c.Check(read(fragment(110), 118, 100), gc.Equals, "89")
+
+	// Adding a third fragment evicts the least recently used.
+	expectFetch()
+	c.Check(read(fragment(120), 120, 100), gc.Equals, "0123456789")
+	expectFetch()
+	c.Check(read(fragment(100), 109, 100), gc.Equals, "9")
+
+	// Cached fragments are retained by a new cache of the directory.
+	c.Assert(client.SetFragmentCache(dir, 20), gc.IsNil)
+	c.Check(read(fragment(100), 100, 100), gc.Equals, "0123456789")
+	c.Check(read(fragment(120), 125, 100), gc.Equals, "56789")
+
+	// Fragments larger than the cache aren't cached.
+	c.Assert(client.SetFragmentCache(dir, 5), gc.IsNil)
+	expectFetch()
+	c.Check(read(fragment(130), 130, 100), gc.Equals, "0123456789")
+
+	var infos, _ = ioutil.ReadDir(dir)
+	c.Check(infos, gc.HasLen, 0)
+
+	mockClient.AssertExpectations(c)
+}
+
+var _ = gc.Suite(&FragmentCacheSuite{})
@@ -0,0 +1,199 @@
+package gazette
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+	"github.com/LiveRamp/gazette/pkg/metrics"
+)
+
+// Maximum hedges which a Client may accrue budget for, bounding a burst of
+// hedged reads (eg, when all route members become slow).
+const maxHedgeBurst = 10
+
+// hedger tracks recent journal routes, and the budget of reads which may be hedged.
+type hedger struct {
+	delay  time.Duration
+	budget float64
+	routes *lru.Cache // Maps journal.Name => journal.RouteToken.
+
+	mu     sync.Mutex
+	tokens float64 // Available hedges.
+}
+
+// SetHedgedReads enables hedging of non-blocking GetDirect reads (including
+// those of Get), for latency-sensitive readers. If response headers of a read
+// haven't been received after |delay|, the read is also issued to another
+// member of the journal route, and the first successful response is used while
+// the other is cancelled. At most |budget| of reads (eg, 0.05) are hedged, so
+// that slow route members can't double the load of the cluster. Blocking
+// reads aren't hedged, as their headers are expected to await new content.
+// SetHedgedReads must be called before the Client is used.
+func (c *Client) SetHedgedReads(delay time.Duration, budget float64) {
+	var routes, err = lru.New(kClientRouteCacheSize)
+	if err != nil {
+		panic(err) // Fails only on a non-positive size.
+	}
+	c.hedger = &hedger{delay: delay, budget: budget, routes: routes}
+}
+
+// observeRoute records the current |route| of journal |name|.
+func (h *hedger) observeRoute(name journal.Name, route journal.RouteToken) {
+	if route != "" {
+		h.routes.Add(name, route)
+	}
+}
+
+// deposit accrues budget for hedging a fraction of a read.
+func (h *hedger) deposit() {
+	h.mu.Lock()
+	if h.tokens += h.budget; h.tokens > maxHedgeBurst {
+		h.tokens = maxHedgeBurst
+	}
+	h.mu.Unlock()
+}
+
+// take returns whether a read may be hedged, consuming budget if so.
+func (h *hedger) take() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+// alternate returns a member of the route of journal |name| having a host
+// other than |host|.
+func (h *hedger) alternate(name journal.Name, host string) (*url.URL, bool) {
+	var route, ok = h.routes.Get(name)
+	if !ok {
+		return nil, false
+	}
+	for _, member := range strings.Split(string(route.(journal.RouteToken)), "|") {
+		if u, err := url.Parse(member); err == nil && u.Host != "" && u.Host != host {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// hedgedGetDirect performs a GetDirect read which is hedged to an alternate
+// route member if the read is slow.
+func (c *Client) hedgedGetDirect(args journal.ReadArgs) (journal.ReadResult, io.ReadCloser) {
+	c.hedger.deposit()
+
+	var parent = args.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	type attempt struct {
+		result journal.ReadResult
+		body   io.ReadCloser
+		hedged bool
+	}
+	var attemptCh = make(chan attempt, 2)
+	var cancels [2]context.CancelFunc // Indexed on whether the attempt is hedged.
+
+	var start = func(do func(*http.Request) (*http.Response, error), hedged bool) {
+		var ctx, cancel = context.WithCancel(parent)
+		var attemptArgs = args
+		attemptArgs.Context = ctx
+
+		if hedged {
+			cancels[1] = cancel
+		} else {
+			cancels[0] = cancel
+		}
+		go func() {
+			var result, body = c.getDirectVia(attemptArgs, do)
+			attemptCh <- attempt{result, body, hedged}
+		}()
+	}
+	var index = func(hedged bool) int {
+		if hedged {
+			return 1
+		}
+		return 0
+	}
+	start(c.Do, false)
+
+	var timer = time.NewTimer(c.hedger.delay)
+	defer timer.Stop()
+
+	for pending := 1; true; {
+		select {
+		case <-timer.C:
+			var alt, ok = c.hedger.alternate(args.Journal, c.readHost(args.Journal))
+			if !ok || !c.hedger.take() {
+				continue
+			}
+			// Issue the hedge directly against |alt|, bypassing the location cache.
+			start(func(request *http.Request) (*http.Response, error) {
+				request.URL.Scheme, request.URL.Host = alt.Scheme, alt.Host
+				return c.httpClient.Do(request)
+			}, true)
+			pending++
+
+		case a := <-attemptCh:
+			var cancel = cancels[index(a.hedged)]
+
+			if pending--; a.result.Error != nil && pending != 0 {
+				cancel()
+				continue // Await the outstanding attempt.
+			}
+			if pending != 0 {
+				// Cancel the losing attempt, and release its response.
+				cancels[index(!a.hedged)]()
+
+				go func() {
+					if loser := <-attemptCh; loser.body != nil {
+						loser.body.Close()
+					}
+				}()
+				if a.hedged {
+					metrics.GazetteHedgedReadsTotal.WithLabelValues("hedge").Inc()
+				} else {
+					metrics.GazetteHedgedReadsTotal.WithLabelValues("primary").Inc()
+				}
+			}
+			if a.body == nil {
+				cancel()
+				return a.result, nil
+			}
+			return a.result, cancelOnClose{a.body, cancel}
+		}
+	}
+	panic("not reached")
+}
+
+// readHost returns the host to which a read of journal |name| is issued.
+func (c *Client) readHost(name journal.Name) string {
+	var path = "/" + name.String()
+	if cached, ok := c.locationCache.Get(path); ok {
+		return cached.(*url.URL).Host
+	}
+	return c.endpointFor(path).Host
+}
+
+// cancelOnClose cancels the context of a read when its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	var err = c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
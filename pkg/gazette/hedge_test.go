@@ -0,0 +1,117 @@
+package gazette
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type HedgeSuite struct{}
+
+func (s *HedgeSuite) TestHedgedRead(c *gc.C) {
+	var respond = func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Range", "bytes 100-199/200")
+		w.Header().Set(WriteHeadHeader, "200")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(body))
+	}
+	var primaryCancelled = make(chan struct{}, 1)
+
+	// |slow| responds only after a delay, or is cancelled.
+	var slow = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+			respond(w, "slow")
+		case <-r.Context().Done():
+			select {
+			case primaryCancelled <- struct{}{}:
+			default:
+			}
+		}
+	}))
+	defer slow.Close()
+
+	var fast = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(w, "fast")
+	}))
+	defer fast.Close()
+
+	var client, _ = NewClient(slow.URL)
+	client.SetHedgedReads(time.Millisecond, 0)
+	client.hedger.observeRoute("a/journal", journal.RouteToken(slow.URL+"|"+fast.URL))
+	client.hedger.tokens = 1 // Budget for a single hedge.
+
+	var read = func() string {
+		var result, body = client.GetDirect(journal.ReadArgs{Journal: "a/journal", Offset: 100})
+		c.Assert(result.Error, gc.IsNil)
+		c.Check(result.Offset, gc.Equals, int64(100))
+
+		var b, _ = ioutil.ReadAll(body)
+		c.Check(body.Close(), gc.IsNil)
+		return string(b)
+	}
+
+	// The read is hedged to |fast|, which wins. The primary is cancelled.
+	c.Check(read(), gc.Equals, "fast")
+	<-primaryCancelled
+
+	// The hedging budget is exhausted. The read awaits the primary.
+	c.Check(read(), gc.Equals, "slow")
+
+	// Blocking reads are not hedged.
+	client.hedger.tokens = 1
+
+	var result, body = client.GetDirect(journal.ReadArgs{Journal: "a/journal", Offset: 100, Blocking: true})
+	c.Assert(result.Error, gc.IsNil)
+	var b, _ = ioutil.ReadAll(body)
+	c.Check(string(b), gc.Equals, "slow")
+	body.Close()
+}
+
+func (s *HedgeSuite) TestBudget(c *gc.C) {
+	var client, _ = NewClient("http://default")
+	client.SetHedgedReads(time.Millisecond, 0.25)
+	var h = client.hedger
+
+	for i := 0; i != 3; i++ {
+		h.deposit()
+		c.Check(h.take(), gc.Equals, false)
+	}
+	h.deposit()
+	c.Check(h.take(), gc.Equals, true)
+	c.Check(h.take(), gc.Equals, false)
+
+	// Accrued budget is bounded.
+	for i := 0; i != 1000; i++ {
+		h.deposit()
+	}
+	c.Check(h.tokens, gc.Equals, float64(maxHedgeBurst))
+}
+
+func (s *HedgeSuite) TestAlternate(c *gc.C) {
+	var client, _ = NewClient("http://default")
+	client.SetHedgedReads(time.Millisecond, 1.0)
+	var h = client.hedger
+
+	var _, ok = h.alternate("a/journal", "one")
+	c.Check(ok, gc.Equals, false) // Route is unknown.
+
+	h.observeRoute("a/journal", "http://one|http://two")
+	alt, ok := h.alternate("a/journal", "one")
+	c.Check(ok, gc.Equals, true)
+	c.Check(alt.String(), gc.Equals, "http://two")
+
+	alt, _ = h.alternate("a/journal", "two")
+	c.Check(alt.String(), gc.Equals, "http://one")
+
+	h.observeRoute("a/journal", "http://one")
+	_, ok = h.alternate("a/journal", "one")
+	c.Check(ok, gc.Equals, false) // No other member.
+}
+
+var _ = gc.Suite(&HedgeSuite{})
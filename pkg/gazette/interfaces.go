@@ -16,11 +16,16 @@ type ReplicateOpHandler interface {
 	Replicate(journal.ReplicateOp)
 }
 
+type RollOpHandler interface {
+	Roll(journal.AppendOp)
+}
+
 // See journal.Replica.
 type JournalReplica interface {
 	AppendOpHandler
 	ReadOpHandler
 	ReplicateOpHandler
+	Roll()
 	Shutdown()
 	StartBrokeringWithPeers(journal.RouteToken, []journal.Replicator)
 	StartReplicating(journal.RouteToken)
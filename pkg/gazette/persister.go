@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"github.com/LiveRamp/gazette/pkg/async"
 	"github.com/LiveRamp/gazette/pkg/cloudstore"
 	"github.com/LiveRamp/gazette/pkg/journal"
+	"github.com/LiveRamp/gazette/pkg/metrics"
 )
 
 const (
@@ -25,6 +27,7 @@ const (
 
 	kPersisterConvergeInterval = time.Minute
 	kPersisterLockTTL          = 10 * time.Minute
+	kPersisterMaxAdmitDelay    = time.Second
 )
 
 type Persister struct {
@@ -32,15 +35,30 @@ type Persister struct {
 	cfs       cloudstore.FileSystem
 	keysAPI   etcd.KeysAPI
 	routeKey  string
+	locksRoot string
 
-	queue        map[string]journal.Fragment
+	queue        map[string]queuedFragment
 	shuttingDown uint32
 	loopExited   chan struct{}
 	mu           sync.Mutex
 
+	// Sequence number of the last queued fragment.
+	queueSeq uint64
+	// Total bytes of queued local fragments, the budget beyond which
+	// persisting is expedited and AppendAdmitted is delayed, and the limit
+	// beyond which AppendAdmitted fails.
+	queuedBytes int64
+	diskBudget  int64
+	diskLimit   int64
+	// Whether the Persister is read-only, having exceeded |diskLimit|.
+	readOnly bool
+	// Signalled to expedite a converge pass.
+	convergeCh chan struct{}
+
 	// Effective constants, which are swappable for testing.
 	osRemove         func(path string) error
 	persisterLockTTL time.Duration
+	maxAdmitDelay    time.Duration
 }
 
 func NewPersister(directory string, cfs cloudstore.FileSystem,
@@ -51,9 +69,12 @@ func NewPersister(directory string, cfs cloudstore.FileSystem,
 		keysAPI:          keysAPI,
 		osRemove:         os.Remove,
 		persisterLockTTL: kPersisterLockTTL,
-		queue:            make(map[string]journal.Fragment),
+		maxAdmitDelay:    kPersisterMaxAdmitDelay,
+		queue:            make(map[string]queuedFragment),
 		loopExited:       make(chan struct{}),
+		convergeCh:       make(chan struct{}, 1),
 		routeKey:         routeKey,
+		locksRoot:        PersisterLocksRoot,
 	}
 	// Make the state of the persister queue available to expvar.
 	gazetteMap.Set("persister", p)
@@ -61,6 +82,97 @@ func NewPersister(directory string, cfs cloudstore.FileSystem,
 	return p
 }
 
+// SetServiceRoot sets the Etcd root under which fragment upload locks are
+// held. It defaults to ServiceRoot. See Runner.SetServiceRoot.
+func (p *Persister) SetServiceRoot(root string) {
+	p.locksRoot = root + "/" + PersisterLocksPrefix
+}
+
+// queuedFragment is a local Fragment awaiting persistence, and the sequence
+// in which it was queued.
+type queuedFragment struct {
+	journal.Fragment
+	seq uint64
+}
+
+// SetDiskBudget sets the total bytes of completed local spools which may
+// await persistence. When |budget| is exceeded, queued spools are persisted
+// immediately and oldest-first, rather than at the next converge interval,
+// and AppendAdmitted applies back-pressure by delaying each append. The delay
+// grows with the backlog, reaching its maximum as the backlog nears |limit|.
+// Should the backlog exceed |limit| regardless, the Persister enters a
+// read-only mode where AppendAdmitted returns ErrInsufficientStorage (reads
+// are unaffected). Read-only mode is exited once persistence catches up and
+// the backlog has drained to |budget|. A zero |budget| (the default) is
+// unbounded, and a zero |limit| never refuses appends.
+func (p *Persister) SetDiskBudget(budget, limit int64) {
+	p.mu.Lock()
+	p.diskBudget, p.diskLimit = budget, limit
+	p.updateReadOnly()
+	p.mu.Unlock()
+}
+
+// QueuedBytes returns the total bytes of local spools awaiting persistence.
+func (p *Persister) QueuedBytes() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queuedBytes
+}
+
+// AppendAdmitted returns ErrInsufficientStorage if the Persister is read-only.
+// Otherwise, it blocks for the current back-pressure delay (if the disk
+// budget is exceeded) and returns nil.
+func (p *Persister) AppendAdmitted() error {
+	p.mu.Lock()
+	var readOnly, delay = p.readOnly, p.admitDelay()
+	p.mu.Unlock()
+
+	if readOnly {
+		return journal.ErrInsufficientStorage
+	} else if delay != 0 {
+		time.Sleep(delay)
+	}
+	return nil
+}
+
+// admitDelay returns the back-pressure delay to apply to an append, which
+// scales linearly from zero at |diskBudget| to |maxAdmitDelay| at |diskLimit|.
+// Must be called with |mu| held.
+func (p *Persister) admitDelay() time.Duration {
+	if p.diskBudget == 0 || p.queuedBytes <= p.diskBudget {
+		return 0
+	} else if p.diskLimit <= p.diskBudget || p.queuedBytes >= p.diskLimit {
+		return p.maxAdmitDelay
+	}
+	return time.Duration(int64(p.maxAdmitDelay) *
+		(p.queuedBytes - p.diskBudget) / (p.diskLimit - p.diskBudget))
+}
+
+// updateReadOnly enters or exits read-only mode, per current queued bytes.
+// Must be called with |mu| held.
+func (p *Persister) updateReadOnly() {
+	var fields = log.Fields{
+		"queuedBytes": p.queuedBytes,
+		"budget":      p.diskBudget,
+		"limit":       p.diskLimit,
+	}
+
+	if !p.readOnly && p.diskLimit != 0 && p.queuedBytes > p.diskLimit {
+		log.WithFields(fields).Warn("spool disk limit exceeded; entering read-only mode")
+		p.readOnly = true
+	} else if p.readOnly && (p.diskLimit == 0 || p.queuedBytes <= p.diskBudget) {
+		log.WithFields(fields).Info("spool persistence caught up; exiting read-only mode")
+		p.readOnly = false
+	} else {
+		return
+	}
+	if p.readOnly {
+		metrics.PersisterReadOnly.Set(1)
+	} else {
+		metrics.PersisterReadOnly.Set(0)
+	}
+}
+
 // Note: This String() implementation is primarily for the benefit of expvar,
 // which expects the string to be a serialized JSON object.
 func (p *Persister) String() string {
@@ -95,7 +207,10 @@ func (p *Persister) StartPersisting() *Persister {
 	go func() {
 		interval := time.Tick(kPersisterConvergeInterval)
 		for {
-			<-interval
+			select {
+			case <-interval:
+			case <-p.convergeCh:
+			}
 
 			// Attempt to converge all items in the queue.
 			p.converge()
@@ -125,26 +240,52 @@ func (p *Persister) Persist(fragment journal.Fragment) {
 		return
 	}
 	p.mu.Lock()
-	p.queue[fragment.ContentName()] = fragment
-	p.mu.Unlock()
+	defer p.mu.Unlock()
+
+	var name = fragment.ContentName()
+	if _, ok := p.queue[name]; !ok {
+		p.queuedBytes += fragment.Size()
+	}
+	p.queueSeq++
+	p.queue[name] = queuedFragment{Fragment: fragment, seq: p.queueSeq}
+	p.updateReadOnly()
+
+	if p.diskBudget != 0 && p.queuedBytes > p.diskBudget {
+		select {
+		case p.convergeCh <- struct{}{}:
+		default: // Already signalled.
+		}
+	}
 }
 
+// converge attempts to persist each queued fragment, oldest-first.
 func (p *Persister) converge() {
 	p.mu.Lock()
-	for name, fragment := range p.queue {
-		p.mu.Unlock()
-		success := p.convergeOne(fragment)
-		p.mu.Lock()
+	var queued = make([]queuedFragment, 0, len(p.queue))
+	for _, q := range p.queue {
+		queued = append(queued, q)
+	}
+	p.mu.Unlock()
 
-		if success {
+	sort.Slice(queued, func(i, j int) bool { return queued[i].seq < queued[j].seq })
+
+	for _, q := range queued {
+		if !p.convergeOne(q.Fragment) {
+			continue
+		}
+		p.mu.Lock()
+		var name = q.ContentName()
+		if cur, ok := p.queue[name]; ok && cur.seq == q.seq {
 			delete(p.queue, name)
+			p.queuedBytes -= q.Size()
+			p.updateReadOnly()
 		}
+		p.mu.Unlock()
 	}
-	p.mu.Unlock()
 }
 
 func (p *Persister) convergeOne(fragment journal.Fragment) bool {
-	var lockPath = PersisterLocksRoot + fragment.ContentName()
+	var lockPath = p.locksRoot + fragment.ContentName()
 	var lockIndex uint64
 
 	// Attempt to lock this fragment for upload.
@@ -2,6 +2,7 @@ package gazette
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"sort"
@@ -103,6 +104,21 @@ func (s *PersisterSuite) TestPersistence(c *gc.C) {
 	c.Check(content, gc.DeepEquals, contentFixture)
 }
 
+func (s *PersisterSuite) TestServiceRoot(c *gc.C) {
+	s.persister.SetServiceRoot("/tenant/gazette")
+
+	// Expect the fragment lock is beneath the configured root.
+	s.keysAPI.On("Set", mock.Anything,
+		"/tenant/gazette/persister_locks/"+s.fragment.ContentName(), "route-key",
+		&etcd.SetOptions{
+			PrevExist: etcd.PrevNoExist,
+			TTL:       kPersisterLockTTL,
+		}).Return(nil, etcd.Error{Code: etcd.ErrorCodeNodeExist})
+
+	c.Check(s.persister.convergeOne(s.fragment), gc.Equals, false)
+	s.keysAPI.AssertExpectations(c)
+}
+
 func (s *PersisterSuite) TestLockIsAlreadyHeld(c *gc.C) {
 	var lockPath = PersisterLocksRoot + s.fragment.ContentName()
 
@@ -189,6 +205,133 @@ func (s *PersisterSuite) TestEmptyFragment(c *gc.C) {
 	c.Check(s.persister.osRemove, gc.IsNil) // Verify osRemove() was called.
 }
 
+func (s *PersisterSuite) TestDiskBudget(c *gc.C) {
+	s.persister.SetDiskBudget(15, 30)
+	s.persister.maxAdmitDelay = 0
+
+	// Queue |frag2| before |frag1|, and expect they're persisted in that order.
+	var frag1, frag2 = s.fragment, s.fragment
+	frag2.Begin, frag2.End = 2000, 2010
+
+	for _, f := range []journal.Fragment{frag2, frag1} {
+		// Fixture content is already present on the target filesystem.
+		c.Assert(s.cfs.MkdirAll(f.Journal.String(), 0740), gc.IsNil)
+		w, err := s.cfs.OpenFile(f.ContentPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+		c.Assert(err, gc.IsNil)
+		c.Assert(w.Close(), gc.IsNil)
+
+		var lockPath = PersisterLocksRoot + f.ContentName()
+		s.keysAPI.On("Set", mock.Anything, lockPath, "route-key", mock.Anything).
+			Return(&etcd.Response{Index: 1234}, nil)
+		s.keysAPI.On("Delete", mock.Anything, lockPath, mock.Anything).
+			Return(&etcd.Response{}, nil)
+	}
+
+	s.persister.Persist(frag2)
+	c.Check(s.persister.AppendAdmitted(), gc.IsNil)
+	s.persister.Persist(frag2) // Re-queuing isn't double-counted.
+	c.Check(s.persister.QueuedBytes(), gc.Equals, int64(10))
+	c.Check(s.persister.convergeCh, gc.HasLen, 0)
+
+	s.persister.Persist(frag1)
+	c.Check(s.persister.QueuedBytes(), gc.Equals, int64(20))
+	c.Check(s.persister.AppendAdmitted(), gc.IsNil) // Delayed, but not rejected.
+	c.Check(s.persister.convergeCh, gc.HasLen, 1)   // Converge is expedited.
+
+	var removed []string
+	s.persister.osRemove = func(path string) error {
+		removed = append(removed, path)
+		return nil
+	}
+	s.persister.converge()
+
+	c.Check(removed, gc.DeepEquals, []string{
+		"base/directory/a/journal/" + frag2.ContentName(),
+		"base/directory/a/journal/" + frag1.ContentName(),
+	})
+	c.Check(s.persister.queue, gc.HasLen, 0)
+	c.Check(s.persister.QueuedBytes(), gc.Equals, int64(0))
+	c.Check(s.persister.AppendAdmitted(), gc.IsNil)
+}
+
+func (s *PersisterSuite) TestAdmitDelay(c *gc.C) {
+	s.persister.maxAdmitDelay = time.Second
+
+	var verify = func(queued int64, expect time.Duration) {
+		s.persister.queuedBytes = queued
+		c.Check(s.persister.admitDelay(), gc.Equals, expect)
+	}
+	// Without a budget, appends are never delayed.
+	verify(100, 0)
+
+	s.persister.SetDiskBudget(100, 200)
+	verify(50, 0)
+	verify(100, 0)
+	verify(125, time.Second/4)
+	verify(150, time.Second/2)
+	verify(200, time.Second)
+	verify(250, time.Second)
+
+	// Without a limit, the full delay applies once the budget is exceeded.
+	s.persister.SetDiskBudget(100, 0)
+	verify(100, 0)
+	verify(101, time.Second)
+
+	s.persister.queuedBytes = 0
+}
+
+func (s *PersisterSuite) TestReadOnlyMode(c *gc.C) {
+	s.persister.SetDiskBudget(5, 15)
+	s.persister.maxAdmitDelay = 0
+	s.persister.osRemove = func(string) error { return nil }
+
+	var frag1, frag2 = s.fragment, s.fragment
+	frag2.Begin, frag2.End = 2000, 2010
+
+	c.Assert(s.cfs.MkdirAll(frag1.Journal.String(), 0740), gc.IsNil)
+	for _, f := range []journal.Fragment{frag1, frag2} {
+		// Fixture content is already present on the target filesystem.
+		w, err := s.cfs.OpenFile(f.ContentPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+		c.Assert(err, gc.IsNil)
+		c.Assert(w.Close(), gc.IsNil)
+	}
+	var lock1, lock2 = PersisterLocksRoot + frag1.ContentName(), PersisterLocksRoot + frag2.ContentName()
+
+	s.keysAPI.On("Set", mock.Anything, lock1, "route-key", mock.Anything).
+		Return(&etcd.Response{Index: 1234}, nil)
+	s.keysAPI.On("Delete", mock.Anything, lock1, mock.Anything).
+		Return(&etcd.Response{}, nil)
+	// The first attempt to persist |frag2| fails.
+	s.keysAPI.On("Set", mock.Anything, lock2, "route-key", mock.Anything).
+		Return(nil, errors.New("unavailable")).Once()
+
+	s.persister.Persist(frag1)
+	s.persister.Persist(frag2)
+	c.Check(s.persister.AppendAdmitted(), gc.Equals, journal.ErrInsufficientStorage)
+
+	// |frag1| is persisted, but the backlog remains above the budget.
+	s.persister.converge()
+	c.Check(s.persister.QueuedBytes(), gc.Equals, int64(10))
+	c.Check(s.persister.AppendAdmitted(), gc.Equals, journal.ErrInsufficientStorage)
+
+	s.keysAPI.On("Set", mock.Anything, lock2, "route-key", mock.Anything).
+		Return(&etcd.Response{Index: 1235}, nil)
+	s.keysAPI.On("Delete", mock.Anything, lock2, mock.Anything).
+		Return(&etcd.Response{}, nil)
+
+	// Persistence has caught up.
+	s.persister.converge()
+	c.Check(s.persister.QueuedBytes(), gc.Equals, int64(0))
+	c.Check(s.persister.AppendAdmitted(), gc.IsNil)
+
+	// Removing the limit also exits read-only mode.
+	s.persister.Persist(frag1)
+	s.persister.Persist(frag2)
+	c.Check(s.persister.AppendAdmitted(), gc.Equals, journal.ErrInsufficientStorage)
+	s.persister.SetDiskBudget(5, 0)
+	c.Check(s.persister.AppendAdmitted(), gc.IsNil)
+}
+
 func (s *PersisterSuite) TestStringFunction(c *gc.C) {
 	// Make sure that JSON marshaler doesn't choke on the |File| field.
 	fp, err := os.Open("/dev/urandom")
@@ -0,0 +1,148 @@
+package gazette
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type PrefetchSuite struct{}
+
+// fragmentStoreFixture is an httpClient which serves HEAD requests of
+// "a/journal" from 10-byte fragments persisted through |persisted|, and GETs
+// of their content. If non-zero, the fragment beginning at |missing| doesn't
+// exist, and reads of it skip to the next fragment.
+type fragmentStoreFixture struct {
+	persisted int64
+	writeHead int64
+	missing   int64
+}
+
+func (f fragmentStoreFixture) Do(request *http.Request) (*http.Response, error) {
+	if request.Method == "GET" && request.URL.Host == "cloud" {
+		return f.Get(request.URL.String())
+	} else if request.Method != "HEAD" {
+		return nil, fmt.Errorf("unexpected request: %s %s", request.Method, request.URL)
+	}
+	offset, _ := strconv.ParseInt(request.URL.Query().Get("offset"), 10, 64)
+
+	if f.missing != 0 && offset-offset%10 == f.missing {
+		offset = f.missing + 10
+	}
+	var fragment = journal.Fragment{Begin: offset - offset%10, End: offset - offset%10 + 10}
+	var response = &http.Response{
+		StatusCode: http.StatusPartialContent,
+		Header: http.Header{
+			"Content-Range":    []string{fmt.Sprintf("bytes %d-9999999999/9999999999", offset)},
+			WriteHeadHeader:    []string{strconv.FormatInt(f.writeHead, 10)},
+			FragmentNameHeader: []string{fragment.ContentName()},
+		},
+		Body: ioutil.NopCloser(strings.NewReader("")),
+	}
+	if fragment.End <= f.persisted {
+		response.Header.Set(FragmentLocationHeader,
+			fmt.Sprintf("http://cloud/%d", fragment.Begin))
+	}
+	return response, nil
+}
+
+func (f fragmentStoreFixture) Get(url string) (*http.Response, error) {
+	begin, err := strconv.ParseInt(strings.TrimPrefix(url, "http://cloud/"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(fragmentContentFixture(begin))),
+	}, nil
+}
+
+func fragmentContentFixture(begin int64) string {
+	return strings.Repeat(string('a'+byte(begin/10)), 10)
+}
+
+func (s *PrefetchSuite) TestStitchesPrefetchedFragments(c *gc.C) {
+	client, _ := NewClient("http://default")
+	client.httpClient = fragmentStoreFixture{persisted: 50, writeHead: 55}
+
+	var getter = NewPrefetchGetter(client, 2)
+
+	result, body := getter.Get(journal.ReadArgs{Journal: "a/journal", Offset: 5})
+	c.Assert(result.Error, gc.IsNil)
+	c.Check(result.Offset, gc.Equals, int64(5))
+	c.Check(result.Fragment.Begin, gc.Equals, int64(0))
+
+	// Expect persisted fragments are read through in order, and the stream
+	// ends at the first non-persisted offset.
+	data, err := ioutil.ReadAll(body)
+	c.Check(err, gc.IsNil)
+	c.Check(string(data), gc.Equals,
+		"aaaaa"+"bbbbbbbbbb"+"cccccccccc"+"dddddddddd"+"eeeeeeeeee")
+	c.Check(body.Close(), gc.IsNil)
+}
+
+func (s *PrefetchSuite) TestStopsAtJournalGap(c *gc.C) {
+	client, _ := NewClient("http://default")
+	client.httpClient = fragmentStoreFixture{persisted: 50, writeHead: 55, missing: 20}
+
+	var getter = NewPrefetchGetter(client, 2)
+
+	result, body := getter.Get(journal.ReadArgs{Journal: "a/journal", Offset: 5})
+	c.Assert(result.Error, gc.IsNil)
+
+	// Expect the stream ends at the gap, rather than joining fragments across
+	// it. A following read of offset 20 observes the skip to offset 30.
+	data, err := ioutil.ReadAll(body)
+	c.Check(err, gc.IsNil)
+	c.Check(string(data), gc.Equals, "aaaaa"+"bbbbbbbbbb")
+	c.Check(body.Close(), gc.IsNil)
+
+	result, body = getter.Get(journal.ReadArgs{Journal: "a/journal", Offset: 20})
+	c.Assert(result.Error, gc.IsNil)
+	c.Check(result.Offset, gc.Equals, int64(30))
+	c.Check(body.Close(), gc.IsNil)
+}
+
+func (s *PrefetchSuite) TestCloseBeforeReadingAll(c *gc.C) {
+	client, _ := NewClient("http://default")
+	client.httpClient = fragmentStoreFixture{persisted: 1000, writeHead: 1000}
+
+	var getter = NewPrefetchGetter(client, 3)
+
+	result, body := getter.Get(journal.ReadArgs{Journal: "a/journal", Offset: 12})
+	c.Assert(result.Error, gc.IsNil)
+
+	var buf = make([]byte, 12)
+	n, err := body.Read(buf)
+	c.Check(err, gc.IsNil)
+	c.Check(string(buf[:n]), gc.Equals, "bbbbbbbb")
+
+	n, err = body.Read(buf)
+	c.Check(err, gc.IsNil)
+	c.Check(string(buf[:n]), gc.Equals, "cccccccccc")
+
+	// Expect Close aborts and cleans up outstanding fetches.
+	c.Check(body.Close(), gc.IsNil)
+}
+
+func (s *PrefetchSuite) TestPassesThroughNonPersistedReads(c *gc.C) {
+	var fixture = fragmentStoreFixture{persisted: 0, writeHead: 55}
+
+	client, _ := NewClient("http://default")
+	client.httpClient = fixture
+
+	// A HEAD without a fragment location falls back to a direct GET, which
+	// the fixture rejects.
+	result, body := NewPrefetchGetter(client, 2).Get(
+		journal.ReadArgs{Journal: "a/journal", Offset: 5})
+	c.Check(result.Error, gc.ErrorMatches, "unexpected request: GET .*")
+	c.Check(body, gc.IsNil)
+}
+
+var _ = gc.Suite(&PrefetchSuite{})
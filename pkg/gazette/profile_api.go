@@ -0,0 +1,143 @@
+package gazette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"runtime/pprof"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/cloudstore"
+)
+
+// ProfileCapturePath is the request path of the ProfileAPI.
+const ProfileCapturePath = "/debug/pprof/capture"
+
+// Default and maximum durations of a captured CPU profile.
+const (
+	defaultCPUProfileDuration = 30 * time.Second
+	maxCPUProfileDuration     = time.Minute
+)
+
+// ProfileCapture describes profiles captured by the ProfileAPI.
+type ProfileCapture struct {
+	// Paths of captured profiles within the capture FileSystem.
+	Paths []string
+}
+
+// ProfileAPI captures goroutine, heap, and CPU profiles of the process on
+// demand, and stores them to a cloudstore.FileSystem for later retrieval. This
+// allows production stalls to be debugged without requiring access to the
+// broker's host. The FileSystem should not be that of the fragment store, where
+// profiles would be mistaken for journal content. A POST captures a CPU profile
+// of 30 seconds, or of the "seconds" query argument (at most 60), and responds
+// with the JSON ProfileCapture once all profiles are stored. Only one capture
+// may run at a time.
+//
+// ProfileAPI is intended to be served alongside net/http/pprof handlers,
+// on a separate administrative port (see mainboilerplate).
+type ProfileAPI struct {
+	cfs cloudstore.FileSystem
+	dir string
+
+	busy    chan struct{} // Held while a capture is running.
+	timeNow func() time.Time
+}
+
+// NewProfileAPI returns a ProfileAPI which stores captures beneath |dir| of |cfs|.
+func NewProfileAPI(cfs cloudstore.FileSystem, dir string) *ProfileAPI {
+	return &ProfileAPI{
+		cfs:     cfs,
+		dir:     dir,
+		busy:    make(chan struct{}, 1),
+		timeNow: time.Now,
+	}
+}
+
+func (h *ProfileAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var duration = defaultCPUProfileDuration
+	if s := r.URL.Query().Get("seconds"); s != "" {
+		if n, err := strconv.ParseFloat(s, 64); err != nil || n < 0 ||
+			n > maxCPUProfileDuration.Seconds() {
+			http.Error(w, fmt.Sprintf("invalid seconds: %q", s), http.StatusBadRequest)
+			return
+		} else {
+			duration = time.Duration(n * float64(time.Second))
+		}
+	}
+
+	select {
+	case h.busy <- struct{}{}:
+		defer func() { <-h.busy }()
+	default:
+		http.Error(w, "a capture is already running", http.StatusConflict)
+		return
+	}
+
+	var capture, err = h.capture(duration)
+	if err != nil {
+		log.WithField("err", err).Warn("failed to capture profiles")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(capture)
+}
+
+// capture profiles the process, including a CPU profile of |duration|, and
+// stores each profile to a directory of the capture time.
+func (h *ProfileAPI) capture(duration time.Duration) (ProfileCapture, error) {
+	var dir = path.Join(h.dir, h.timeNow().UTC().Format("20060102T150405Z"))
+	var out ProfileCapture
+
+	if err := h.cfs.MkdirAll(dir, 0750); err != nil {
+		return out, err
+	}
+	for _, name := range []string{"goroutine", "heap"} {
+		var buf bytes.Buffer
+		if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+			return out, fmt.Errorf("%s profile: %s", name, err)
+		} else if p, err := h.store(dir, name, &buf); err != nil {
+			return out, err
+		} else {
+			out.Paths = append(out.Paths, p)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return out, fmt.Errorf("cpu profile: %s", err)
+	}
+	time.Sleep(duration)
+	pprof.StopCPUProfile()
+
+	if p, err := h.store(dir, "cpu", &buf); err != nil {
+		return out, err
+	} else {
+		out.Paths = append(out.Paths, p)
+	}
+	return out, nil
+}
+
+// store writes profile |name| of |buf| to |dir|, returning its path.
+func (h *ProfileAPI) store(dir, name string, buf *bytes.Buffer) (string, error) {
+	var p = path.Join(dir, name+".pprof")
+
+	if f, err := h.cfs.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640); err != nil {
+		return "", fmt.Errorf("opening %s: %s", p, err)
+	} else if _, err = h.cfs.CopyAtomic(f, buf); err != nil {
+		return "", fmt.Errorf("writing %s: %s", p, err)
+	}
+	return p, nil
+}
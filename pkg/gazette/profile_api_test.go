@@ -0,0 +1,71 @@
+package gazette
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/cloudstore"
+)
+
+type ProfileAPISuite struct{}
+
+func (s *ProfileAPISuite) TestCapture(c *gc.C) {
+	var cfs = cloudstore.NewTmpFileSystem()
+	defer cfs.Close()
+
+	var api = NewProfileAPI(cfs, "profiles/broker")
+	api.timeNow = func() time.Time { return time.Unix(1500000000, 0) }
+
+	var w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("POST", ProfileCapturePath+"?seconds=0.01", nil))
+	c.Assert(w.Code, gc.Equals, http.StatusOK)
+
+	var capture ProfileCapture
+	c.Check(json.NewDecoder(w.Body).Decode(&capture), gc.IsNil)
+	c.Check(capture.Paths, gc.DeepEquals, []string{
+		"profiles/broker/20170714T024000Z/goroutine.pprof",
+		"profiles/broker/20170714T024000Z/heap.pprof",
+		"profiles/broker/20170714T024000Z/cpu.pprof",
+	})
+	for _, p := range capture.Paths {
+		var f, err = cfs.Open(p)
+		c.Assert(err, gc.IsNil)
+		var b, _ = ioutil.ReadAll(f)
+		c.Check(len(b), gc.Not(gc.Equals), 0)
+		f.Close()
+	}
+
+	// A capture at the same time fails, as profiles already exist.
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("POST", ProfileCapturePath+"?seconds=0", nil))
+	c.Check(w.Code, gc.Equals, http.StatusInternalServerError)
+}
+
+func (s *ProfileAPISuite) TestRequestValidation(c *gc.C) {
+	var api = NewProfileAPI(cloudstore.NewTmpFileSystem(), "profiles")
+
+	var w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("GET", ProfileCapturePath, nil))
+	c.Check(w.Code, gc.Equals, http.StatusMethodNotAllowed)
+
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("POST", ProfileCapturePath+"?seconds=-1", nil))
+	c.Check(w.Code, gc.Equals, http.StatusBadRequest)
+
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("POST", ProfileCapturePath+"?seconds=61", nil))
+	c.Check(w.Code, gc.Equals, http.StatusBadRequest)
+
+	// Only one capture may run at a time.
+	api.busy <- struct{}{}
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest("POST", ProfileCapturePath+"?seconds=0", nil))
+	c.Check(w.Code, gc.Equals, http.StatusConflict)
+}
+
+var _ = gc.Suite(&ProfileAPISuite{})
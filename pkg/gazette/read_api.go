@@ -21,8 +21,17 @@ type ReadAPI struct {
 	cfs     cloudstore.FileSystem
 	decoder *schema.Decoder
 	handler ReadOpHandler
+	tokens  *ReadTokenSigner
+	filter  ReadFilter
 }
 
+// ReadFilter returns a function which transforms content of journal |name|
+// streamed to request |r|, or nil if the read is unfiltered. For example, a
+// ReadFilter may redact sensitive fields of a journal unless PeerIdentity(r)
+// is privileged to read raw content. Transforms must preserve content length,
+// as readers track journal offsets by bytes read.
+type ReadFilter func(name journal.Name, r *http.Request) func(content io.Reader) io.Reader
+
 func NewReadAPI(handler ReadOpHandler, cfs cloudstore.FileSystem) *ReadAPI {
 	decoder := schema.NewDecoder()
 	decoder.IgnoreUnknownKeys(false)
@@ -31,6 +40,20 @@ func NewReadAPI(handler ReadOpHandler, cfs cloudstore.FileSystem) *ReadAPI {
 	return &ReadAPI{handler: handler, cfs: cfs, decoder: decoder}
 }
 
+// RequireReadTokens requires that reads which don't present a verified client
+// certificate instead present a read token, verified by |signer|, which grants
+// the read. Reads of such requests are limited to the granted offset range.
+func (h *ReadAPI) RequireReadTokens(signer *ReadTokenSigner) {
+	h.tokens = signer
+}
+
+// SetReadFilter sets a ReadFilter of content streamed by reads. Filtered reads
+// aren't offered direct URLs of remote fragments, which would bypass the
+// filter, and instead stream remote fragments through the broker.
+func (h *ReadAPI) SetReadFilter(filter ReadFilter) {
+	h.filter = filter
+}
+
 func (h *ReadAPI) Register(router *mux.Router) {
 	router.NewRoute().Methods("HEAD").HandlerFunc(h.Head)
 	router.NewRoute().Methods("GET").HandlerFunc(h.Read)
@@ -40,21 +63,16 @@ func (h *ReadAPI) Head(w http.ResponseWriter, r *http.Request) {
 	r = maybeTrace(r, "ReadAPI.Head")
 	defer finishTrace(r)
 
-	var op, result = h.initialRead(w, r)
-
-	switch result.Error {
-	case nil, journal.ErrNotYetAvailable, journal.ErrNotReplica, journal.ErrNotFound:
-		// Common expected error cases: don't log.
-	default:
-		log.WithFields(log.Fields{"err": result.Error, "ReadOp": op}).Warn("head failed")
-	}
+	// Failed requests are logged by the AccessLog, if any.
+	h.initialRead(w, r, h.transformOf(r))
 }
 
 func (h *ReadAPI) Read(w http.ResponseWriter, r *http.Request) {
 	r = maybeTrace(r, "ReadAPI.Read")
 	defer finishTrace(r)
 
-	var op, result = h.initialRead(w, r)
+	var transform = h.transformOf(r)
+	var op, result, grant = h.initialRead(w, r, transform)
 
 	// Loop performing incremental reads and copying to the client. If we fail
 	// here, we log and just drop the connection (since we've already written
@@ -62,7 +80,7 @@ func (h *ReadAPI) Read(w http.ResponseWriter, r *http.Request) {
 	for iter := 0; true; iter++ {
 
 		switch result.Error {
-		case journal.ErrNotYetAvailable, journal.ErrNotReplica, journal.ErrNotFound:
+		case journal.ErrNotYetAvailable, journal.ErrNotReplica, journal.ErrNotFound, ErrReadNotGranted:
 			return // Common error cases: don't log.
 		case nil:
 			// Fall through.
@@ -93,6 +111,13 @@ func (h *ReadAPI) Read(w http.ResponseWriter, r *http.Request) {
 				Warn("failed to get a fragment reader")
 			break
 		}
+		if grant != nil && grant.End != 0 {
+			// Read no further than the token grants.
+			reader = io.LimitReader(reader, grant.End-result.Offset)
+		}
+		if transform != nil {
+			reader = transform(reader)
+		}
 
 		delta, err := io.Copy(w, reader)
 		if err != nil {
@@ -105,22 +130,35 @@ func (h *ReadAPI) Read(w http.ResponseWriter, r *http.Request) {
 		}
 		op.Offset = result.Offset + delta
 
+		if grant != nil && !grant.Allows(op.Journal, op.Offset) {
+			break
+		}
 		// Next incremental read.
 		h.handler.Read(op)
 		result = <-op.Result
 	}
 }
 
-func (h *ReadAPI) initialRead(w http.ResponseWriter, r *http.Request) (journal.ReadOp,
-	journal.ReadResult) {
+// transformOf returns the content transform of read request |r|, or nil.
+func (h *ReadAPI) transformOf(r *http.Request) func(io.Reader) io.Reader {
+	if h.filter == nil {
+		return nil
+	}
+	return h.filter(journal.Name(r.URL.Path[1:]), r)
+}
+
+func (h *ReadAPI) initialRead(w http.ResponseWriter, r *http.Request,
+	transform func(io.Reader) io.Reader) (journal.ReadOp, journal.ReadResult, *ReadGrant) {
 
 	var schema struct {
 		Offset  int64 // Required.
 		Block   bool
 		BlockMS int64
+		Token   string
 	}
 	var op journal.ReadOp
 	var result journal.ReadResult
+	var grant *ReadGrant
 
 	if result.Error = r.ParseForm(); result.Error == nil {
 		result.Error = h.decoder.Decode(&schema, r.Form)
@@ -131,7 +169,20 @@ func (h *ReadAPI) initialRead(w http.ResponseWriter, r *http.Request) (journal.R
 			tr.SetError()
 		}
 		http.Error(w, result.Error.Error(), http.StatusBadRequest)
-		return op, result
+		return op, result, nil
+	}
+
+	if h.tokens != nil && PeerIdentity(r) == "" {
+		var g, err = h.tokens.Verify(readTokenFromRequest(r))
+		if err == nil && g.Journal != journal.Name(r.URL.Path[1:]) {
+			err = ErrReadNotGranted
+		}
+		if err != nil {
+			result.Error = ErrReadNotGranted
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return op, result, nil
+		}
+		grant = &g
 	}
 
 	var deadline time.Time
@@ -165,15 +216,20 @@ func (h *ReadAPI) initialRead(w http.ResponseWriter, r *http.Request) (journal.R
 		// Return a 302 redirect on a routing error.
 		if result.Error == journal.ErrNotReplica {
 			brokerRedirect(w, r, result.RouteToken, journal.StatusCodeForError(result.Error))
-			return op, result
+			return op, result, grant
 		}
 		// Fail now if we encountered an error other than ErrNotYetAvailable,
 		// or we saw ErrNotYetAvailable for a non-blocking read.
 		if schema.Block == false || result.Error != journal.ErrNotYetAvailable {
 			http.Error(w, result.Error.Error(), journal.StatusCodeForError(result.Error))
-			return op, result
+			return op, result, grant
 		}
 	}
+	if grant != nil && !grant.Allows(op.Journal, result.Offset) {
+		result.Error = ErrReadNotGranted
+		http.Error(w, result.Error.Error(), http.StatusForbidden)
+		return op, result, grant
+	}
 	// Switch to requested blocking mode.
 	op.Blocking = schema.Block
 	op.Deadline = deadline
@@ -188,9 +244,16 @@ func (h *ReadAPI) initialRead(w http.ResponseWriter, r *http.Request) (journal.R
 		w.Header().Add(FragmentNameHeader, result.Fragment.ContentName())
 		// If this is a remote fragment, also include a signed URL for direct access.
 		// This allows the client to abort this request (or better: use HEAD first),
-		// and then directly fetch content from cloud storage.
-		if !result.Fragment.IsLocal() {
-			url, err := result.Fragment.AsDirectURL(h.cfs, time.Minute)
+		// and then directly fetch content from cloud storage. A signed URL exposes
+		// the entire fragment, and isn't offered to a read limited by a grant
+		// which doesn't cover it.
+		if !result.Fragment.IsLocal() && transform == nil &&
+			(grant == nil || grant.Covers(result.Fragment)) {
+			var ttl = time.Minute
+			if grant != nil && grant.Expires.Sub(time.Now()) < ttl {
+				ttl = grant.Expires.Sub(time.Now()) // Don't outlive the token.
+			}
+			url, err := result.Fragment.AsDirectURL(h.cfs, ttl)
 
 			if err == nil {
 				w.Header().Add(FragmentLocationHeader, url.String())
@@ -213,5 +276,5 @@ func (h *ReadAPI) initialRead(w http.ResponseWriter, r *http.Request) (journal.R
 		h.handler.Read(op)
 		result = <-op.Result
 	}
-	return op, result
+	return op, result, grant
 }
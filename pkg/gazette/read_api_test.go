@@ -1,7 +1,9 @@
 package gazette
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"net/http"
@@ -275,15 +277,19 @@ func (s *ReadAPISuite) TestHEADWithRemoteFragment(c *gc.C) {
 					Begin:   12350,
 					End:     12371,
 				},
+				RouteToken: "http://foo|http://bar",
 			}
 		},
 	}
 	s.mux.ServeHTTP(w, req)
 
+	// Expect the covering fragment, its location, the write head and route
+	// are returned, without content.
 	c.Check(w.Code, gc.Equals, http.StatusPartialContent)
 	c.Check(w.HeaderMap.Get("Content-Range"), gc.Equals,
 		fmt.Sprintf("bytes 12350-%v/%v", math.MaxInt64, math.MaxInt64))
 	c.Check(w.HeaderMap.Get(WriteHeadHeader), gc.Equals, "12371")
+	c.Check(w.HeaderMap.Get(RouteTokenHeader), gc.Equals, "http://foo|http://bar")
 	c.Check(w.HeaderMap.Get(FragmentNameHeader), gc.Equals,
 		"000000000000303e-0000000000003053-0000000000000000000000000000000000000000")
 	c.Check(w.HeaderMap.Get(FragmentLocationHeader), gc.Matches,
@@ -356,6 +362,143 @@ func (s *ReadAPISuite) TestInternalError(c *gc.C) {
 	c.Check(w.Body.String(), gc.Equals, "some error\n")
 }
 
+func (s *ReadAPISuite) TestReadTokens(c *gc.C) {
+	var signer = NewReadTokenSigner([]byte("secret"))
+	var m = mux.NewRouter()
+
+	var api = NewReadAPI(s, s.cfs)
+	api.RequireReadTokens(signer)
+	api.Register(m)
+
+	// Reads without a token are rejected.
+	req, _ := http.NewRequest("GET", "/journal/name?offset=12350", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	c.Check(w.Code, gc.Equals, http.StatusForbidden)
+
+	// As are reads of a journal other than that granted.
+	token, err := signer.Issue(ReadGrant{
+		Journal: "other/journal",
+		Expires: time.Now().Add(time.Minute),
+	})
+	c.Assert(err, gc.IsNil)
+
+	req, _ = http.NewRequest("GET", "/journal/name?offset=12350&token="+token, nil)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	c.Check(w.Code, gc.Equals, http.StatusForbidden)
+
+	// A granted read is limited to the granted range.
+	token, err = signer.Issue(ReadGrant{
+		Journal: "journal/name",
+		Begin:   12350,
+		End:     12358,
+		Expires: time.Now().Add(time.Minute),
+	})
+	c.Assert(err, gc.IsNil)
+
+	s.readCallbacks = []func(journal.ReadOp){
+		func(op journal.ReadOp) {
+			op.Result <- journal.ReadResult{
+				Offset:    12350,
+				WriteHead: 12371,
+				Fragment:  s.spool.Fragment,
+			}
+		},
+	}
+	req, _ = http.NewRequest("GET", "/journal/name?offset=12350", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+
+	c.Check(w.Code, gc.Equals, http.StatusPartialContent)
+	c.Check(w.Body.String(), gc.Equals, "expected")
+
+	// Reads at offsets outside the grant are rejected.
+	s.readCallbacks = []func(journal.ReadOp){
+		func(op journal.ReadOp) {
+			op.Result <- journal.ReadResult{
+				Offset:    12360,
+				WriteHead: 12371,
+				Fragment:  s.spool.Fragment,
+			}
+		},
+	}
+	req, _ = http.NewRequest("GET", "/journal/name?offset=12360", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	c.Check(w.Code, gc.Equals, http.StatusForbidden)
+	// A direct URL of a remote fragment is offered only if the grant covers
+	// the entire fragment.
+	var head = func(fragment journal.Fragment) *httptest.ResponseRecorder {
+		s.readCallbacks = []func(journal.ReadOp){
+			func(op journal.ReadOp) {
+				op.Result <- journal.ReadResult{Offset: 12350, WriteHead: 12371, Fragment: fragment}
+			},
+		}
+		req, _ := http.NewRequest("HEAD", "/journal/name?offset=12350", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		var w = httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w
+	}
+	w = head(journal.Fragment{Journal: "journal/name", Begin: 12350, End: 12358})
+	c.Check(w.Code, gc.Equals, http.StatusPartialContent)
+	c.Check(w.HeaderMap.Get(FragmentLocationHeader), gc.Not(gc.Equals), "")
+
+	w = head(journal.Fragment{Journal: "journal/name", Begin: 12340, End: 12371})
+	c.Check(w.Code, gc.Equals, http.StatusPartialContent)
+	c.Check(w.HeaderMap.Get(FragmentLocationHeader), gc.Equals, "")
+}
+
+func (s *ReadAPISuite) TestReadFilter(c *gc.C) {
+	var m = mux.NewRouter()
+	var api = NewReadAPI(s, s.cfs)
+	api.Register(m)
+
+	// Content of reads is upper-cased, unless the reader asks for it raw.
+	api.SetReadFilter(func(name journal.Name, r *http.Request) func(io.Reader) io.Reader {
+		c.Check(name, gc.Equals, journal.Name("journal/name"))
+
+		if r.Header.Get("X-Raw") != "" {
+			return nil
+		}
+		return func(content io.Reader) io.Reader {
+			var b, _ = ioutil.ReadAll(content)
+			return bytes.NewReader(bytes.ToUpper(b))
+		}
+	})
+	var read = func(method string, raw bool, fragment journal.Fragment) *httptest.ResponseRecorder {
+		s.readCallbacks = []func(journal.ReadOp){
+			func(op journal.ReadOp) {
+				op.Result <- journal.ReadResult{Offset: 12350, WriteHead: 12371, Fragment: fragment}
+			},
+		}
+		if method == "GET" {
+			s.readCallbacks = append(s.readCallbacks, func(op journal.ReadOp) {
+				op.Result <- journal.ReadResult{Error: journal.ErrNotYetAvailable, Offset: 12371}
+			})
+		}
+		req, _ := http.NewRequest(method, "/journal/name?offset=12350", nil)
+		if raw {
+			req.Header.Set("X-Raw", "1")
+		}
+		var w = httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w
+	}
+
+	c.Check(read("GET", false, s.spool.Fragment).Body.String(), gc.Equals, "EXPECTED READ FIXTURE")
+	c.Check(read("GET", true, s.spool.Fragment).Body.String(), gc.Equals, "expected read fixture")
+
+	// Filtered reads aren't offered a direct URL of a remote fragment.
+	var remote = journal.Fragment{Journal: "journal/name", Begin: 12350, End: 12371}
+
+	c.Check(read("HEAD", false, remote).HeaderMap.Get(FragmentLocationHeader), gc.Equals, "")
+	c.Check(read("HEAD", true, remote).HeaderMap.Get(FragmentLocationHeader), gc.Not(gc.Equals), "")
+}
+
 // Implementation of ReadOpHandler.
 func (s *ReadAPISuite) Read(op journal.ReadOp) {
 	s.readCallbacks[0](op)
@@ -0,0 +1,171 @@
+package gazette
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+var (
+	ErrInvalidReadToken = errors.New("invalid read token")
+	ErrExpiredReadToken = errors.New("read token expired")
+	ErrReadNotGranted   = errors.New("read not granted by token")
+)
+
+// ReadGrant grants read access to a byte range of a journal until Expires.
+type ReadGrant struct {
+	Journal journal.Name
+	// Offset range [Begin, End) which may be read. An End of zero is unbounded.
+	Begin, End int64
+	Expires    time.Time
+}
+
+// Allows returns whether the grant permits a read of |name| at |offset|.
+func (g ReadGrant) Allows(name journal.Name, offset int64) bool {
+	return g.Journal == name && offset >= g.Begin && (g.End == 0 || offset < g.End)
+}
+
+// Covers returns whether the grant permits reads of all of |fragment|.
+func (g ReadGrant) Covers(fragment journal.Fragment) bool {
+	return g.Journal == fragment.Journal && fragment.Begin >= g.Begin &&
+		(g.End == 0 || fragment.End <= g.End)
+}
+
+// ReadTokenSigner issues and verifies ReadGrants as signed, opaque tokens.
+// Brokers sharing a key verify each other's tokens, allowing short-lived
+// readers (eg, browser dashboards) to be granted narrow, expiring access
+// without holding full cluster credentials.
+type ReadTokenSigner struct {
+	key []byte
+	// Test support: allow time.Now() to be swapped out.
+	timeNow func() time.Time
+}
+
+func NewReadTokenSigner(key []byte) *ReadTokenSigner {
+	return &ReadTokenSigner{key: key, timeNow: time.Now}
+}
+
+// Issue returns a token representing |grant|.
+func (s *ReadTokenSigner) Issue(grant ReadGrant) (string, error) {
+	payload, err := json.Marshal(grant)
+	if err != nil {
+		return "", err
+	}
+	var enc = base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.sign(payload)), nil
+}
+
+// Verify returns the ReadGrant of |token|, or an error if the token wasn't
+// issued by a signer of the same key, or has expired.
+func (s *ReadTokenSigner) Verify(token string) (ReadGrant, error) {
+	var grant ReadGrant
+	var enc = base64.RawURLEncoding
+
+	var parts = strings.Split(token, ".")
+	if len(parts) != 2 {
+		return grant, ErrInvalidReadToken
+	}
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return grant, ErrInvalidReadToken
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, s.sign(payload)) {
+		return grant, ErrInvalidReadToken
+	}
+	if err = json.Unmarshal(payload, &grant); err != nil {
+		return grant, ErrInvalidReadToken
+	}
+	if !s.timeNow().Before(grant.Expires) {
+		return grant, ErrExpiredReadToken
+	}
+	return grant, nil
+}
+
+func (s *ReadTokenSigner) sign(payload []byte) []byte {
+	var mac = hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// readTokenFromRequest returns a read token presented as an "Authorization:
+// Bearer" header, or as a "token" query argument.
+func readTokenFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+// ReadTokenAPI issues read tokens. Issuance is itself a privileged operation,
+// and requires that the client present a verified certificate (see
+// NewServerTLSConfig).
+type ReadTokenAPI struct {
+	signer *ReadTokenSigner
+	maxTTL time.Duration
+}
+
+// ReadTokenPath is the request path of the ReadTokenAPI.
+const ReadTokenPath = "/_read-token"
+
+func NewReadTokenAPI(signer *ReadTokenSigner, maxTTL time.Duration) *ReadTokenAPI {
+	return &ReadTokenAPI{signer: signer, maxTTL: maxTTL}
+}
+
+// Register the ReadTokenAPI. It must be registered before other APIs, as
+// their routes match all paths.
+func (h *ReadTokenAPI) Register(router *mux.Router) {
+	router.Path(ReadTokenPath).Methods("POST").HandlerFunc(h.Issue)
+}
+
+// Issue responds with a token granting reads of the "journal" form argument,
+// over offsets ["begin", "end"), for "ttl" (a time.Duration, limited to the
+// API's maximum TTL).
+func (h *ReadTokenAPI) Issue(w http.ResponseWriter, r *http.Request) {
+	if PeerIdentity(r) == "" {
+		http.Error(w, "client certificate required", http.StatusForbidden)
+		return
+	}
+	var grant = ReadGrant{Journal: journal.Name(r.FormValue("journal"))}
+	var ttl = h.maxTTL
+	var err error
+
+	if grant.Journal == "" {
+		err = errors.New("expected journal")
+	}
+	if s := r.FormValue("begin"); s != "" && err == nil {
+		grant.Begin, err = strconv.ParseInt(s, 10, 64)
+	}
+	if s := r.FormValue("end"); s != "" && err == nil {
+		grant.End, err = strconv.ParseInt(s, 10, 64)
+	}
+	if s := r.FormValue("ttl"); s != "" && err == nil {
+		ttl, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ttl > h.maxTTL {
+		ttl = h.maxTTL
+	}
+	grant.Expires = h.signer.timeNow().Add(ttl)
+
+	token, err := h.signer.Issue(grant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(token))
+}
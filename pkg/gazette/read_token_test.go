@@ -0,0 +1,92 @@
+package gazette
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	gc "github.com/go-check/check"
+	"github.com/gorilla/mux"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type ReadTokenSuite struct{}
+
+func (s *ReadTokenSuite) TestIssueAndVerify(c *gc.C) {
+	var signer = NewReadTokenSigner([]byte("secret"))
+	signer.timeNow = func() time.Time { return time.Unix(1000, 0) }
+
+	var grant = ReadGrant{
+		Journal: "a/journal",
+		Begin:   100,
+		End:     200,
+		Expires: time.Unix(1060, 0).UTC(),
+	}
+	token, err := signer.Issue(grant)
+	c.Assert(err, gc.IsNil)
+
+	verified, err := signer.Verify(token)
+	c.Check(err, gc.IsNil)
+	c.Check(verified, gc.DeepEquals, grant)
+
+	// A signer of a different key rejects the token.
+	_, err = NewReadTokenSigner([]byte("other")).Verify(token)
+	c.Check(err, gc.Equals, ErrInvalidReadToken)
+
+	// As does a signer after the token expires.
+	signer.timeNow = func() time.Time { return time.Unix(1060, 0) }
+	_, err = signer.Verify(token)
+	c.Check(err, gc.Equals, ErrExpiredReadToken)
+
+	// Malformed and tampered tokens are rejected.
+	for _, t := range []string{"", "foo", "foo.bar", "x" + token, token[:len(token)-2]} {
+		_, err = signer.Verify(t)
+		c.Check(err, gc.Equals, ErrInvalidReadToken)
+	}
+}
+
+func (s *ReadTokenSuite) TestGrantAllows(c *gc.C) {
+	var grant = ReadGrant{Journal: "a/journal", Begin: 100, End: 200}
+
+	c.Check(grant.Allows("a/journal", 99), gc.Equals, false)
+	c.Check(grant.Allows("a/journal", 100), gc.Equals, true)
+	c.Check(grant.Allows("a/journal", 199), gc.Equals, true)
+	c.Check(grant.Allows("a/journal", 200), gc.Equals, false)
+	c.Check(grant.Allows("other/journal", 150), gc.Equals, false)
+
+	grant.End = 0 // Unbounded.
+	c.Check(grant.Allows("a/journal", 1<<40), gc.Equals, true)
+}
+
+func (s *ReadTokenSuite) TestGrantCovers(c *gc.C) {
+	var grant = ReadGrant{Journal: "a/journal", Begin: 100, End: 200}
+	var fragment = func(name journal.Name, begin, end int64) journal.Fragment {
+		return journal.Fragment{Journal: name, Begin: begin, End: end}
+	}
+
+	c.Check(grant.Covers(fragment("a/journal", 100, 200)), gc.Equals, true)
+	c.Check(grant.Covers(fragment("a/journal", 90, 150)), gc.Equals, false)
+	c.Check(grant.Covers(fragment("a/journal", 150, 210)), gc.Equals, false)
+	c.Check(grant.Covers(fragment("other/journal", 100, 200)), gc.Equals, false)
+
+	grant.End = 0 // Unbounded.
+	c.Check(grant.Covers(fragment("a/journal", 150, 1<<40)), gc.Equals, true)
+}
+
+func (s *ReadTokenSuite) TestAPIRequiresClientCertificate(c *gc.C) {
+	var m = mux.NewRouter()
+	NewReadTokenAPI(NewReadTokenSigner([]byte("secret")), time.Minute).Register(m)
+
+	req, _ := http.NewRequest("POST", ReadTokenPath,
+		strings.NewReader(url.Values{"journal": {"a/journal"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var w = httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	c.Check(w.Code, gc.Equals, http.StatusForbidden)
+}
+
+var _ = gc.Suite(&ReadTokenSuite{})
@@ -1,7 +1,10 @@
 package gazette
 
 import (
+	"compress/gzip"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 
@@ -76,8 +79,11 @@ func (h *ReplicateAPI) Replicate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var n, commitDelta int64
+	var body io.Reader
 
-	if n, err = io.Copy(result.Writer, r.Body); err != nil {
+	if body, err = replicateBody(r); err != nil {
+		result.Writer.Commit(0) // Abort.
+	} else if n, err = journal.CopyPooled(result.Writer, body); err != nil {
 		result.Writer.Commit(0) // Abort.
 	} else if commitDelta, err = strconv.ParseInt(
 		r.Trailer.Get(CommitDeltaHeader), 16, 64); err != nil {
@@ -104,3 +110,31 @@ func (h *ReplicateAPI) Replicate(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent) // Success.
 }
+
+// replicateBody returns the transaction content of REPLICATE request |r|,
+// decompressing it if it's gzipped (see ReplicateClient.SetCompression). The returned
+// reader consumes |r.Body| through EOF, so that trailers are available.
+func replicateBody(r *http.Request) (io.Reader, error) {
+	switch enc := r.Header.Get("Content-Encoding"); enc {
+	case "":
+		return r.Body, nil
+	case "gzip":
+		var zr, err = gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return io.MultiReader(zr, drainReader{r.Body}), nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", enc)
+	}
+}
+
+// drainReader discards remaining content of its Reader, and returns io.EOF.
+type drainReader struct{ io.Reader }
+
+func (d drainReader) Read([]byte) (int, error) {
+	if _, err := io.Copy(ioutil.Discard, d.Reader); err != nil {
+		return 0, err
+	}
+	return 0, io.EOF
+}
@@ -3,6 +3,8 @@ package gazette
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -23,6 +25,8 @@ import (
 )
 
 const (
+	AppendProducerHeader       = "X-Append-Producer"
+	AppendSumHeader            = "X-Append-Sum"
 	CommitDeltaHeader          = "X-Commit-Delta"
 	FragmentLastModifiedHeader = "X-Fragment-Last-Modified"
 	FragmentLocationHeader     = "X-Fragment-Location"
@@ -34,8 +38,10 @@ const (
 )
 
 type ReplicateClient struct {
-	endpoint *CachedURL
-	idlePool chan replicaClientConn
+	endpoint  *CachedURL
+	idlePool  chan replicaClientConn
+	tlsConfig *tls.Config
+	compress  bool
 }
 
 type replicaClientConn struct {
@@ -47,6 +53,7 @@ type replicaClientTransaction struct {
 	client ReplicateClient
 
 	chunker io.WriteCloser
+	gzipper *gzip.Writer // Non-nil if the client compresses.
 	conn    replicaClientConn
 	request *http.Request
 }
@@ -68,6 +75,22 @@ func NewReplicateClient(ep *CachedURL) ReplicateClient {
 	}
 }
 
+// SetTLSConfig sets a tls.Config used when dialing an https:// endpoint.
+// Typically a broker presents its own server certificate as a client
+// certificate to peers (see NewClientTLSConfig).
+func (c *ReplicateClient) SetTLSConfig(cfg *tls.Config) {
+	c.tlsConfig = cfg
+}
+
+// SetCompression sets whether transaction content replicated by the client
+// is gzipped. This trades broker CPU for reduced bandwidth, and is worthwhile
+// where peers replicate across zones or regions. ReplicateAPI accepts
+// compressed content regardless, but compression must be enabled only once
+// all brokers of the cluster support it.
+func (c *ReplicateClient) SetCompression(compress bool) {
+	c.compress = compress
+}
+
 func (c ReplicateClient) Replicate(op journal.ReplicateOp) {
 	transaction := replicaClientTransaction{client: c}
 	go transaction.start(op)
@@ -88,6 +111,9 @@ func (t *replicaClientTransaction) start(op journal.ReplicateOp) {
 	req.URL.RawQuery = queryArgs.Encode()
 	req.Header.Add("Expect", "100-continue")
 	req.Header.Add("Trailer", CommitDeltaHeader)
+	if t.client.compress {
+		req.Header.Add("Content-Encoding", "gzip")
+	}
 	req.TransferEncoding = []string{"chunked"}
 
 	reqBytes, err := httpdump.DumpRequest(req, false)
@@ -133,6 +159,10 @@ func (t *replicaClientTransaction) start(op journal.ReplicateOp) {
 	// We've now opened a transaction stream.
 	conn.raw.SetReadDeadline(time.Time{}) // Clear timeout.
 	t.chunker = httputil.NewChunkedWriter(conn.buf)
+	if t.client.compress {
+		// Favor speed, as compression is in the path of every commit.
+		t.gzipper, _ = gzip.NewWriterLevel(t.chunker, gzip.BestSpeed)
+	}
 	t.conn = conn
 	t.request = req
 
@@ -152,7 +182,7 @@ func (t *replicaClientTransaction) takeConn() (replicaClientConn, error) {
 	if err != nil {
 		return replicaClientConn{}, err
 	}
-	raw, err := keepalive.Dialer.Dial("tcp", url.Host)
+	raw, err := t.dial(url)
 	if err != nil {
 		t.client.endpoint.InvalidateResolution()
 		return replicaClientConn{}, err
@@ -161,6 +191,26 @@ func (t *replicaClientTransaction) takeConn() (replicaClientConn, error) {
 		bufio.NewReadWriter(bufio.NewReader(raw), bufio.NewWriter(raw))}, nil
 }
 
+// dial |url|, using TLS if |url| is https. As |url| is resolved to an IP,
+// the server name is taken from the unresolved endpoint URL.
+func (t *replicaClientTransaction) dial(url *url.URL) (net.Conn, error) {
+	if url.Scheme != "https" {
+		return keepalive.Dialer.Dial("tcp", url.Host)
+	}
+	var cfg = new(tls.Config)
+	if t.client.tlsConfig != nil {
+		cfg = t.client.tlsConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		if base, err := t.client.endpoint.URL(); err != nil {
+			return nil, err
+		} else {
+			cfg.ServerName = base.Hostname()
+		}
+	}
+	return tls.DialWithDialer(keepalive.Dialer, "tcp", url.Host, cfg)
+}
+
 func (t *replicaClientTransaction) putConn(conn replicaClientConn) {
 	conn.raw.SetReadDeadline(time.Time{}) // Clear timeout.
 	select {
@@ -170,11 +220,18 @@ func (t *replicaClientTransaction) putConn(conn replicaClientConn) {
 }
 
 func (t *replicaClientTransaction) Write(p []byte) (n int, err error) {
+	if t.gzipper != nil {
+		return t.gzipper.Write(p)
+	}
 	return t.chunker.Write(p)
 }
 
 func (t *replicaClientTransaction) Commit(delta int64) error {
-	// Close the chunker and write the commit delta as a trailing header.
+	// Flush remaining compressed content, close the chunker, and write the
+	// commit delta as a trailing header.
+	if t.gzipper != nil {
+		t.gzipper.Close()
+	}
 	t.chunker.Close()
 	fmt.Fprintf(t.conn.buf, "%s: %x\r\n\r\n", CommitDeltaHeader, delta)
 
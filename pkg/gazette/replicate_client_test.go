@@ -51,7 +51,10 @@ func (s *ReplicateClientSuite) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	}
 	// Begin reading the body. This triggers a 100-continue response.
 	var body bytes.Buffer
-	if _, err := io.Copy(&body, r.Body); err != nil {
+	if rb, err := replicateBody(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if _, err := io.Copy(&body, rb); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -60,7 +63,9 @@ func (s *ReplicateClientSuite) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		c.Close()
 		return
 	}
-	if body.String() != "expected write body" {
+	if body.String() == "expected compressed body" && r.Header.Get("Content-Encoding") == "gzip" {
+		// Pass.
+	} else if body.String() != "expected write body" {
 		http.Error(w, "unexpected body", http.StatusInternalServerError)
 		return
 	}
@@ -95,6 +100,22 @@ func (s *ReplicateClientSuite) TestSuccess(c *gc.C) {
 	c.Check(result.Writer.Commit(2345), gc.IsNil)
 }
 
+func (s *ReplicateClientSuite) TestCompression(c *gc.C) {
+	var client = s.client
+	client.SetCompression(true)
+
+	var op = s.opFixture()
+	client.Replicate(op)
+
+	result := <-op.Result
+	c.Check(result.Error, gc.IsNil)
+	c.Assert(result.Writer, gc.Not(gc.IsNil))
+
+	result.Writer.Write([]byte("expected "))
+	result.Writer.Write([]byte("compressed body"))
+	c.Check(result.Writer.Commit(2345), gc.IsNil)
+}
+
 func (s *ReplicateClientSuite) TestIncorrectWriteOffsetHandling(c *gc.C) {
 	var op = s.opFixture()
 	op.WriteHead = 123
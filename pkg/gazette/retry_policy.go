@@ -0,0 +1,165 @@
+package gazette
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+// ErrCircuitOpen is returned for a request to a broker endpoint which has
+// failed too many consecutive requests, and is cooling off.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// RetryPolicy determines how a Client retries failed operations. Client Head,
+// Get, Put, Create, and Roll operations (and operations built upon them, such
+// as FragmentsInRange) are retried on Retryable errors, after a back-off
+// which grows exponentially with each attempt, and is jittered to avoid
+// synchronized retries of many clients. Errors which RetryAfterReroute are
+// retried without back-off. ErrNotYetAvailable is a result rather than a
+// failure, and is not retried.
+//
+// A Put which fails may nonetheless have committed (eg, if its response was
+// lost), and a retry would then append its content twice. Put is therefore
+// retried only if its journal.AppendArgs.Producer is set, allowing a broker
+// to recognize a retry of an append which already committed. Otherwise, only
+// errors which RetryAfterReroute are retried, as the append was rejected
+// without being committed.
+type RetryPolicy struct {
+	// Maximum attempts of an operation, including the first. Zero is unlimited.
+	MaxAttempts int
+	// Timeout of each attempt. Zero is no timeout. It doesn't apply to Get,
+	// as a successful attempt streams content beyond the attempt's lifetime.
+	AttemptTimeout time.Duration
+	// Back-off of the first retry, which doubles with each further retry up
+	// to MaxBackoff. A back-off is jittered to between half and all of its value.
+	MinBackoff, MaxBackoff time.Duration
+	// Number of consecutive failed requests (network errors or 5xx responses)
+	// of a broker endpoint, after which its circuit breaker opens and requests
+	// of the endpoint fail with ErrCircuitOpen for BreakerCoolOff. Zero
+	// disables the circuit breaker.
+	BreakerThreshold int
+	BreakerCoolOff   time.Duration
+}
+
+// DefaultRetryPolicy makes a single attempt of each operation, and disables
+// the circuit breaker. Its back-off is used by the WriteService, which
+// retries writes indefinitely.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 1,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+}
+
+// Backoff returns the jittered back-off to apply before retry |attempt|,
+// where the first retry is attempt zero.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	var d = p.MinBackoff
+	for i := 0; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// SetRetryPolicy sets the RetryPolicy of the Client. It must be called before
+// the Client is used.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// withRetries invokes |op| until it succeeds, fails with a non-retryable error,
+// |ctx| is done, or the RetryPolicy's attempts are exhausted, and returns the
+// error of the last attempt. If |timed|, each attempt is bounded by the
+// policy AttemptTimeout.
+func (c *Client) withRetries(ctx context.Context, timed bool, op func(context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var policy = c.retryPolicy
+
+	for attempt := 0; ; attempt++ {
+		var attemptCtx, cancel = ctx, context.CancelFunc(func() {})
+		if timed && policy.AttemptTimeout != 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.AttemptTimeout)
+		}
+		var err = op(attemptCtx)
+		cancel()
+
+		if err == nil || err == journal.ErrNotYetAvailable || ctx.Err() != nil {
+			return err
+		} else if policy.MaxAttempts != 0 && attempt+1 >= policy.MaxAttempts {
+			return err
+		}
+
+		switch journal.RetryabilityOf(err) {
+		case journal.NotRetryable:
+			return err
+		case journal.RetryAfterReroute:
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(policy.Backoff(attempt)):
+		}
+	}
+}
+
+// circuitBreaker tracks consecutive failed requests of broker endpoints.
+type circuitBreaker struct {
+	mu    sync.Mutex
+	hosts map[string]breakerState
+}
+
+type breakerState struct {
+	failures  int       // Consecutive failed requests.
+	openUntil time.Time // If in the future, requests fail with ErrCircuitOpen.
+}
+
+// allow returns ErrCircuitOpen if the breaker of |host| is open at |now|.
+func (b *circuitBreaker) allow(host string, now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Before(b.hosts[host].openUntil) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// observe records the outcome of a request of |host|. On failure, the breaker
+// opens if |policy.BreakerThreshold| consecutive requests have failed.
+// Once a cool-off elapses, further requests are allowed, and the breaker
+// re-opens upon the next failure or resets on a success.
+func (b *circuitBreaker) observe(host string, failed bool, now time.Time, policy RetryPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		delete(b.hosts, host)
+		return
+	} else if b.hosts == nil {
+		b.hosts = make(map[string]breakerState)
+	}
+
+	var state = b.hosts[host]
+	if state.failures++; state.failures >= policy.BreakerThreshold {
+		state.openUntil = now.Add(policy.BreakerCoolOff)
+
+		log.WithFields(log.Fields{"host": host, "failures": state.failures,
+			"coolOff": policy.BreakerCoolOff}).Warn("opened broker circuit breaker")
+	}
+	b.hosts[host] = state
+}
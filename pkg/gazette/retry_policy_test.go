@@ -0,0 +1,169 @@
+package gazette
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	gc "github.com/go-check/check"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type RetryPolicySuite struct{}
+
+func (s *RetryPolicySuite) TestBackoff(c *gc.C) {
+	var policy = RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for i := 0; i != 100; i++ {
+		for attempt, expect := range []time.Duration{
+			100 * time.Millisecond,
+			200 * time.Millisecond,
+			400 * time.Millisecond,
+			800 * time.Millisecond,
+			time.Second,
+			time.Second,
+		} {
+			var d = policy.Backoff(attempt)
+			c.Check(d >= expect/2 && d <= expect, gc.Equals, true,
+				gc.Commentf("attempt %d: %s", attempt, d))
+		}
+	}
+	c.Check(RetryPolicy{}.Backoff(3), gc.Equals, time.Duration(0))
+}
+
+func (s *RetryPolicySuite) TestPutRetries(c *gc.C) {
+	var mockClient = &mockHttpClient{}
+	var client, _ = NewClient("http://default")
+	client.httpClient = mockClient
+	client.locationCache.Add("/a/journal", newURL("http://broker/a/journal"))
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3})
+
+	var isPut = mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT" && request.ContentLength == 6
+	})
+	// Each attempt sends the complete content, which is consumed.
+	var readBody = func(args mock.Arguments) {
+		var b, _ = ioutil.ReadAll(args.Get(0).(*http.Request).Body)
+		c.Check(string(b), gc.Equals, "foobar")
+	}
+	var response = func(status int) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Body:       ioutil.NopCloser(nil),
+			Header:     http.Header{WriteHeadHeader: []string{"6"}},
+		}
+	}
+
+	// A network error and a failed replication are retried. The network error
+	// invalidates the cached broker location, which is re-queried.
+	mockClient.On("Do", isPut).Return(nil, io.ErrUnexpectedEOF).Run(readBody).Once()
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "HEAD"
+	})).Return(newReadResponseFixture(), nil).Once()
+	mockClient.On("Do", isPut).Return(response(http.StatusServiceUnavailable), nil).Run(readBody).Once()
+	mockClient.On("Do", isPut).Return(response(http.StatusNoContent), nil).Run(readBody).Once()
+
+	var args = journal.AppendArgs{Journal: "a/journal", Producer: "producer"}
+	var put = func() journal.AppendResult {
+		args.Content = strings.NewReader("foobar")
+		return client.Put(args)
+	}
+
+	var result = put()
+	c.Check(result.Error, gc.IsNil)
+	c.Check(result.WriteHead, gc.Equals, int64(6))
+
+	// Attempts are bounded by MaxAttempts.
+	mockClient.On("Do", isPut).Return(response(http.StatusServiceUnavailable), nil).Times(3)
+
+	result = put()
+	c.Check(result.Error, gc.Equals, journal.ErrReplicationFailed)
+
+	// Non-retryable errors are not retried.
+	mockClient.On("Do", isPut).Return(response(http.StatusNotFound), nil).Once()
+
+	result = put()
+	c.Check(result.Error, gc.Equals, journal.ErrNotFound)
+
+	// Without a Producer, an append which may have committed is not retried.
+	args.Producer = ""
+	mockClient.On("Do", isPut).Return(response(http.StatusServiceUnavailable), nil).Once()
+
+	result = put()
+	c.Check(result.Error, gc.Equals, journal.ErrReplicationFailed)
+
+	// But one rejected by a broker which isn't the journal's is re-routed.
+	mockClient.On("Do", isPut).Return(response(http.StatusGone), nil).Once()
+	mockClient.On("Do", isPut).Return(response(http.StatusNoContent), nil).Once()
+
+	result = put()
+	c.Check(result.Error, gc.IsNil)
+
+	mockClient.AssertExpectations(c)
+}
+
+func (s *RetryPolicySuite) TestNotYetAvailableIsNotRetried(c *gc.C) {
+	var mockClient = &mockHttpClient{}
+	var client, _ = NewClient("http://default")
+	client.httpClient = mockClient
+	client.SetRetryPolicy(RetryPolicy{})
+
+	var r = newReadResponseFixture()
+	r.StatusCode = http.StatusRequestedRangeNotSatisfiable
+	mockClient.On("Do", mock.Anything).Return(r, nil).Once()
+
+	var result, _ = client.Head(journal.ReadArgs{Journal: "a/journal"})
+	c.Check(result.Error, gc.Equals, journal.ErrNotYetAvailable)
+
+	mockClient.AssertExpectations(c)
+}
+
+func (s *RetryPolicySuite) TestCircuitBreaker(c *gc.C) {
+	var mockClient = &mockHttpClient{}
+	var client, _ = NewClient("http://default")
+	client.httpClient = mockClient
+	client.SetRetryPolicy(RetryPolicy{
+		MaxAttempts:      1,
+		BreakerThreshold: 2,
+		BreakerCoolOff:   time.Minute,
+	})
+	var now = time.Unix(1234, 0)
+	client.timeNow = func() time.Time { return now }
+
+	var head = func() error {
+		var result, _ = client.Head(journal.ReadArgs{Journal: "a/journal"})
+		return result.Error
+	}
+
+	// Two consecutive failures open the breaker.
+	mockClient.On("Do", mock.Anything).Return(nil, io.ErrUnexpectedEOF).Twice()
+	c.Check(head(), gc.Equals, io.ErrUnexpectedEOF)
+	c.Check(head(), gc.Equals, io.ErrUnexpectedEOF)
+
+	// Further requests fail without being attempted.
+	c.Check(head(), gc.Equals, ErrCircuitOpen)
+
+	// After the cool-off, requests are again attempted. A failure re-opens it.
+	now = now.Add(time.Minute)
+	mockClient.On("Do", mock.Anything).Return(nil, io.ErrUnexpectedEOF).Once()
+	c.Check(head(), gc.Equals, io.ErrUnexpectedEOF)
+	c.Check(head(), gc.Equals, ErrCircuitOpen)
+
+	// A success resets the breaker.
+	now = now.Add(time.Minute)
+	mockClient.On("Do", mock.Anything).Return(newReadResponseFixture(), nil).Once()
+	c.Check(head(), gc.IsNil)
+
+	mockClient.On("Do", mock.Anything).Return(nil, io.ErrUnexpectedEOF).Once()
+	c.Check(head(), gc.Equals, io.ErrUnexpectedEOF)
+	mockClient.On("Do", mock.Anything).Return(newReadResponseFixture(), nil).Once()
+	c.Check(head(), gc.IsNil)
+
+	mockClient.AssertExpectations(c)
+}
+
+var _ = gc.Suite(&RetryPolicySuite{})
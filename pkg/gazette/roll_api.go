@@ -0,0 +1,45 @@
+package gazette
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+// RollAPI serves requests that the broker of a journal roll the spools of its
+// replicas, causing content written to date to be promptly persisted to the
+// fragment store (eg, prior to a planned broker decommission). Requests use
+// the ROLL method, and are answered as an empty append would be.
+type RollAPI struct {
+	handler RollOpHandler
+}
+
+func NewRollAPI(handler RollOpHandler) *RollAPI {
+	return &RollAPI{handler: handler}
+}
+
+func (h *RollAPI) Register(router *mux.Router) {
+	router.NewRoute().Methods("ROLL").HandlerFunc(h.Roll)
+}
+
+func (h *RollAPI) Roll(w http.ResponseWriter, r *http.Request) {
+	r = maybeTrace(r, "RollAPI.Roll")
+	defer finishTrace(r)
+
+	var op = journal.AppendOp{
+		AppendArgs: journal.AppendArgs{
+			Journal: journal.Name(r.URL.Path[1:]),
+			Content: new(bytes.Buffer),
+			Context: r.Context(),
+		},
+		Result: make(chan journal.AppendResult, 1),
+	}
+	h.handler.Roll(op)
+	var result = <-op.Result
+
+	r.Body.Close()
+	writeAppendResponse(w, r, result)
+}
@@ -1,6 +1,7 @@
 package gazette
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"expvar"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/trace"
@@ -20,29 +23,95 @@ import (
 // Builds a JournalReplica instance with the given journal.Name.
 type ReplicaFactory func(journal.Name) JournalReplica
 
+// Default duration after which a locally brokered journal which hasn't served
+// an append is considered idle. See Router.SetIdleJournalThreshold.
+const defaultIdleJournalThreshold = time.Hour
+
 // Routes and dispatches Read, Append, and Replicate operations to a
 // collection of responsible JournalReplicas.
 type Router struct {
 	replicaFactory ReplicaFactory
 
 	routes map[journal.Name]*journalRoute
+	// Duration for which a replica removed from its journal's route is retained
+	// before it's shut down. See SetShutdownGracePeriod.
+	shutdownGrace time.Duration
+	// Duration without appends after which a brokered journal is idle.
+	idleThreshold time.Duration
+	// TLS configuration used in replicating to https:// peers, and whether
+	// replicated content is compressed.
+	peerTLSConfig   *tls.Config
+	peerCompression bool
 
 	// This mutex guards any read or write operation on |routes| *and* its
 	// underlying |*journalRoute| values.
 	routesMu sync.Mutex
+
+	// Test support: allow time.Now() to be swapped out.
+	timeNow func() time.Time
 }
 
 func NewRouter(factory ReplicaFactory) *Router {
 	var r = &Router{
 		replicaFactory: factory,
 		routes:         make(map[journal.Name]*journalRoute),
+		idleThreshold:  defaultIdleJournalThreshold,
+		timeNow:        time.Now,
 	}
 
 	gazetteMap.Set("brokers", journalStringer(r.BrokeredJournals))
 	gazetteMap.Set("replicas", journalStringer(r.ReplicatedJournals))
+	gazetteMap.Set("idle", journalStringer(func() []journal.Name {
+		return r.IdleJournals(r.idleJournalThreshold())
+	}))
 	return r
 }
 
+// SetShutdownGracePeriod sets a period for which a replica removed from its
+// journal's route is retained, rather than being immediately shut down. If the
+// local replica is restored to the route within the period (eg, because its
+// removal was due to brief Etcd churn), the retained replica resumes service
+// without having to be re-created and re-load its fragment index. Retained
+// replicas don't serve operations. Zero (the default) shuts down immediately.
+func (r *Router) SetShutdownGracePeriod(period time.Duration) {
+	r.routesMu.Lock()
+	r.shutdownGrace = period
+	r.routesMu.Unlock()
+}
+
+// SetIdleJournalThreshold sets the duration after which a locally brokered
+// journal which hasn't served an append is considered idle (eg, because it's
+// been abandoned by its writers). It defaults to one hour.
+func (r *Router) SetIdleJournalThreshold(threshold time.Duration) {
+	r.routesMu.Lock()
+	r.idleThreshold = threshold
+	r.routesMu.Unlock()
+}
+
+// idleJournalThreshold returns the threshold set by SetIdleJournalThreshold.
+func (r *Router) idleJournalThreshold() time.Duration {
+	r.routesMu.Lock()
+	defer r.routesMu.Unlock()
+	return r.idleThreshold
+}
+
+// SetPeerTLSConfig sets a tls.Config used when dialing peer brokers having
+// https:// routes, in replicating journals brokered by this Router.
+func (r *Router) SetPeerTLSConfig(cfg *tls.Config) {
+	r.routesMu.Lock()
+	r.peerTLSConfig = cfg
+	r.routesMu.Unlock()
+}
+
+// SetPeerCompression sets whether content replicated to peer brokers, of
+// journals brokered by this Router, is compressed. See
+// ReplicateClient.SetCompression.
+func (r *Router) SetPeerCompression(compress bool) {
+	r.routesMu.Lock()
+	r.peerCompression = compress
+	r.routesMu.Unlock()
+}
+
 func (r *Router) Read(op journal.ReadOp) {
 	if tr, ok := trace.FromContext(op.Context); ok {
 		tr.LazyPrintf("Read request: %s", op.ReadArgs)
@@ -139,6 +208,11 @@ func (r *Router) Append(op journal.AppendOp) {
 			}
 		}
 
+		if result.Error == nil {
+			// Only appends which advance the write head (and not, eg, empty
+			// broker pulses) mark the journal as being written.
+			route.appends.observe(result.WriteHead, r.timeNow())
+		}
 		if result.Error == nil && token != lastAppendToken {
 			// Note that the journal route may have changed on us during the
 			// append operation, and we therefore apply our retained |token|
@@ -157,6 +231,16 @@ func (r *Router) Append(op journal.AppendOp) {
 	route.replica.Append(op)
 }
 
+// Roll rolls the spools of a locally brokered journal with the transaction of
+// |op| (typically an empty append), such that current spools are persisted.
+func (r *Router) Roll(op journal.AppendOp) {
+	if route, ok := r.readRoute(op.Journal); ok && route.broker && route.brokerReady {
+		route.replica.Roll()
+	}
+	// Append validates the route, and fails |op| if we're not a ready broker.
+	r.Append(op)
+}
+
 func (r *Router) Replicate(op journal.ReplicateOp) {
 	if tr, ok := trace.FromContext(op.Context); ok {
 		tr.LazyPrintf("Replicate request: %s", op.ReplicateArgs)
@@ -225,6 +309,24 @@ func (r *Router) BrokeredJournals() []journal.Name {
 	return result
 }
 
+// IdleJournals returns the Journals brokered by this Router which haven't
+// served an append of content within |threshold|. Journals are not idle for
+// |threshold| after becoming brokered.
+func (r *Router) IdleJournals(threshold time.Duration) []journal.Name {
+	r.routesMu.Lock()
+	defer r.routesMu.Unlock()
+
+	var result []journal.Name
+	var horizon = r.timeNow().Add(-threshold)
+
+	for name, route := range r.routes {
+		if route.broker && route.appends.last().Before(horizon) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
 // Returns the set of Journals which are replicated by this Router.
 func (r *Router) ReplicatedJournals() []journal.Name {
 	r.routesMu.Lock()
@@ -251,6 +353,12 @@ type journalRoute struct {
 	// Current topology |token| of journal, and the token of the most-recent
 	// Append operation which we successfully brokered.
 	token, lastAppendToken journal.RouteToken
+	// Tracks brokered Appends which advance the journal write head. Shared by
+	// copies of the route, and updated without holding |routesMu|.
+	appends *appendActivity
+	// Replica removed from the route, which is pending shut down by |retiredTimer|.
+	retired      JournalReplica
+	retiredTimer *time.Timer
 }
 
 // Updates |routes| with new information about the journal. Creates a route if
@@ -272,13 +380,20 @@ func (r *Router) transition(name journal.Name, rt journal.RouteToken,
 	var route, ok = r.routes[name]
 	if !ok {
 		// Journal |name| is being tracked for the first time.
-		route = new(journalRoute)
+		route = &journalRoute{appends: new(appendActivity)}
 		r.routes[name] = route
 	}
 
-	if route.replica == nil && replica {
+	if route.replica == nil && replica && route.retired != nil {
+		// The replica was removed, but is restored within its grace period.
+		route.retiredTimer.Stop()
+		route.replica, route.retired, route.retiredTimer = route.retired, nil, nil
+	} else if route.replica == nil && replica {
 		// The replica doesn't exist, but should.
 		route.replica = r.replicaFactory(name)
+	} else if route.replica != nil && !replica && r.shutdownGrace != 0 {
+		// The replica exists, but should not. Retain it for the grace period.
+		r.retireReplica(route)
 	} else if route.replica != nil && !replica {
 		// The replica exists, but should not.
 		route.replica.Shutdown()
@@ -299,9 +414,12 @@ func (r *Router) transition(name journal.Name, rt journal.RouteToken,
 	if index == 0 {
 		broker = true
 
-		if peers = routePeers(rt); len(peers) >= requiredReplicas {
+		if peers = r.routePeers(rt); len(peers) >= requiredReplicas {
 			brokerReady = true
 		}
+		if !route.broker {
+			route.appends.reset(r.timeNow())
+		}
 	}
 	route.broker = broker
 	route.brokerReady = brokerReady
@@ -313,6 +431,64 @@ func (r *Router) transition(name journal.Name, rt journal.RouteToken,
 	}
 }
 
+// retireReplica moves the |route| replica to |route.retired|, and arranges
+// for it to be shut down after the grace period. |routesMu| must be held.
+func (r *Router) retireReplica(route *journalRoute) {
+	var timer *time.Timer
+	timer = time.AfterFunc(r.shutdownGrace, func() {
+		r.routesMu.Lock()
+		defer r.routesMu.Unlock()
+
+		// Shut down only if the replica wasn't since restored (and perhaps
+		// retired again, under a different timer).
+		if route.retiredTimer == timer {
+			route.retired.Shutdown()
+			route.retired, route.retiredTimer = nil, nil
+		}
+	})
+	route.replica, route.retired, route.retiredTimer = nil, route.replica, timer
+}
+
+// appendActivity tracks the write head of a brokered journal, and the time
+// of the most-recent Append which advanced it. Its fields are accessed
+// atomically, so that Appends needn't contend on the Router-wide |routesMu|.
+type appendActivity struct {
+	// Journal write head as of the most-recent brokered Append, or -1 if none
+	// has been brokered since we became broker.
+	writeHead int64
+	// UnixNano time of the most-recent brokered Append which advanced the
+	// journal write head, or at which we became broker, if more recent.
+	lastAppend int64
+}
+
+// reset records that we became broker at |now|.
+func (a *appendActivity) reset(now time.Time) {
+	atomic.StoreInt64(&a.writeHead, -1)
+	atomic.StoreInt64(&a.lastAppend, now.UnixNano())
+}
+
+// observe records a brokered Append which resulted in |writeHead| at |now|.
+// The Append is recorded only if it advanced a previously observed write head.
+func (a *appendActivity) observe(writeHead int64, now time.Time) {
+	for {
+		var prior = atomic.LoadInt64(&a.writeHead)
+		if writeHead <= prior {
+			return
+		} else if !atomic.CompareAndSwapInt64(&a.writeHead, prior, writeHead) {
+			continue // Raced with another Append. Try again.
+		} else if prior != -1 {
+			atomic.StoreInt64(&a.lastAppend, now.UnixNano())
+		}
+		return
+	}
+}
+
+// last returns the time of the most-recent brokered Append which advanced the
+// journal write head, or at which we became broker, if more recent.
+func (a *appendActivity) last() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&a.lastAppend))
+}
+
 func (r *Router) readRoute(name journal.Name) (journalRoute, bool) {
 	r.routesMu.Lock()
 	defer r.routesMu.Unlock()
@@ -324,7 +500,8 @@ func (r *Router) readRoute(name journal.Name) (journalRoute, bool) {
 }
 
 // Builds a Replicator for each non-master replica of |route|.
-func routePeers(rt journal.RouteToken) []journal.Replicator {
+// Must be called with |routesMu| held.
+func (r *Router) routePeers(rt journal.RouteToken) []journal.Replicator {
 	var peers []journal.Replicator
 
 	for i, url := range strings.Split(string(rt), "|") {
@@ -332,8 +509,10 @@ func routePeers(rt journal.RouteToken) []journal.Replicator {
 			// Skip local token.
 			continue
 		}
-		var ep = &CachedURL{Base: url}
-		peers = append(peers, NewReplicateClient(ep))
+		var client = NewReplicateClient(&CachedURL{Base: url})
+		client.SetTLSConfig(r.peerTLSConfig)
+		client.SetCompression(r.peerCompression)
+		peers = append(peers, client)
 	}
 	return peers
 }
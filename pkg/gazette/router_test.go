@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	gc "github.com/go-check/check"
 
@@ -136,6 +137,96 @@ func (s *RouterSuite) TestAppendConditions(c *gc.C) {
 	c.Check(router.HasServedAppend("foo/bar"), gc.Equals, false)
 }
 
+func (s *RouterSuite) TestIdleJournals(c *gc.C) {
+	var recorder routerRecorder
+	var writeHead int64 = 1234
+	var router = NewRouter(func(name journal.Name) JournalReplica {
+		return writeHeadReplica{recorder.NewReplica(name).(replicaRecorder), &writeHead}
+	})
+	var now = time.Unix(1500000000, 0)
+	router.timeNow = func() time.Time { return now }
+
+	var resultCh = make(chan journal.AppendResult, 1)
+	var appendOp = func() {
+		router.Append(journal.AppendOp{
+			AppendArgs: journal.AppendArgs{Journal: "foo/bar", Context: context.Background()},
+			Result:     resultCh,
+		})
+		c.Assert((<-resultCh).Error, gc.IsNil)
+	}
+
+	// Replicated journals are never idle.
+	router.transition("foo/bar", "http://remote|http://local", 1, 1)
+	router.transition("baz/bing", "http://remote|http://local", 1, 1)
+	now = now.Add(2 * time.Hour)
+	c.Check(router.IdleJournals(time.Hour), gc.HasLen, 0)
+
+	// Journals become idle an idle threshold after becoming brokered.
+	router.transition("foo/bar", "http://local|http://remote", 0, 1)
+	router.transition("baz/bing", "http://local|http://remote", 0, 1)
+	c.Check(router.IdleJournals(time.Hour), gc.HasLen, 0)
+	now = now.Add(2 * time.Hour)
+	c.Check(router.IdleJournals(time.Hour), gc.HasLen, 2)
+
+	// Appends which don't advance the write head (eg, broker pulses) don't
+	// reset the idle journal.
+	appendOp()
+	appendOp()
+	c.Check(router.IdleJournals(time.Hour), gc.HasLen, 2)
+
+	// Appends which do, do.
+	writeHead += 100
+	appendOp()
+	c.Check(router.IdleJournals(time.Hour), gc.DeepEquals, []journal.Name{"baz/bing"})
+
+	now = now.Add(30 * time.Minute)
+	c.Check(router.IdleJournals(time.Hour), gc.DeepEquals, []journal.Name{"baz/bing"})
+	now = now.Add(time.Hour)
+	c.Check(router.IdleJournals(time.Hour), gc.HasLen, 2)
+
+	// The configured threshold defaults to an hour.
+	c.Check(router.idleJournalThreshold(), gc.Equals, time.Hour)
+	router.SetIdleJournalThreshold(3 * time.Hour)
+	c.Check(router.idleJournalThreshold(), gc.Equals, 3*time.Hour)
+}
+
+func (s *RouterSuite) TestRollConditions(c *gc.C) {
+	var recorder routerRecorder
+	var router = NewRouter(recorder.NewReplica)
+
+	var resultCh = make(chan journal.AppendResult, 1)
+	var op = journal.AppendOp{
+		AppendArgs: journal.AppendArgs{
+			Journal: "foo/bar",
+			Context: context.Background(),
+		},
+		Result: resultCh,
+	}
+
+	// Journal is a local replica. Roll fails as an Append would.
+	router.transition("foo/bar", "http://server|http://local", 1, 1)
+	recorder.verify(c, "created replica foo/bar",
+		"foo/bar => replica http://server|http://local")
+
+	router.Roll(op)
+	c.Check(<-resultCh, gc.DeepEquals, journal.AppendResult{
+		Error:      journal.ErrNotBroker,
+		RouteToken: "http://server|http://local",
+	})
+	c.Check(recorder, gc.HasLen, 0)
+
+	// Journal is a local broker. The replica is rolled, and |op| appended.
+	router.transition("foo/bar", "http://local|http://remote", 0, 1)
+	recorder.verify(c, "foo/bar => broker http://local|http://remote ([remote])")
+
+	router.Roll(op)
+	c.Check(<-resultCh, gc.DeepEquals, journal.AppendResult{
+		WriteHead:  1234,
+		RouteToken: "http://local|http://remote",
+	})
+	recorder.verify(c, "foo/bar => roll")
+}
+
 func (s *RouterSuite) TestReplicateConditions(c *gc.C) {
 	var recorder routerRecorder
 	var router = NewRouter(recorder.NewReplica)
@@ -182,6 +273,43 @@ func (s *RouterSuite) TestReplicateConditions(c *gc.C) {
 	})
 }
 
+func (s *RouterSuite) TestShutdownGracePeriod(c *gc.C) {
+	var recorder routerRecorder
+	var router = NewRouter(recorder.NewReplica)
+	router.SetShutdownGracePeriod(time.Hour)
+
+	router.transition("foo/bar", "http://server|http://local", 1, 1)
+	recorder.verify(c, "created replica foo/bar",
+		"foo/bar => replica http://server|http://local")
+
+	// Journal is no longer local. The replica is retained, but not served.
+	router.transition("foo/bar", "http://server|http://other", -1, 1)
+	c.Check(recorder, gc.HasLen, 0)
+
+	var route, _ = router.readRoute("foo/bar")
+	c.Check(route.replica, gc.IsNil)
+	c.Check(route.retired, gc.NotNil)
+
+	// Journal is again local within the grace period. The replica is restored.
+	router.transition("foo/bar", "http://local|http://server", 0, 1)
+	recorder.verify(c, "foo/bar => broker http://local|http://server ([server])")
+
+	// Journal is no longer local, and the grace period elapses.
+	router.SetShutdownGracePeriod(time.Millisecond)
+	router.transition("foo/bar", "http://server|http://other", -1, 1)
+
+	for route.retired != nil {
+		time.Sleep(time.Millisecond)
+		route, _ = router.readRoute("foo/bar")
+	}
+	recorder.verify(c, "foo/bar => shutdown")
+
+	// Journal is again local, and a new replica is created.
+	router.transition("foo/bar", "http://server|http://local", 1, 1)
+	recorder.verify(c, "created replica foo/bar",
+		"foo/bar => replica http://server|http://local")
+}
+
 func (s *RouterSuite) TestBrokerRedirect(c *gc.C) {
 	req, _ := http.NewRequest("GET", "/foo/bar?baz", nil)
 
@@ -235,6 +363,10 @@ func (r replicaRecorder) StartReplicating(token journal.RouteToken) {
 		fmt.Sprintf("%s => replica %s", r.Name, token))
 }
 
+func (r replicaRecorder) Roll() {
+	*r.recorder = append(*r.recorder, fmt.Sprintf("%s => roll", r.Name))
+}
+
 func (r replicaRecorder) Shutdown() {
 	*r.recorder = append(*r.recorder, fmt.Sprintf("%s => shutdown", r.Name))
 }
@@ -251,6 +383,16 @@ func (r replicaRecorder) Replicate(op journal.ReplicateOp) {
 	op.Result <- journal.ReplicateResult{ErrorWriteHead: 3456}
 }
 
+// writeHeadReplica is a replicaRecorder which passes back the WriteHead |head|.
+type writeHeadReplica struct {
+	replicaRecorder
+	head *int64
+}
+
+func (r writeHeadReplica) Append(op journal.AppendOp) {
+	op.Result <- journal.AppendResult{WriteHead: *r.head}
+}
+
 func flatPaths(peers []journal.Replicator) string {
 	var tmp []string
 	for _, peer := range peers {
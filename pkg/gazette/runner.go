@@ -2,7 +2,10 @@ package gazette
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"net/url"
+	"path"
 	"time"
 
 	etcd "github.com/coreos/etcd/client"
@@ -13,13 +16,54 @@ import (
 	"github.com/LiveRamp/gazette/pkg/metrics"
 )
 
+// ServiceRoot is the default Etcd root of a Gazette cluster. Independent
+// clusters sharing an Etcd cluster must each use a distinct root.
 const ServiceRoot = "/gazette/cluster"
 
+// Minimum intervals between updates of quarantined items, and of idle journals.
+const (
+	quarantineUpdateInterval = 10 * time.Second
+	idleUpdateInterval       = time.Minute
+)
+
+// ValidateServiceRoot returns an error if |root| isn't usable as the Etcd root
+// of a Gazette cluster. A root must be a clean, absolute path other than "/".
+// Clusters sharing an Etcd must also use roots which don't nest within one
+// another, as a cluster's keys are anywhere beneath its root.
+func ValidateServiceRoot(root string) error {
+	if !path.IsAbs(root) {
+		return fmt.Errorf("service root %q must be an absolute path", root)
+	} else if root == "/" {
+		return fmt.Errorf("service root may not be '/'")
+	} else if path.Clean(root) != root {
+		return fmt.Errorf("service root %q is not a clean path (expected %q)", root, path.Clean(root))
+	}
+	return nil
+}
+
 type Runner struct {
 	client        etcd.Client
 	localRouteKey string
 	replicaCount  int
 	router        *Router
+	inspectCh     chan func(*etcd.Node)
+	serviceRoot   string
+
+	// Time of the last quarantine update, and items then quarantined.
+	quarantineUpdate time.Time
+	quarantined      map[string]bool
+	// Time of the last idle journal update, and journals then idle.
+	idleUpdate time.Time
+	idle       map[journal.Name]bool
+}
+
+// QuarantinedItem is an allocator item which cannot be decoded (eg, due to an
+// invalid out-of-band write to Etcd), and is therefore not brokered.
+type QuarantinedItem struct {
+	Item  string
+	Error string
+	// Etcd ModifiedIndex of the item.
+	Revision uint64
 }
 
 func NewRunner(client etcd.Client, localRouteKey string, replicaCount int, router *Router) *Runner {
@@ -28,11 +72,20 @@ func NewRunner(client etcd.Client, localRouteKey string, replicaCount int, route
 		localRouteKey: localRouteKey,
 		replicaCount:  replicaCount,
 		router:        router,
+		inspectCh:     make(chan func(*etcd.Node)),
+		serviceRoot:   ServiceRoot,
 	}
 
 	return &runner
 }
 
+// SetServiceRoot sets the Etcd root under which the Runner allocates
+// journals. It defaults to ServiceRoot, and must be validated by
+// ValidateServiceRoot.
+func (r *Runner) SetServiceRoot(root string) {
+	r.serviceRoot = root
+}
+
 func (r *Runner) Run() error {
 	return consensus.CreateAndAllocateWithSignalHandling(r)
 }
@@ -41,10 +94,35 @@ func (r *Runner) Run() error {
 func (r *Runner) FixedItems() []string         { return nil }
 func (r *Runner) InstanceKey() string          { return r.localRouteKey }
 func (r *Runner) KeysAPI() etcd.KeysAPI        { return etcd.NewKeysAPI(r.client) }
-func (r *Runner) PathRoot() string             { return ServiceRoot }
+func (r *Runner) PathRoot() string             { return r.serviceRoot }
 func (r *Runner) Replicas() int                { return r.replicaCount }
 func (r *Runner) ItemState(item string) string { return "ready" }
 
+func (r *Runner) InspectChan() chan func(*etcd.Node) { return r.inspectCh }
+
+// ObserveAction logs allocation actions as structured events. Routine lock
+// refreshes are logged only at debug level.
+func (r *Runner) ObserveAction(action consensus.Action) {
+	var entry = log.WithFields(log.Fields{
+		"kind":          action.Kind,
+		"key":           action.Key,
+		"reason":        action.Reason,
+		"index":         action.Index,
+		"desiredMaster": action.DesiredMaster,
+		"desiredTotal":  action.DesiredTotal,
+		"master":        action.Master,
+		"replica":       action.Replica,
+		"members":       action.Members,
+		"items":         action.Items,
+	})
+	switch action.Kind {
+	case consensus.ActionRefreshMember, consensus.ActionRefreshMaster, consensus.ActionRefreshReplica:
+		entry.Debug("allocator action")
+	default:
+		entry.Info("allocator action")
+	}
+}
+
 func (r *Runner) ItemIsReadyForPromotion(item, state string) bool {
 	name, err := itemToJournal(item)
 	if err != nil {
@@ -65,22 +143,105 @@ func (r *Runner) ItemRoute(item string, route consensus.Route, index int, tree *
 		metrics.ItemRouteDurationSeconds.Observe(s)
 	}(time.Now())
 
+	r.updateQuarantine(tree)
+	r.updateIdle()
+
 	var name, err = itemToJournal(item)
 	if err != nil {
-		log.WithFields(log.Fields{"err": err, "item": item}).
-			Error("failed to decode journal")
-		return
+		return // Quarantined.
 	}
 	token, err := routeToToken(route)
 	if err != nil {
-		log.WithFields(log.Fields{"route": route, "err": err}).
-			Error("failed to extract route token")
-		return
+		return // Quarantined.
 	}
 
 	r.router.transition(name, token, index, r.replicaCount)
 }
 
+// Quarantined returns allocator items which cannot be decoded, and are
+// therefore not brokered. It fails if the allocator doesn't service the
+// request before |ctx| is done.
+func (r *Runner) Quarantined(ctx context.Context) ([]QuarantinedItem, error) {
+	var out = make(chan []QuarantinedItem, 1)
+	var cb = func(tree *etcd.Node) { out <- quarantinedItems(tree) }
+
+	select {
+	case r.inspectCh <- cb:
+		return <-out, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// updateQuarantine updates the quarantine from |tree|, if
+// quarantineUpdateInterval has elapsed since the last update. The allocator
+// patches |tree| in place, so it can't cheaply be determined whether |tree|
+// has changed, and the interval bounds the cost of re-walking its items.
+// Newly quarantined items are logged.
+func (r *Runner) updateQuarantine(tree *etcd.Node) {
+	if time.Since(r.quarantineUpdate) < quarantineUpdateInterval {
+		return
+	}
+	r.quarantineUpdate = time.Now()
+
+	var items = quarantinedItems(tree)
+	var next = make(map[string]bool, len(items))
+
+	for _, q := range items {
+		if !r.quarantined[q.Item] {
+			log.WithFields(log.Fields{"item": q.Item, "err": q.Error, "revision": q.Revision}).
+				Error("quarantined undecodable item")
+		}
+		next[q.Item] = true
+	}
+	r.quarantined = next
+	metrics.QuarantinedItems.Set(float64(len(items)))
+}
+
+// updateIdle updates journals idle for more than the Router's idle journal
+// threshold, if idleUpdateInterval has elapsed since the last update. Newly
+// idle journals are logged.
+func (r *Runner) updateIdle() {
+	if time.Since(r.idleUpdate) < idleUpdateInterval {
+		return
+	}
+	r.idleUpdate = time.Now()
+
+	var threshold = r.router.idleJournalThreshold()
+	var names = r.router.IdleJournals(threshold)
+	var next = make(map[journal.Name]bool, len(names))
+
+	for _, name := range names {
+		if !r.idle[name] {
+			log.WithFields(log.Fields{"journal": name, "threshold": threshold}).
+				Info("brokered journal is idle")
+		}
+		next[name] = true
+	}
+	r.idle = next
+	metrics.IdleJournals.Set(float64(len(names)))
+}
+
+// quarantinedItems returns the items of |tree| which cannot be decoded.
+func quarantinedItems(tree *etcd.Node) []QuarantinedItem {
+	var out []QuarantinedItem
+
+	consensus.WalkItems(tree, nil, func(item string, route consensus.Route) {
+		var _, err = itemToJournal(item)
+		if err == nil {
+			_, err = routeToToken(route)
+		}
+		if err != nil {
+			out = append(out, QuarantinedItem{
+				Item:     item,
+				Error:    err.Error(),
+				Revision: route.Item.ModifiedIndex,
+			})
+		}
+	})
+	return out
+}
+
 func itemToJournal(s string) (journal.Name, error) {
 	s, err := url.QueryUnescape(s)
 	return journal.Name(s), err
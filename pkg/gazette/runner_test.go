@@ -0,0 +1,94 @@
+package gazette
+
+import (
+	"context"
+	"time"
+
+	etcd "github.com/coreos/etcd/client"
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/consensus"
+)
+
+type RunnerSuite struct{}
+
+func (s *RunnerSuite) TestServiceRootValidation(c *gc.C) {
+	c.Check(ValidateServiceRoot(ServiceRoot), gc.IsNil)
+	c.Check(ValidateServiceRoot("/tenant-a/gazette"), gc.IsNil)
+
+	c.Check(ValidateServiceRoot("gazette"), gc.ErrorMatches,
+		`service root "gazette" must be an absolute path`)
+	c.Check(ValidateServiceRoot("/"), gc.ErrorMatches, `service root may not be '/'`)
+	c.Check(ValidateServiceRoot("/gazette/"), gc.ErrorMatches,
+		`service root "/gazette/" is not a clean path \(expected "/gazette"\)`)
+	c.Check(ValidateServiceRoot("/a/../gazette"), gc.ErrorMatches,
+		`service root "/a/../gazette" is not a clean path \(expected "/gazette"\)`)
+}
+
+func (s *RunnerSuite) TestServiceRoot(c *gc.C) {
+	var runner = NewRunner(nil, "http://foo", 1, nil)
+	c.Check(runner.PathRoot(), gc.Equals, ServiceRoot)
+
+	runner.SetServiceRoot("/tenant-a/gazette")
+	c.Check(runner.PathRoot(), gc.Equals, "/tenant-a/gazette")
+}
+
+func (s *RunnerSuite) TestQuarantine(c *gc.C) {
+	var tree = &etcd.Node{Key: ServiceRoot, Dir: true, Nodes: etcd.Nodes{
+		{Key: ServiceRoot + "/items", Dir: true, Nodes: etcd.Nodes{
+			{Key: ServiceRoot + "/items/a%2Fgood", Dir: true, ModifiedIndex: 2, Nodes: etcd.Nodes{
+				{Key: ServiceRoot + "/items/a%2Fgood/http%3A%2F%2Ffoo", CreatedIndex: 3},
+			}},
+			{Key: ServiceRoot + "/items/bad%ZZitem", Dir: true, ModifiedIndex: 4},
+			{Key: ServiceRoot + "/items/bad-route", Dir: true, ModifiedIndex: 5, Nodes: etcd.Nodes{
+				{Key: ServiceRoot + "/items/bad-route/http%ZZ", CreatedIndex: 6},
+			}},
+		}},
+	}}
+	var expect = []QuarantinedItem{
+		{Item: "bad%ZZitem", Error: `invalid URL escape "%ZZ"`, Revision: 4},
+		{Item: "bad-route", Error: `invalid URL escape "%ZZ"`, Revision: 5},
+	}
+	c.Check(quarantinedItems(tree), gc.DeepEquals, expect)
+
+	var runner = NewRunner(nil, "http://foo", 1, nil)
+	runner.updateQuarantine(tree)
+	c.Check(runner.quarantined, gc.DeepEquals, map[string]bool{"bad%ZZitem": true, "bad-route": true})
+
+	// The allocator patches the tree in place. Once the update interval has
+	// elapsed, the quarantine reflects the patched tree.
+	var patched, err = consensus.PatchTree(tree, &etcd.Response{
+		Action: "delete",
+		Node:   &etcd.Node{Key: ServiceRoot + "/items/bad%ZZitem", Dir: true, ModifiedIndex: 7},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(patched, gc.Equals, tree)
+
+	runner.updateQuarantine(tree)
+	c.Check(runner.quarantined, gc.HasLen, 2) // Not yet updated.
+
+	runner.quarantineUpdate = time.Time{}
+	runner.updateQuarantine(tree)
+	c.Check(runner.quarantined, gc.DeepEquals, map[string]bool{"bad-route": true})
+	expect = expect[1:]
+
+	// Quarantined items may be enumerated, as serviced by the allocator.
+	go func() {
+		for cb := range runner.InspectChan() {
+			cb(tree)
+		}
+	}()
+	items, err := runner.Quarantined(context.Background())
+	c.Check(err, gc.IsNil)
+	c.Check(items, gc.DeepEquals, expect)
+	close(runner.inspectCh)
+
+	// Enumeration fails if the allocator is unavailable.
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err = NewRunner(nil, "http://foo", 1, nil).Quarantined(ctx)
+	c.Check(err, gc.Equals, context.DeadlineExceeded)
+}
+
+var _ = gc.Suite(&RunnerSuite{})
@@ -0,0 +1,75 @@
+package gazette
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// NewServerTLSConfig returns a tls.Config for a server presenting the
+// certificate and key at |certPath| and |keyPath|. If |clientCAPath| is
+// non-empty, clients must present a certificate signed by a CA of the
+// PEM-encoded bundle at |clientCAPath| (mutual TLS).
+func NewServerTLSConfig(certPath, keyPath, clientCAPath string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	var cfg = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAPath != "" {
+		if cfg.ClientCAs, err = loadCertPool(clientCAPath); err != nil {
+			return nil, err
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// NewClientTLSConfig returns a tls.Config for a client which verifies servers
+// against the PEM-encoded CA bundle at |caPath|, or against system roots if
+// |caPath| is empty. If |certPath| and |keyPath| are non-empty, the client
+// presents their certificate to servers which request one.
+func NewClientTLSConfig(certPath, keyPath, caPath string) (*tls.Config, error) {
+	var cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if certPath != "" || keyPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if caPath != "" {
+		var err error
+		if cfg.RootCAs, err = loadCertPool(caPath); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// PeerIdentity returns the subject common name of the verified client
+// certificate of |r|, or the empty string if |r| didn't present one.
+func PeerIdentity(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
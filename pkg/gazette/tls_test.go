@@ -0,0 +1,129 @@
+package gazette
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	gc "github.com/go-check/check"
+)
+
+type TLSSuite struct {
+	dir string
+}
+
+func (s *TLSSuite) SetUpSuite(c *gc.C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "tls-suite")
+	c.Assert(err, gc.IsNil)
+
+	caCert, caKey := writeCertFixture(c, s.dir, "ca", nil, nil)
+	writeCertFixture(c, s.dir, "server", caCert, caKey)
+	writeCertFixture(c, s.dir, "client", caCert, caKey)
+}
+
+func (s *TLSSuite) TearDownSuite(c *gc.C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *TLSSuite) path(name string) string { return filepath.Join(s.dir, name) }
+
+func (s *TLSSuite) TestMutualTLSWithPeerIdentity(c *gc.C) {
+	serverCfg, err := NewServerTLSConfig(s.path("server.crt"), s.path("server.key"), s.path("ca.crt"))
+	c.Assert(err, gc.IsNil)
+
+	var server = httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, PeerIdentity(r)) }))
+	server.TLS = serverCfg
+	server.StartTLS()
+	defer server.Close()
+
+	// A client presenting a certificate is identified by its common name.
+	clientCfg, err := NewClientTLSConfig(s.path("client.crt"), s.path("client.key"), s.path("ca.crt"))
+	c.Assert(err, gc.IsNil)
+
+	var transport = MakeHttpTransport()
+	transport.TLSClientConfig = clientCfg
+	response, err := (&http.Client{Transport: transport}).Get(server.URL)
+	c.Assert(err, gc.IsNil)
+
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	c.Check(string(body), gc.Equals, "client")
+
+	// A client which doesn't present a certificate is rejected.
+	clientCfg, err = NewClientTLSConfig("", "", s.path("ca.crt"))
+	c.Assert(err, gc.IsNil)
+
+	transport = MakeHttpTransport()
+	transport.TLSClientConfig = clientCfg
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	c.Check(err, gc.NotNil)
+}
+
+func (s *TLSSuite) TestConfigErrors(c *gc.C) {
+	_, err := NewServerTLSConfig(s.path("missing.crt"), s.path("server.key"), "")
+	c.Check(err, gc.ErrorMatches, ".*no such file or directory")
+
+	// A certificate's private key is not a CA bundle.
+	_, err = NewClientTLSConfig("", "", s.path("ca.key"))
+	c.Check(err, gc.ErrorMatches, "no certificates found in .*")
+}
+
+func (s *TLSSuite) TestPeerIdentityWithoutTLS(c *gc.C) {
+	var r, _ = http.NewRequest("GET", "http://host/path", nil)
+	c.Check(PeerIdentity(r), gc.Equals, "")
+}
+
+// writeCertFixture writes a certificate and key of common name |name| to
+// |dir|, signed by |parent| (or self-signed, as a CA, if |parent| is nil).
+func writeCertFixture(c *gc.C, dir, name string, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, gc.IsNil)
+
+	var template = &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	c.Assert(err, gc.IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, gc.IsNil)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, name+".crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600), gc.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600), gc.IsNil)
+
+	return cert, key
+}
+
+var _ = gc.Suite(&TLSSuite{})
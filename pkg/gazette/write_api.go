@@ -1,20 +1,108 @@
 package gazette
 
 import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/hashicorp/golang-lru"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/LiveRamp/gazette/pkg/journal"
+	"github.com/LiveRamp/gazette/pkg/metrics"
 )
 
 type WriteAPI struct {
-	handler AppendOpHandler
+	handler     AppendOpHandler
+	idleTimeout time.Duration
+	idleConns   *DeadlineListener
+	admit       func() error
+
+	validate     func(name journal.Name, prefix []byte) error
+	validateSize int
+
+	maxInFlight int
+	inFlight    map[journal.Name]int
+	inFlightMu  sync.Mutex
+
+	// Write heads of recently committed appends, by dedupKey. Nil if disabled.
+	dedup *lru.Cache
+}
+
+// dedupKey identifies an append by its journal, producer, checksum and length.
+type dedupKey struct {
+	journal  journal.Name
+	producer string
+	sum      string
+	length   int64
 }
 
 func NewWriteAPI(handler AppendOpHandler) *WriteAPI {
-	return &WriteAPI{handler: handler}
+	return &WriteAPI{
+		handler:  handler,
+		inFlight: make(map[journal.Name]int),
+	}
+}
+
+// SetIdleTimeout bounds the time an append may spend awaiting further request
+// content from its client, once the broker has begun to read it. An idle append
+// fails with journal.ErrIdleTimeout, and its partial content is rolled back
+// rather than stalling the journal's pipeline. The timeout is applied as a read
+// deadline of the request's connection, which must have been accepted by
+// |conns| (appends of other connections are unbounded). Zero (the default) is
+// unbounded.
+func (h *WriteAPI) SetIdleTimeout(timeout time.Duration, conns *DeadlineListener) {
+	h.idleTimeout, h.idleConns = timeout, conns
+}
+
+// SetMaxInFlight bounds the number of appends of a journal which may be in
+// flight (queued for, or being brokered) at once. Further appends fail
+// immediately with journal.ErrTooManyAppends, rather than queuing behind them.
+// Zero (the default) is unbounded.
+func (h *WriteAPI) SetMaxInFlight(max int) {
+	h.maxInFlight = max
+}
+
+// SetAdmitter sets a function which is consulted before each append is begun.
+// It may block to apply back-pressure and, if it returns an error, the append
+// fails immediately with that error. For example, Persister.AppendAdmitted
+// delays appends while the broker's spool disk budget is exceeded, and
+// rejects them while its hard limit is.
+func (h *WriteAPI) SetAdmitter(admit func() error) {
+	h.admit = admit
+}
+
+// SetContentValidator sets a function which validates the content of each
+// append before it's brokered. |validate| is passed the journal and up to
+// |size| leading bytes of content (fewer, if the content is shorter). If it
+// returns an error, the append fails with journal.ErrInvalidContent. For
+// example, a validator may require that content of journals under a prefix
+// begin with a registered schema ID, and pass content of other journals.
+func (h *WriteAPI) SetContentValidator(size int, validate func(name journal.Name, prefix []byte) error) {
+	h.validate, h.validateSize = validate, size
+}
+
+// SetDedupWindow enables recognition of retried appends. Appends sent with a
+// producer and content checksum (see journal.AppendArgs.Producer) are
+// remembered as they commit, up to |size| most-recent appends. A further
+// append having the same journal, producer, checksum and length is presumed
+// to be a client's retry of an append which already committed (eg, after a
+// timeout), and succeeds with the write head of the prior commit rather than
+// being appended again. Zero (the default) disables the window.
+func (h *WriteAPI) SetDedupWindow(size int) {
+	if size <= 0 {
+		h.dedup = nil
+	} else {
+		h.dedup, _ = lru.New(size) // Fails only if |size| is not positive.
+	}
 }
 
 func (h *WriteAPI) Register(router *mux.Router) {
@@ -25,10 +113,67 @@ func (h *WriteAPI) Write(w http.ResponseWriter, r *http.Request) {
 	r = maybeTrace(r, "WriteAPI.Write")
 	defer finishTrace(r)
 
+	var name = journal.Name(r.URL.Path[1:])
+
+	var key, dedup = h.dedupKeyOf(name, r)
+	if dedup {
+		if head, ok := h.dedup.Get(key); ok {
+			r.Body.Close()
+			metrics.DeduplicatedAppendsTotal.Inc()
+			writeAppendResponse(w, r, journal.AppendResult{WriteHead: head.(int64)})
+			return
+		}
+	}
+
+	if h.admit != nil {
+		if err := h.admit(); err != nil {
+			r.Body.Close()
+			http.Error(w, err.Error(), journal.StatusCodeForError(err))
+			return
+		}
+	}
+	if !h.beginAppend(name) {
+		r.Body.Close()
+		http.Error(w, journal.ErrTooManyAppends.Error(),
+			journal.StatusCodeForError(journal.ErrTooManyAppends))
+		return
+	}
+	defer h.endAppend(name)
+
+	var content io.Reader = r.Body
+	if h.idleTimeout != 0 {
+		if conn, ok := h.idleConns.connOf(r); ok {
+			content = &idleTimeoutReader{body: r.Body, conn: conn, timeout: h.idleTimeout}
+			defer conn.SetReadDeadline(time.Time{})
+		}
+	}
+	if h.validate != nil {
+		var br = bufio.NewReaderSize(content, h.validateSize)
+		var prefix, err = br.Peek(h.validateSize)
+
+		if err == nil || err == io.EOF {
+			if err = h.validate(name, prefix); err != nil {
+				log.WithFields(log.Fields{"journal": name, "err": err}).Warn("rejected invalid append")
+				err = journal.ErrInvalidContent
+			}
+		}
+		if err != nil {
+			r.Body.Close()
+			http.Error(w, err.Error(), journal.StatusCodeForError(err))
+			return
+		}
+		content = br
+	}
+	// Verify content against its checksum, if the client sent one. A mismatch
+	// fails the append before it commits.
+	if sum := strings.ToLower(r.Header.Get(AppendSumHeader)); sum != "" {
+		content = &appendSumReader{Reader: content, hash: sha1.New(), sum: sum}
+	}
+
 	var op = journal.AppendOp{
 		AppendArgs: journal.AppendArgs{
-			Journal: journal.Name(r.URL.Path[1:]),
-			Content: r.Body,
+			Journal: name,
+			Content: content,
 			Context: r.Context(),
 		},
 		Result: make(chan journal.AppendResult, 1),
@@ -36,13 +181,24 @@ func (h *WriteAPI) Write(w http.ResponseWriter, r *http.Request) {
 	h.handler.Append(op)
 	result := <-op.Result
 
+	// Remember the committed append, which was verified against its checksum.
+	// The HTTP server has already verified its length.
+	if dedup && result.Error == nil {
+		h.dedup.Add(key, result.WriteHead)
+	}
+
+	r.Body.Close()
+	writeAppendResponse(w, r, result)
+}
+
+// writeAppendResponse writes the HTTP response of append |result|.
+func writeAppendResponse(w http.ResponseWriter, r *http.Request, result journal.AppendResult) {
 	if result.WriteHead != 0 {
 		w.Header().Set(WriteHeadHeader, strconv.FormatInt(result.WriteHead, 10))
 	}
 	if result.RouteToken != "" {
 		w.Header().Set(RouteTokenHeader, string(result.RouteToken))
 	}
-	r.Body.Close()
 
 	if result.Error == journal.ErrNotBroker {
 		// Return a Location header with the broker location.
@@ -53,3 +209,57 @@ func (h *WriteAPI) Write(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// dedupKeyOf returns the dedupKey of append request |r| of |name|, and whether
+// the append is subject to de-duplication.
+func (h *WriteAPI) dedupKeyOf(name journal.Name, r *http.Request) (dedupKey, bool) {
+	var key = dedupKey{
+		journal:  name,
+		producer: r.Header.Get(AppendProducerHeader),
+		sum:      strings.ToLower(r.Header.Get(AppendSumHeader)),
+		length:   r.ContentLength,
+	}
+	return key, h.dedup != nil && key.producer != "" && key.sum != "" && key.length >= 0
+}
+
+// appendSumReader verifies content read through EOF against its hex-encoded
+// SHA1 |sum|. On a mismatch, it returns journal.ErrAppendSumMismatch rather
+// than io.EOF. As a broker commits only appends read through EOF, the append
+// then fails without committing.
+type appendSumReader struct {
+	io.Reader
+	hash hash.Hash
+	sum  string
+}
+
+func (r *appendSumReader) Read(p []byte) (int, error) {
+	var n, err = r.Reader.Read(p)
+	r.hash.Write(p[:n])
+
+	if err == io.EOF && hex.EncodeToString(r.hash.Sum(nil)) != r.sum {
+		err = journal.ErrAppendSumMismatch
+	}
+	return n, err
+}
+
+// beginAppend returns whether an append of |name| may begin, accounting for it
+// as in-flight if so. Each successful beginAppend must be matched by endAppend.
+func (h *WriteAPI) beginAppend(name journal.Name) bool {
+	h.inFlightMu.Lock()
+	defer h.inFlightMu.Unlock()
+
+	if h.maxInFlight != 0 && h.inFlight[name] >= h.maxInFlight {
+		return false
+	}
+	h.inFlight[name]++
+	return true
+}
+
+func (h *WriteAPI) endAppend(name journal.Name) {
+	h.inFlightMu.Lock()
+	defer h.inFlightMu.Unlock()
+
+	if h.inFlight[name]--; h.inFlight[name] == 0 {
+		delete(h.inFlight, name)
+	}
+}
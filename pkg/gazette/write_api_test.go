@@ -0,0 +1,248 @@
+package gazette
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	gc "github.com/go-check/check"
+	"github.com/gorilla/mux"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type WriteAPISuite struct{}
+
+func (s *WriteAPISuite) TestMaxInFlightAppends(c *gc.C) {
+	var handler = make(heldAppends)
+	var api = NewWriteAPI(handler)
+	api.SetMaxInFlight(1)
+
+	var m = mux.NewRouter()
+	api.Register(m)
+
+	// The first append of a/journal is held in-flight.
+	var doneCh = make(chan *httptest.ResponseRecorder)
+	go func() {
+		var w = httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("PUT", "/a/journal", strings.NewReader("one")))
+		doneCh <- w
+	}()
+	var held = <-handler
+
+	// A second append of a/journal is rejected.
+	var w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("PUT", "/a/journal", strings.NewReader("two")))
+	c.Check(w.Code, gc.Equals, http.StatusTooManyRequests)
+
+	// An append of another journal may proceed.
+	go func() {
+		var op = <-handler
+		op.Result <- journal.AppendResult{WriteHead: 3}
+	}()
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("PUT", "/other/journal", strings.NewReader("foo")))
+	c.Check(w.Code, gc.Equals, http.StatusNoContent)
+
+	// Complete the held append. A further append of a/journal may now proceed.
+	var b, _ = ioutil.ReadAll(held.Content)
+	c.Check(string(b), gc.Equals, "one")
+	held.Result <- journal.AppendResult{WriteHead: 3}
+	c.Check((<-doneCh).Code, gc.Equals, http.StatusNoContent)
+
+	go func() {
+		var op = <-handler
+		op.Result <- journal.AppendResult{WriteHead: 6}
+	}()
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("PUT", "/a/journal", strings.NewReader("two")))
+	c.Check(w.Code, gc.Equals, http.StatusNoContent)
+	c.Check(w.Header().Get(WriteHeadHeader), gc.Equals, "6")
+}
+
+func (s *WriteAPISuite) TestAdmitter(c *gc.C) {
+	var api = NewWriteAPI(make(heldAppends)) // Appends would block.
+	api.SetAdmitter(func() error { return journal.ErrInsufficientStorage })
+
+	var m = mux.NewRouter()
+	api.Register(m)
+
+	var w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("PUT", "/a/journal", strings.NewReader("one")))
+	c.Check(w.Code, gc.Equals, http.StatusInsufficientStorage)
+}
+
+func (s *WriteAPISuite) TestContentValidator(c *gc.C) {
+	var handler = make(heldAppends)
+	var api = NewWriteAPI(handler)
+	api.SetContentValidator(4, func(name journal.Name, prefix []byte) error {
+		if name != "schema/journal" || string(prefix) == "ID01" {
+			return nil
+		}
+		return fmt.Errorf("unknown schema %q", prefix)
+	})
+
+	var m = mux.NewRouter()
+	api.Register(m)
+
+	var put = func(name, content string) *httptest.ResponseRecorder {
+		var w = httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("PUT", "/"+name, strings.NewReader(content)))
+		return w
+	}
+	var commit = func(expect string) {
+		var op = <-handler
+		var b, _ = ioutil.ReadAll(op.Content)
+		c.Check(string(b), gc.Equals, expect)
+		op.Result <- journal.AppendResult{WriteHead: 123}
+	}
+
+	// Valid content is appended in full.
+	go commit("ID01 content")
+	c.Check(put("schema/journal", "ID01 content").Code, gc.Equals, http.StatusNoContent)
+
+	// Invalid content is rejected without being brokered.
+	var w = put("schema/journal", "ID02 content")
+	c.Check(w.Code, gc.Equals, http.StatusUnprocessableEntity)
+	c.Check(w.Body.String(), gc.Equals, "invalid append content\n")
+
+	// As is content shorter than the prefix.
+	c.Check(put("schema/journal", "ID").Code, gc.Equals, http.StatusUnprocessableEntity)
+
+	// Validators may pass journals, including those of short content.
+	go commit("ID")
+	c.Check(put("other/journal", "ID").Code, gc.Equals, http.StatusNoContent)
+}
+
+func (s *WriteAPISuite) TestDedupWindow(c *gc.C) {
+	var handler = make(heldAppends)
+	var api = NewWriteAPI(handler)
+	api.SetDedupWindow(10)
+
+	var m = mux.NewRouter()
+	api.Register(m)
+
+	var put = func(producer, sum, content string) *httptest.ResponseRecorder {
+		var r = httptest.NewRequest("PUT", "/a/journal", strings.NewReader(content))
+		r.Header.Set(AppendProducerHeader, producer)
+		r.Header.Set(AppendSumHeader, sum)
+
+		var w = httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+	var commit = func(head int64) {
+		var op = <-handler
+		if _, err := ioutil.ReadAll(op.Content); err != nil {
+			op.Result <- journal.AppendResult{Error: err} // As would a Broker.
+		} else {
+			op.Result <- journal.AppendResult{WriteHead: head}
+		}
+	}
+	const fooSum = "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33" // SHA1 of "foo".
+
+	// An append is committed.
+	go commit(3)
+	var w = put("producer-1", fooSum, "foo")
+	c.Check(w.Code, gc.Equals, http.StatusNoContent)
+	c.Check(w.Header().Get(WriteHeadHeader), gc.Equals, "3")
+
+	// Its retry is recognized, and is not appended again.
+	w = put("producer-1", strings.ToUpper(fooSum), "foo")
+	c.Check(w.Code, gc.Equals, http.StatusNoContent)
+	c.Check(w.Header().Get(WriteHeadHeader), gc.Equals, "3")
+
+	// The same content of another producer is appended.
+	go commit(6)
+	w = put("producer-2", fooSum, "foo")
+	c.Check(w.Header().Get(WriteHeadHeader), gc.Equals, "6")
+
+	// An append having an incorrect checksum fails, and isn't remembered.
+	for i := 0; i != 2; i++ {
+		go commit(9)
+		w = put("producer-3", "badbadbad", "foo")
+		c.Check(w.Code, gc.Equals, http.StatusExpectationFailed)
+	}
+
+	// Appends lacking a producer are always appended.
+	for _, head := range []int64{9, 12} {
+		go commit(head)
+		w = put("", fooSum, "foo")
+		c.Check(w.Header().Get(WriteHeadHeader), gc.Equals, strconv.FormatInt(head, 10))
+	}
+	// Their checksum is verified, if sent.
+	go commit(15)
+	w = put("", "badbadbad", "foo")
+	c.Check(w.Code, gc.Equals, http.StatusExpectationFailed)
+}
+
+func (s *WriteAPISuite) TestIdleTimeout(c *gc.C) {
+	var handler = make(heldAppends)
+	var api = NewWriteAPI(handler)
+
+	var m = mux.NewRouter()
+	api.Register(m)
+
+	var server = httptest.NewUnstartedServer(m)
+	var conns = NewDeadlineListener(server.Listener)
+	server.Listener = conns
+	server.Start()
+	defer server.Close()
+
+	api.SetIdleTimeout(50*time.Millisecond, conns)
+
+	var commit = func(head int64) {
+		var op = <-handler
+		var b, err = ioutil.ReadAll(op.Content)
+		c.Check(string(b), gc.Equals, "foo")
+		c.Check(err, gc.IsNil)
+		op.Result <- journal.AppendResult{WriteHead: head}
+	}
+	var put = func() *http.Response {
+		var req, _ = http.NewRequest("PUT", server.URL+"/a/journal", strings.NewReader("foo"))
+		var resp, err = server.Client().Do(req)
+		c.Assert(err, gc.IsNil)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Appends which don't idle succeed, and a connection idling between
+	// appends remains usable.
+	go commit(3)
+	c.Check(put().StatusCode, gc.Equals, http.StatusNoContent)
+	time.Sleep(100 * time.Millisecond)
+	go commit(6)
+	c.Check(put().StatusCode, gc.Equals, http.StatusNoContent)
+
+	// An append which stalls mid-body fails with ErrIdleTimeout.
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	c.Assert(err, gc.IsNil)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("PUT /a/journal HTTP/1.1\r\nHost: test\r\n" +
+		"Content-Length: 10\r\n\r\nhello"))
+	c.Assert(err, gc.IsNil)
+
+	go func() {
+		var op = <-handler
+		var _, err = ioutil.ReadAll(op.Content)
+		c.Check(err, gc.Equals, journal.ErrIdleTimeout)
+		op.Result <- journal.AppendResult{Error: err}
+	}()
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	c.Assert(err, gc.IsNil)
+	c.Check(resp.StatusCode, gc.Equals, http.StatusRequestTimeout)
+}
+
+// heldAppends is an AppendOpHandler which passes AppendOps to the test.
+type heldAppends chan journal.AppendOp
+
+func (h heldAppends) Append(op journal.AppendOp) { h <- op }
+
+var _ = gc.Suite(&WriteAPISuite{})
@@ -2,12 +2,17 @@ package gazette
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"flag"
+	"fmt"
 	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -17,13 +22,18 @@ import (
 )
 
 var (
-	// Time to wait in between broker write errors. Exposed for debugging.
-	writeServiceCoolOffTimeout = time.Second * 5
-
 	writeConcurrency = flag.Int("gazetteWriteConcurrency", 4,
 		"Concurrency of asynchronous, locally-spooled Gazette write client")
 )
 
+// ErrWouldBlock is returned by a non-blocking WriteService when a write
+// would exceed a spool budget.
+var ErrWouldBlock = errors.New("write would block")
+
+// ErrDependencyFailed resolves a write ordered after a dependency which
+// failed, and which is therefore never attempted.
+var ErrDependencyFailed = errors.New("write dependency failed")
+
 const (
 	kMaxWriteSpoolSize = 1 << 27 // A single spool is up to 128MiB.
 	kWriteQueueSize    = 1024    // Allows a total of 128GiB of spooled writes.
@@ -38,6 +48,15 @@ type pendingWrite struct {
 	offset  int64
 	started time.Time
 	result  *journal.AsyncAppend
+	// AsyncAppends which must commit before this write may be attempted.
+	dependencies []*journal.AsyncAppend
+	// Closed when the write may no longer be appended to, and should be
+	// dispatched without waiting out the coalescing delay.
+	flushCh chan struct{}
+	// Directory of a durable, named spool (empty if the spool is unlinked),
+	// and the spool's creation sequence number.
+	durableDir string
+	seq        int64
 }
 
 var pendingWritePool = sync.Pool{
@@ -55,6 +74,9 @@ var pendingWritePool = sync.Pool{
 	}}
 
 func releasePendingWrite(p *pendingWrite) error {
+	if p.durableDir != "" {
+		return releaseDurableWrite(p)
+	}
 	*p = pendingWrite{file: p.file}
 	if _, err := p.file.Seek(0, 0); err != nil {
 		return err
@@ -65,6 +87,9 @@ func releasePendingWrite(p *pendingWrite) error {
 
 func writeAllOrNone(write *pendingWrite, r io.Reader) error {
 	n, err := io.Copy(write.file, r)
+	if err == nil && write.durableDir != "" {
+		err = write.commitDurable(write.offset + n)
+	}
 	if err == nil {
 		write.offset += int64(n)
 	} else {
@@ -77,7 +102,9 @@ func writeAllOrNone(write *pendingWrite, r io.Reader) error {
 // of writes to Gazette journals. Writes to each journal are spooled to local
 // disk (and never memory), so back-pressure from slow or down brokers does not
 // affect busy writers (at least, until disk runs out). Writes are retried
-// indefinitely, until aknowledged by a broker.
+// indefinitely, until aknowledged by a broker. Writes to a journal are
+// committed in the order they were made, and a write may additionally be
+// ordered after writes to other journals (see ReadFromAfter).
 type WriteService struct {
 	client  *Client
 	stopped chan struct{} // Coordinates exit of service loops.
@@ -88,6 +115,51 @@ type WriteService struct {
 	// Indexes pendingWrite's which are in |writeQueue|, and still append-able.
 	writeIndex   map[journal.Name]*pendingWrite
 	writeIndexMu sync.Mutex
+
+	// Directory of durable spools, if durable spooling is enabled, the
+	// sequence number of the next durable spool, and spools of a prior process
+	// which are queued ahead of new writes.
+	durableDir       string
+	durableSeq       int64
+	durableRecovered []*pendingWrite
+
+	// Bytes spooled but not yet committed, by journal and in total, and
+	// budgets (if non-zero) of each. |spoolCond| is signaled (under
+	// |writeIndexMu|) as spooled writes commit.
+	spooled       map[journal.Name]int64
+	spooledTotal  int64
+	journalBudget int64
+	globalBudget  int64
+	budgetBlocks  bool
+	spoolCond     *sync.Cond
+
+	// Optional observer of write events.
+	observer WriteObserver
+	// Whether journal-labeled write metrics are exported.
+	journalMetrics bool
+	// Duration for which a write may coalesce further writes before dispatch.
+	coalesceDelay time.Duration
+
+	// Random identifier of this WriteService, and a counter of dispatched
+	// writes, from which each write's journal.AppendArgs.Producer is formed.
+	producer   string
+	producerID int64
+}
+
+// WriteObserver is notified of WriteService events, allowing applications to
+// export write statistics to their own monitoring systems. Its methods are
+// called synchronously from WriteService goroutines (sometimes while holding
+// internal locks), and must not block.
+type WriteObserver interface {
+	// ObserveSpooled is called as |bytes| are spooled to |name|, and with
+	// negative |bytes| as spooled writes commit.
+	ObserveSpooled(name journal.Name, bytes int64)
+	// ObserveRetry is called as a write attempt of |name| fails with |err|,
+	// and will be retried.
+	ObserveRetry(name journal.Name, err error)
+	// ObserveCommit is called as a write of |bytes| to |name| commits, having
+	// |latency| since the write was first spooled.
+	ObserveCommit(name journal.Name, bytes int64, latency time.Duration)
 }
 
 func NewWriteService(client *Client) *WriteService {
@@ -96,19 +168,115 @@ func NewWriteService(client *Client) *WriteService {
 		stopped:    make(chan struct{}),
 		writeQueue: nil,
 		writeIndex: make(map[journal.Name]*pendingWrite),
+		spooled:    make(map[journal.Name]int64),
 	}
+	writeService.spoolCond = sync.NewCond(&writeService.writeIndexMu)
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		log.WithField("err", err).Panic("failed to generate producer ID")
+	}
+	writeService.producer = hex.EncodeToString(id[:])
 
 	writeService.SetConcurrency(*writeConcurrency)
 
 	return writeService
 }
 
+// SetConcurrency sets the number of concurrent service loops. Writes
+// recovered from a durable spool directory are queued ahead of any others.
+// Must be called before Start, and before any writes are made.
 func (c *WriteService) SetConcurrency(concurrency int) {
+	var recovered = make([][]*pendingWrite, concurrency)
 	c.writeQueue = make([]chan *pendingWrite, concurrency)
 
+	for _, write := range c.durableRecovered {
+		var route = c.writeRoute(write.journal)
+		recovered[route] = append(recovered[route], write)
+	}
 	for i := range c.writeQueue {
-		c.writeQueue[i] = make(chan *pendingWrite, kWriteQueueSize)
+		c.writeQueue[i] = make(chan *pendingWrite, kWriteQueueSize+len(recovered[i]))
+
+		for _, write := range recovered[i] {
+			c.writeQueue[i] <- write
+		}
+	}
+}
+
+// SetDurableSpoolDirectory enables durable spooling of writes within |dir|.
+// By default, spools are unlinked files which are lost if the process exits
+// before they're written to a broker. Durable spools are instead named files
+// of |dir|, and each write is synced to disk before Write or ReadFrom return.
+// Spools remaining in |dir| from a prior process are recovered, and are queued
+// ahead of any new writes. Writes are thus delivered at least once
+// across process restarts, at the cost of a disk sync per write.
+// Must be called before Start, and before any writes are made.
+func (c *WriteService) SetDurableSpoolDirectory(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
 	}
+	recovered, err := recoverDurableWrites(dir)
+	if err != nil {
+		return err
+	}
+	c.durableDir = dir
+	c.durableSeq = time.Now().UnixNano()
+	c.durableRecovered = recovered
+
+	for _, write := range recovered {
+		c.addSpooled(write.journal, write.offset, 1)
+
+		// Sequence new spools after those recovered.
+		if write.seq >= c.durableSeq {
+			c.durableSeq = write.seq + 1
+		}
+		log.WithFields(log.Fields{"journal": write.journal, "bytes": write.offset}).
+			Info("recovered durable write")
+	}
+	// Re-build queues, with recovered writes queued first.
+	c.SetConcurrency(len(c.writeQueue))
+	return nil
+}
+
+// SetSpoolBudgets bounds the number of bytes which may be spooled but not yet
+// committed, for each journal and across all journals. A budget of zero is
+// unbounded (the default). A write is admitted if spooled bytes are below
+// budget at the time it's made, and budgets may thus be exceeded by up to the
+// size of a single write. Otherwise, if |block| the write waits for prior
+// writes to commit, and if not it fails with ErrWouldBlock. Budgets prevent a
+// single stalled journal from consuming all available spool, to the detriment
+// of writes to other journals.
+func (c *WriteService) SetSpoolBudgets(perJournal, global int64, block bool) {
+	c.writeIndexMu.Lock()
+	c.journalBudget, c.globalBudget, c.budgetBlocks = perJournal, global, block
+	c.spoolCond.Broadcast()
+	c.writeIndexMu.Unlock()
+}
+
+// SetJournalMetrics enables Prometheus metrics of writes labeled by journal
+// (metrics.GazetteWriteJournal*), in addition to unlabeled totals. Each written
+// journal adds label values which are retained for the life of the process,
+// so it should be enabled only by applications writing a bounded set of
+// journals. Must be called before Start, and before any writes are made.
+func (c *WriteService) SetJournalMetrics(enabled bool) {
+	c.journalMetrics = enabled
+}
+
+// SetObserver sets a WriteObserver to be notified of write events, in addition
+// to the service's Prometheus metrics. Must be called before Start, and before
+// any writes are made.
+func (c *WriteService) SetObserver(observer WriteObserver) {
+	c.observer = observer
+}
+
+// SetCoalescingDelay sets a delay from the start of a write to its dispatch
+// to a broker, during which further writes to the journal may be coalesced
+// into it. By default writes are dispatched as soon as a service loop is
+// available, and coalesce only while service loops are busy. A delay trades
+// latency for larger (and fewer) appends. Flush dispatches a write without
+// waiting out the delay. Must be called before Start.
+func (c *WriteService) SetCoalescingDelay(delay time.Duration) {
+	c.coalesceDelay = delay
 }
 
 // Begins the write service loop. Be sure to invoke Stop() prior to process
@@ -126,6 +294,14 @@ func (c *WriteService) Start() {
 
 // Stops the write service loop. Returns only after all writes have completed.
 func (c *WriteService) Stop() {
+	// Flush pending writes, rather than waiting out coalescing delays.
+	c.writeIndexMu.Lock()
+	for name, write := range c.writeIndex {
+		delete(c.writeIndex, name)
+		close(write.flushCh)
+	}
+	c.writeIndexMu.Unlock()
+
 	for i := range c.writeQueue {
 		close(c.writeQueue[i])
 	}
@@ -134,13 +310,35 @@ func (c *WriteService) Stop() {
 	}
 }
 
-func (c *WriteService) obtainWrite(name journal.Name) (*pendingWrite, bool, error) {
+// obtainWrite returns a pendingWrite of |name| into which content may be
+// appended, and whether it's newly created (and must be queued). If
+// |dependencies| is non-empty, a new pendingWrite is always started: allowing
+// dependencies to be added to an already-queued write could otherwise order
+// it after writes queued behind it, and deadlock.
+func (c *WriteService) obtainWrite(name journal.Name,
+	dependencies []*journal.AsyncAppend) (*pendingWrite, bool, error) {
+
 	// Is a non-full pendingWrite for this journal already in |writeQueue|?
 	write, ok := c.writeIndex[name]
-	if ok && write.offset < kMaxWriteSpoolSize {
+	if ok && write.offset < kMaxWriteSpoolSize && len(dependencies) == 0 {
 		return write, false, nil
+	} else if ok {
+		// |write| is being replaced, and won't coalesce further. Remove it from
+		// the index now, as its replacement may yet fail to be created.
+		delete(c.writeIndex, name)
+		close(write.flushCh)
+	}
+	var popped interface{}
+
+	if c.durableDir != "" {
+		var err error
+		if popped, err = newDurableWrite(c.durableDir, c.durableSeq, name); err != nil {
+			popped = err
+		}
+		c.durableSeq++
+	} else {
+		popped = pendingWritePool.Get()
 	}
-	popped := pendingWritePool.Get()
 
 	if err, ok := popped.(error); ok {
 		return nil, false, err
@@ -150,7 +348,9 @@ func (c *WriteService) obtainWrite(name journal.Name) (*pendingWrite, bool, erro
 		write.result = &journal.AsyncAppend{
 			Ready: make(chan struct{}),
 		}
+		write.dependencies = append(write.dependencies, dependencies...)
 		write.started = time.Now()
+		write.flushCh = make(chan struct{})
 		c.writeIndex[name] = write
 		return write, true, nil
 	}
@@ -167,14 +367,46 @@ func (c *WriteService) Write(name journal.Name, buf []byte) (*journal.AsyncAppen
 // |r| is written, or none of it is. Returns an AsyncAppend which is
 // resolved when the write has been fully committed.
 func (c *WriteService) ReadFrom(name journal.Name, r io.Reader) (*journal.AsyncAppend, error) {
+	return c.ReadFromAfter(name, r)
+}
+
+// WriteAfter is like Write, but |buf| is not appended until each of
+// |dependencies| has committed. See ReadFromAfter.
+func (c *WriteService) WriteAfter(name journal.Name, buf []byte,
+	dependencies ...*journal.AsyncAppend) (*journal.AsyncAppend, error) {
+	return c.ReadFromAfter(name, bytes.NewReader(buf), dependencies...)
+}
+
+// ReadFromAfter is like ReadFrom, but |r|'s content is not appended until each
+// of |dependencies| has committed. Dependencies may be AsyncAppends of any
+// journal. This allows, for example, a checkpoint to be written to one journal
+// only after the data it references has committed to others. Once resolved,
+// the returned AsyncAppend's WriteHead is the journal offset through which
+// the write (and any writes batched with it) committed.
+func (c *WriteService) ReadFromAfter(name journal.Name, r io.Reader,
+	dependencies ...*journal.AsyncAppend) (*journal.AsyncAppend, error) {
+
 	var result *journal.AsyncAppend
 	var writeErr error
 
 	c.writeIndexMu.Lock()
-	write, isNew, obtainErr := c.obtainWrite(name)
+	if err := c.awaitSpoolBudget(name); err != nil {
+		c.writeIndexMu.Unlock()
+		return nil, err
+	}
+	dependencies = c.pendingDependencies(name, dependencies)
+
+	write, isNew, obtainErr := c.obtainWrite(name, dependencies)
 	if obtainErr == nil {
+		var offset = write.offset
 		writeErr = writeAllOrNone(write, r)
 		result = write.result // Retain, as we can't access |write| after unlock.
+
+		if isNew {
+			c.addSpooled(name, write.offset-offset, 1)
+		} else {
+			c.addSpooled(name, write.offset-offset, 0)
+		}
 	}
 	c.writeIndexMu.Unlock()
 
@@ -182,15 +414,117 @@ func (c *WriteService) ReadFrom(name journal.Name, r io.Reader) (*journal.AsyncA
 		return nil, obtainErr
 	}
 	if isNew {
-		// Hash |name| to identify a service loop to queue |write| on. This allows
-		// for multiple, concurrent service loops while ensuring that |writes| from
-		// a single client are strictly in-order.
-		route := int(crc32.Checksum([]byte(name), crc32.IEEETable))
-		c.writeQueue[route%len(c.writeQueue)] <- write
+		c.writeQueue[c.writeRoute(name)] <- write
 	}
 	return result, writeErr
 }
 
+// Flush dispatches the pending write of |name| (if any) without waiting out
+// the coalescing delay, and returns its AsyncAppend. Further writes of |name|
+// begin a new pending write. Flush returns nil if there is no pending write
+// which may still be appended to.
+func (c *WriteService) Flush(name journal.Name) *journal.AsyncAppend {
+	c.writeIndexMu.Lock()
+	defer c.writeIndexMu.Unlock()
+
+	var write, ok = c.writeIndex[name]
+	if !ok {
+		return nil
+	}
+	delete(c.writeIndex, name)
+	close(write.flushCh)
+
+	return write.result
+}
+
+// WriteSync appends |buf| to |journal| as does Write, then flushes and returns
+// only after the write has committed.
+func (c *WriteService) WriteSync(name journal.Name, buf []byte) (*journal.AsyncAppend, error) {
+	var result, err = c.Write(name, buf)
+	if err != nil {
+		return nil, err
+	}
+	c.Flush(name)
+	<-result.Ready
+
+	return result, nil
+}
+
+// writeRoute hashes |name| to identify a service loop to queue its writes on.
+// This allows for multiple, concurrent service loops while ensuring that
+// writes to a single journal are strictly in-order.
+func (c *WriteService) writeRoute(name journal.Name) int {
+	return int(crc32.Checksum([]byte(name), crc32.IEEETable)) % len(c.writeQueue)
+}
+
+// awaitSpoolBudget returns once a write of |name| is within spool budgets, or
+// returns ErrWouldBlock if budgets are exceeded and the service doesn't block.
+// Must be called with |writeIndexMu| held.
+func (c *WriteService) awaitSpoolBudget(name journal.Name) error {
+	var blocked bool
+
+	for (c.journalBudget != 0 && c.spooled[name] >= c.journalBudget) ||
+		(c.globalBudget != 0 && c.spooledTotal >= c.globalBudget) {
+
+		if !blocked {
+			metrics.GazetteWriteBlockedTotal.Inc()
+			blocked = true
+		}
+		if !c.budgetBlocks {
+			return ErrWouldBlock
+		}
+		c.spoolCond.Wait()
+	}
+	return nil
+}
+
+// addSpooled accounts for |bytes| and |writes| newly spooled to |name|,
+// which are negative as spooled writes commit.
+// Must be called with |writeIndexMu| held.
+func (c *WriteService) addSpooled(name journal.Name, bytes, writes int64) {
+	if c.spooled[name] += bytes; c.spooled[name] == 0 {
+		delete(c.spooled, name)
+	}
+	c.spooledTotal += bytes
+
+	metrics.GazetteWriteSpooledBytes.Add(float64(bytes))
+	metrics.GazetteWriteSpooledWrites.Add(float64(writes))
+
+	if _, ok := c.spooled[name]; ok && c.journalMetrics {
+		metrics.GazetteWriteJournalSpooledBytes.WithLabelValues(name.String()).Add(float64(bytes))
+	} else if c.journalMetrics {
+		// The journal's spool has drained. Drop its gauge, rather than
+		// retaining one for every journal which has been written.
+		metrics.GazetteWriteJournalSpooledBytes.DeleteLabelValues(name.String())
+	}
+
+	if c.observer != nil {
+		c.observer.ObserveSpooled(name, bytes)
+	}
+}
+
+// pendingDependencies filters |dependencies| to those which have not yet
+// committed, and which are not already ordered before further writes to
+// |name| (because they are themselves writes of |name|).
+// Must be called with |writeIndexMu| held.
+func (c *WriteService) pendingDependencies(name journal.Name,
+	dependencies []*journal.AsyncAppend) []*journal.AsyncAppend {
+
+	var out []*journal.AsyncAppend
+	for _, dep := range dependencies {
+		if cur, ok := c.writeIndex[name]; ok && cur.result == dep {
+			continue // Already ordered, as a prior write of |name|.
+		}
+		select {
+		case <-dep.Ready:
+			continue // Already committed.
+		default:
+			out = append(out, dep)
+		}
+	}
+	return out
+}
+
 func (c *WriteService) serveWrites(index int) {
 	for {
 		write := <-c.writeQueue[index]
@@ -199,56 +533,107 @@ func (c *WriteService) serveWrites(index int) {
 			break
 		}
 
+		if c.coalesceDelay != 0 {
+			// Allow further writes to coalesce, until flushed or the delay elapses.
+			var timer = time.NewTimer(write.started.Add(c.coalesceDelay).Sub(time.Now()))
+			select {
+			case <-timer.C:
+			case <-write.flushCh:
+			}
+			timer.Stop()
+		}
+
 		c.writeIndexMu.Lock()
 		if c.writeIndex[write.journal] == write {
 			delete(c.writeIndex, write.journal)
 		}
 		c.writeIndexMu.Unlock()
 
-		if err := c.onWrite(write); err != nil {
+		// Dependencies were queued before |write| was, and are resolved in
+		// order by their own service loops. If any failed, so does |write|.
+		var depFailed bool
+		for _, dep := range write.dependencies {
+			if <-dep.Ready; dep.Error != nil {
+				depFailed = true
+			}
+		}
+
+		// Retain, as |write| is released by onWrite.
+		var name, size = write.journal, write.offset
+
+		var err error
+		if depFailed {
+			err = ErrDependencyFailed
+			c.failWrite(write, err)
+		} else {
+			err = c.onWrite(write)
+		}
+		if err != nil {
 			metrics.GazetteWriteFailureTotal.Inc()
-			log.WithFields(log.Fields{"journal": write.journal, "err": err}).
+			log.WithFields(log.Fields{"journal": name, "err": err}).
 				Error("write failed")
 		}
+
+		c.writeIndexMu.Lock()
+		c.addSpooled(name, -size, -1)
+		c.spoolCond.Broadcast()
+		c.writeIndexMu.Unlock()
 	}
 	c.stopped <- struct{}{} // Signal exit.
 }
 
 func (c *WriteService) onWrite(write *pendingWrite) error {
+	// Attempts of |write| share a Producer, allowing a broker to recognize a
+	// retry of an attempt which committed despite failing (eg, on timeout).
+	var producer = fmt.Sprintf("%s-%d", c.producer, atomic.AddInt64(&c.producerID, 1))
+
 	// We now have exclusive ownership of |write|. Iterate
-	// attempting to write to server, until it's acknowledged.
-	for true {
+	// attempting to write to server, until it's acknowledged. Failed attempts
+	// back off per the Client's RetryPolicy.
+	for failures := 0; true; failures++ {
 		if _, err := write.file.Seek(0, 0); err != nil {
+			c.failWrite(write, err)
 			return err // Not recoverable
 		}
 		result := c.client.Put(journal.AppendArgs{
-			Journal: write.journal,
-			Content: io.NewSectionReader(write.file, 0, write.offset),
+			Journal:  write.journal,
+			Content:  io.NewSectionReader(write.file, 0, write.offset),
+			Producer: producer,
 		})
 
-		switch result.Error {
-		case nil:
-			break
+		if result.Error != nil {
+			c.observeRetry(write.journal, result.Error)
+		}
 
-		case journal.ErrNotBroker:
-			// The route topology has changed, generally due to a service update.
-			// Immediately retry against the indicated broker.
-			continue
+		switch {
+		case result.Error == nil:
+			break
 
-		case journal.ErrNotFound:
+		case result.Error == journal.ErrNotFound:
 			// First-write case: Implicitly create a Journal which doesn't yet exist.
 			if err := c.client.Create(write.journal); err != nil {
 				log.WithFields(log.Fields{"journal": write.journal, "err": err}).
 					Warn("failed to create journal")
-				time.Sleep(writeServiceCoolOffTimeout)
+				time.Sleep(c.client.retryPolicy.Backoff(failures))
 			} else {
 				log.WithField("journal", write.journal).Info("created journal")
 			}
 			continue
 
+		case result.Error == journal.ErrInvalidContent:
+			// The broker will never accept this content, and retries would stall
+			// further writes of the journal. Fail the write.
+			c.failWrite(write, result.Error)
+			return result.Error
+
+		case journal.RetryabilityOf(result.Error) == journal.RetryAfterReroute:
+			// The route topology has changed, generally due to a service update.
+			// Immediately retry against the indicated broker.
+			continue
+
 		default:
 			metrics.GazetteWriteFailureTotal.Inc()
-			time.Sleep(writeServiceCoolOffTimeout)
+			time.Sleep(c.client.retryPolicy.Backoff(failures))
 			continue
 		}
 
@@ -256,9 +641,19 @@ func (c *WriteService) onWrite(write *pendingWrite) error {
 		write.result.AppendResult = result
 		close(write.result.Ready)
 
-		metrics.GazetteWriteDurationTotal.Add(time.Now().Sub(write.started).Seconds())
+		var latency = time.Now().Sub(write.started)
+
+		metrics.GazetteWriteDurationTotal.Add(latency.Seconds())
 		metrics.GazetteWriteBytesTotal.Add(float64(write.offset))
 		metrics.GazetteWriteCountTotal.Inc()
+		if c.journalMetrics {
+			metrics.GazetteWriteJournalDurationSeconds.WithLabelValues(write.journal.String()).Observe(latency.Seconds())
+			metrics.GazetteWriteJournalBytesTotal.WithLabelValues(write.journal.String()).Add(float64(write.offset))
+		}
+
+		if c.observer != nil {
+			c.observer.ObserveCommit(write.journal, write.offset, latency)
+		}
 
 		if err := releasePendingWrite(write); err != nil {
 			log.WithField("err", err).Error("failed to release pending write")
@@ -268,6 +663,27 @@ func (c *WriteService) onWrite(write *pendingWrite) error {
 	panic("not reached")
 }
 
+// failWrite resolves |write| (including all writes coalesced into it) with
+// |err|, and releases it. Writes which depend on |write| also fail.
+func (c *WriteService) failWrite(write *pendingWrite, err error) {
+	write.result.AppendResult = journal.AppendResult{Error: err}
+	close(write.result.Ready)
+
+	if err := releasePendingWrite(write); err != nil {
+		log.WithField("err", err).Error("failed to release pending write")
+	}
+}
+
+// observeRetry accounts for a failed write attempt of |name|, which is retried.
+func (c *WriteService) observeRetry(name journal.Name, err error) {
+	if c.journalMetrics {
+		metrics.GazetteWriteJournalRetriesTotal.WithLabelValues(name.String()).Inc()
+	}
+	if c.observer != nil {
+		c.observer.ObserveRetry(name, err)
+	}
+}
+
 // Adapter to allow |WriteService| to return io.Writers for arbitrary journals
 // that can be written to directly.
 type namedWriter struct {
@@ -285,12 +701,14 @@ func (c *WriteService) WriterFor(name journal.Name, sync bool) io.Writer {
 }
 
 func (w *namedWriter) Write(data []byte) (int, error) {
-	result, err := w.writeService.Write(w.name, data)
+	var err error
+	if w.sync {
+		_, err = w.writeService.WriteSync(w.name, data)
+	} else {
+		_, err = w.writeService.Write(w.name, data)
+	}
 	if err != nil {
 		return 0, err
 	}
-	if w.sync {
-		<-result.Ready
-	}
 	return len(data), nil
 }
@@ -8,12 +8,15 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	gc "github.com/go-check/check"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/LiveRamp/gazette/pkg/journal"
+	"github.com/LiveRamp/gazette/pkg/metrics"
 )
 
 type WriteServiceSuite struct{}
@@ -93,15 +96,12 @@ func (s *WriteServiceSuite) TestParallelWriteSpooling(c *gc.C) {
 }
 
 func (s *WriteServiceSuite) TestWriteLifecycle(c *gc.C) {
-	// Shorten the write error cool-off interval for this test.
-	actualTimeout := writeServiceCoolOffTimeout
-	writeServiceCoolOffTimeout = time.Millisecond
-	defer func() { writeServiceCoolOffTimeout = actualTimeout }()
-
 	var mockClient mockHttpClient
 
 	client, _ := NewClient("http://server")
 	client.httpClient = &mockClient
+	// Shorten the write error back-off for this test.
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
 
 	// Pre-fill client's route cache for /a/journal and /another/journal.
 	client.locationCache.Add("/a/journal", newURL("http://server/a/journal"))
@@ -204,4 +204,500 @@ func (s *WriteServiceSuite) TestWriteLifecycle(c *gc.C) {
 	mockClient.AssertExpectations(c)
 }
 
+func (s *WriteServiceSuite) TestDependenciesStartNewWrites(c *gc.C) {
+	var writer = NewWriteService(nil)
+	writer.SetConcurrency(1)
+
+	// Writes are queued, but not served (the service isn't started).
+	aaA, err := writer.Write("a/journal", []byte("one"))
+	c.Check(err, gc.IsNil)
+	aaB, err := writer.Write("another/journal", []byte("two"))
+	c.Check(err, gc.IsNil)
+
+	// A dependency on a prior write of the same journal is already ordered,
+	// and doesn't require a new pendingWrite.
+	aa, err := writer.WriteAfter("a/journal", []byte("three"), aaA)
+	c.Check(err, gc.IsNil)
+	c.Check(aa, gc.Equals, aaA)
+
+	// A dependency on another journal's write starts a new pendingWrite.
+	aaC, err := writer.WriteAfter("a/journal", []byte("four"), aaB)
+	c.Check(err, gc.IsNil)
+	c.Check(aaC, gc.Not(gc.Equals), aaA)
+	c.Check(writer.writeIndex["a/journal"].dependencies,
+		gc.DeepEquals, []*journal.AsyncAppend{aaB})
+
+	// Writes without dependencies coalesce into the new pendingWrite.
+	aa, err = writer.Write("a/journal", []byte("five"))
+	c.Check(err, gc.IsNil)
+	c.Check(aa, gc.Equals, aaC)
+
+	// Dependencies which have already committed are ignored.
+	close(aaB.Ready)
+	aa, err = writer.WriteAfter("a/journal", []byte("six"), aaB)
+	c.Check(err, gc.IsNil)
+	c.Check(aa, gc.Equals, aaC)
+
+	c.Check(len(writer.writeQueue[0]), gc.Equals, 3)
+}
+
+func (s *WriteServiceSuite) TestWriteAfterDependency(c *gc.C) {
+	var mockClient mockHttpClient
+
+	client, _ := NewClient("http://server")
+	client.httpClient = &mockClient
+
+	client.locationCache.Add("/a/journal", newURL("http://server/a/journal"))
+	client.locationCache.Add("/another/journal", newURL("http://server/another/journal"))
+
+	var writer = NewWriteService(client)
+	writer.SetConcurrency(2)
+
+	var dataAA, err = writer.Write("a/journal", []byte("data"))
+	c.Check(err, gc.IsNil)
+	checkpointAA, err := writer.WriteAfter("another/journal", []byte("checkpoint"), dataAA)
+	c.Check(err, gc.IsNil)
+
+	// Hold the PUT of a/journal until released by the test.
+	var release = make(chan struct{})
+
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT" && request.URL.Path == "/a/journal"
+	})).Return(&http.Response{
+		StatusCode: http.StatusNoContent,
+		Header:     http.Header{WriteHeadHeader: []string{"1234"}},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil).Run(func(mock.Arguments) { <-release }).Once()
+
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT" && request.URL.Path == "/another/journal"
+	})).Return(&http.Response{
+		StatusCode: http.StatusNoContent,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil).Run(func(mock.Arguments) {
+		// Expect the dependency committed before this PUT was attempted.
+		select {
+		case <-dataAA.Ready:
+		default:
+			c.Error("dependency not yet committed")
+		}
+	}).Once()
+
+	writer.Start()
+
+	select {
+	case <-checkpointAA.Ready:
+		c.Error("checkpoint committed before its dependency")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+
+	<-checkpointAA.Ready
+	c.Check(dataAA.WriteHead, gc.Equals, int64(1234))
+
+	writer.Stop()
+	mockClient.AssertExpectations(c)
+}
+
+func (s *WriteServiceSuite) TestDurableSpoolRecovery(c *gc.C) {
+	dir, err := ioutil.TempDir("", "write-service-test")
+	c.Assert(err, gc.IsNil)
+	defer os.RemoveAll(dir)
+
+	// A first writer spools writes, but "crashes" before serving them.
+	var crashed = NewWriteService(nil)
+	c.Check(crashed.SetDurableSpoolDirectory(dir), gc.IsNil)
+	crashed.SetConcurrency(1)
+
+	_, err = crashed.Write("a/journal", []byte("foo"))
+	c.Check(err, gc.IsNil)
+	_, err = crashed.ReadFrom("a/journal", errReader{strings.NewReader("xxx")})
+	c.Check(err, gc.ErrorMatches, "error!")
+	_, err = crashed.Write("a/journal", []byte("bar"))
+	c.Check(err, gc.IsNil)
+
+	// Expect a named spool reflecting the committed offset.
+	var spool = crashed.writeIndex["a/journal"]
+	c.Check(spool.file.Name(), gc.Equals, spool.durablePath(0))
+	_, err = os.Stat(spool.durablePath(6))
+	c.Check(err, gc.IsNil)
+	c.Check(spool.file.Close(), gc.IsNil)
+
+	var mockClient mockHttpClient
+
+	client, _ := NewClient("http://server")
+	client.httpClient = &mockClient
+	client.locationCache.Add("/a/journal", newURL("http://server/a/journal"))
+
+	// A second writer recovers spooled writes, and orders them before new ones.
+	var writer = NewWriteService(client)
+	c.Check(writer.SetDurableSpoolDirectory(dir), gc.IsNil)
+
+	bazAA, err := writer.Write("a/journal", []byte("baz"))
+	c.Check(err, gc.IsNil)
+
+	var expect = []string{"foobar", "baz"}
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT" && request.URL.Path == "/a/journal"
+	})).Return(&http.Response{
+		StatusCode: http.StatusNoContent,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil).Run(func(args mock.Arguments) {
+		content, _ := ioutil.ReadAll(args[0].(*http.Request).Body)
+		c.Check(string(content), gc.Equals, expect[0])
+		expect = expect[1:]
+	}).Twice()
+
+	writer.Start()
+	<-bazAA.Ready
+	writer.Stop()
+
+	mockClient.AssertExpectations(c)
+
+	// Expect committed spools were removed.
+	infos, err := ioutil.ReadDir(dir)
+	c.Check(err, gc.IsNil)
+	c.Check(infos, gc.HasLen, 0)
+}
+
+func (s *WriteServiceSuite) TestFailedReplacementOfPendingWrite(c *gc.C) {
+	var dir, err = ioutil.TempDir("", "write-service-test")
+	c.Assert(err, gc.IsNil)
+	defer os.RemoveAll(dir)
+
+	var writer = NewWriteService(nil)
+	c.Check(writer.SetDurableSpoolDirectory(dir), gc.IsNil)
+
+	dep, err := writer.Write("b/journal", []byte("dep"))
+	c.Check(err, gc.IsNil)
+	_, err = writer.Write("a/journal", []byte("foo"))
+	c.Check(err, gc.IsNil)
+
+	// Spools can no longer be created. A write with a dependency must replace
+	// the pending write of its journal, and fails to.
+	c.Assert(os.RemoveAll(dir), gc.IsNil)
+	_, err = writer.WriteAfter("a/journal", []byte("bar"), dep)
+	c.Check(err, gc.NotNil)
+
+	// The replaced write was dispatched, and is not flushed again.
+	c.Check(writer.Flush("a/journal"), gc.IsNil)
+	_, err = writer.Write("a/journal", []byte("baz"))
+	c.Check(err, gc.NotNil)
+	c.Check(writer.writeIndex, gc.HasLen, 1) // Only |dep| remains pending.
+}
+
+func (s *WriteServiceSuite) TestSpoolBudgets(c *gc.C) {
+	var mockClient mockHttpClient
+
+	client, _ := NewClient("http://server")
+	client.httpClient = &mockClient
+	client.locationCache.Add("/a/journal", newURL("http://server/a/journal"))
+
+	var writer = NewWriteService(client)
+	writer.SetConcurrency(1)
+	writer.SetSpoolBudgets(5, 8, false)
+
+	// Writes are admitted while spooled bytes are under budget.
+	_, err := writer.Write("a/journal", []byte("foo"))
+	c.Check(err, gc.IsNil)
+	_, err = writer.Write("a/journal", []byte("bar"))
+	c.Check(err, gc.IsNil)
+	_, err = writer.Write("a/journal", []byte("baz"))
+	c.Check(err, gc.Equals, ErrWouldBlock)
+
+	// The global budget is shared across journals.
+	_, err = writer.Write("another/journal", []byte("12"))
+	c.Check(err, gc.IsNil)
+	_, err = writer.Write("another/journal", []byte("3"))
+	c.Check(err, gc.Equals, ErrWouldBlock)
+
+	c.Check(writer.spooled, gc.DeepEquals,
+		map[journal.Name]int64{"a/journal": 6, "another/journal": 2})
+	c.Check(writer.spooledTotal, gc.Equals, int64(8))
+
+	// Switch to blocking. A further write blocks until spooled writes commit.
+	writer.SetSpoolBudgets(5, 0, true)
+
+	var done = make(chan struct{})
+	go func() {
+		_, err := writer.Write("a/journal", []byte("baz"))
+		c.Check(err, gc.IsNil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		c.Error("write didn't block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	client.locationCache.Add("/another/journal", newURL("http://server/another/journal"))
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT"
+	})).Return(&http.Response{
+		StatusCode: http.StatusNoContent,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil)
+
+	writer.Start()
+	<-done
+	writer.Stop()
+
+	c.Check(writer.spooled, gc.HasLen, 0)
+	c.Check(writer.spooledTotal, gc.Equals, int64(0))
+}
+
+func (s *WriteServiceSuite) TestObserver(c *gc.C) {
+	var mockClient mockHttpClient
+
+	client, _ := NewClient("http://server")
+	client.httpClient = &mockClient
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	client.locationCache.Add("/a/journal", newURL("http://server/a/journal"))
+
+	var obv = new(recordingObserver)
+	var writer = NewWriteService(client)
+	writer.SetConcurrency(1)
+	writer.SetObserver(obv)
+
+	promise, err := writer.Write("a/journal", []byte("foo"))
+	c.Check(err, gc.IsNil)
+	_, err = writer.Write("a/journal", []byte("bar"))
+	c.Check(err, gc.IsNil)
+
+	// The first attempt fails, and is retried.
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT"
+	})).Return(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Status:     "Whoops!",
+		Body:       ioutil.NopCloser(strings.NewReader("error")),
+	}, nil).Once()
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT"
+	})).Return(&http.Response{
+		StatusCode: http.StatusNoContent,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil).Once()
+
+	writer.Start()
+	<-promise.Ready
+	writer.Stop()
+
+	c.Check(obv.spooled, gc.DeepEquals, []int64{3, 3, -6})
+	c.Check(obv.retries, gc.Equals, 1)
+	c.Check(obv.committed, gc.Equals, int64(6))
+}
+
+func (s *WriteServiceSuite) TestJournalMetrics(c *gc.C) {
+	var mockClient mockHttpClient
+
+	client, _ := NewClient("http://server")
+	client.httpClient = &mockClient
+	client.locationCache.Add("/metrics/journal", newURL("http://server/metrics/journal"))
+
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT"
+	})).Return(&http.Response{
+		StatusCode: http.StatusNoContent,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil)
+
+	var registry = prometheus.NewRegistry()
+	registry.MustRegister(metrics.GazetteWriteJournalBytesTotal,
+		metrics.GazetteWriteJournalSpooledBytes)
+
+	// labeled returns the metric names having a "metrics/journal" label.
+	var labeled = func() (out []string) {
+		var families, err = registry.Gather()
+		c.Assert(err, gc.IsNil)
+
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetValue() == "metrics/journal" {
+						out = append(out, family.GetName())
+					}
+				}
+			}
+		}
+		return
+	}
+	var write = func(writer *WriteService) {
+		writer.SetConcurrency(1)
+
+		promise, err := writer.Write("metrics/journal", []byte("foo"))
+		c.Check(err, gc.IsNil)
+
+		writer.Start()
+		<-promise.Ready
+		writer.Stop()
+	}
+
+	// By default, journal-labeled metrics aren't exported.
+	write(NewWriteService(client))
+	c.Check(labeled(), gc.HasLen, 0)
+
+	// When enabled, they are. The spooled bytes of the journal are dropped
+	// once its spool drains.
+	var writer = NewWriteService(client)
+	writer.SetJournalMetrics(true)
+	write(writer)
+	c.Check(labeled(), gc.DeepEquals, []string{metrics.GazetteWriteJournalBytesTotalKey})
+}
+
+func (s *WriteServiceSuite) TestInvalidContentIsNotRetried(c *gc.C) {
+	var mockClient mockHttpClient
+
+	client, _ := NewClient("http://server")
+	client.httpClient = &mockClient
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	client.locationCache.Add("/a/journal", newURL("http://server/a/journal"))
+
+	var writer = NewWriteService(client)
+	writer.SetConcurrency(1)
+
+	promise, err := writer.Write("a/journal", []byte("foo"))
+	c.Check(err, gc.IsNil)
+
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT"
+	})).Return(&http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Body:       ioutil.NopCloser(strings.NewReader("invalid append content")),
+	}, nil).Once()
+
+	writer.Start()
+	<-promise.Ready
+	writer.Stop()
+
+	c.Check(promise.Error, gc.Equals, journal.ErrInvalidContent)
+	mockClient.AssertExpectations(c)
+}
+
+func (s *WriteServiceSuite) TestFailedDependencyFailsDependents(c *gc.C) {
+	var mockClient mockHttpClient
+
+	client, _ := NewClient("http://server")
+	client.httpClient = &mockClient
+	client.locationCache.Add("/a/journal", newURL("http://server/a/journal"))
+	client.locationCache.Add("/another/journal", newURL("http://server/another/journal"))
+
+	var writer = NewWriteService(client)
+	writer.SetConcurrency(2)
+
+	dataAA, err := writer.Write("a/journal", []byte("data"))
+	c.Check(err, gc.IsNil)
+	checkpointAA, err := writer.WriteAfter("another/journal", []byte("checkpoint"), dataAA)
+	c.Check(err, gc.IsNil)
+
+	// The dependency is rejected. Expect its dependent is never attempted.
+	mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+		return request.Method == "PUT" && request.URL.Path == "/a/journal"
+	})).Return(&http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Body:       ioutil.NopCloser(strings.NewReader("invalid append content")),
+	}, nil).Once()
+
+	writer.Start()
+	<-checkpointAA.Ready
+	writer.Stop()
+
+	c.Check(dataAA.Error, gc.Equals, journal.ErrInvalidContent)
+	c.Check(checkpointAA.Error, gc.Equals, ErrDependencyFailed)
+	mockClient.AssertExpectations(c)
+}
+
+func (s *WriteServiceSuite) TestCoalescingAndFlush(c *gc.C) {
+	var mockClient mockHttpClient
+
+	client, _ := NewClient("http://server")
+	client.httpClient = &mockClient
+	client.locationCache.Add("/a/journal", newURL("http://server/a/journal"))
+
+	var writer = NewWriteService(client)
+	writer.SetConcurrency(1)
+	writer.SetCoalescingDelay(time.Hour) // Writes dispatch only if flushed.
+
+	var expectPut = func(expect string) {
+		mockClient.On("Do", mock.MatchedBy(func(request *http.Request) bool {
+			return request.Method == "PUT"
+		})).Return(&http.Response{
+			StatusCode: http.StatusNoContent,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}, nil).Run(func(args mock.Arguments) {
+			content, _ := ioutil.ReadAll(args[0].(*http.Request).Body)
+			c.Check(string(content), gc.Equals, expect)
+		}).Once()
+	}
+	writer.Start()
+
+	// Writes continue to coalesce after the service loop has dequeued them.
+	foo, err := writer.Write("a/journal", []byte("foo"))
+	c.Check(err, gc.IsNil)
+	for len(writer.writeQueue[0]) != 0 {
+		runtime.Gosched() // Await the service loop's dequeue of |foo|.
+	}
+	_, err = writer.Write("a/journal", []byte("bar"))
+	c.Check(err, gc.IsNil)
+
+	select {
+	case <-foo.Ready:
+		c.Error("unexpected dispatch before flush")
+	default:
+	}
+
+	expectPut("foobar")
+	c.Check(writer.Flush("a/journal"), gc.Equals, foo)
+	<-foo.Ready
+
+	// Nothing remains to flush.
+	c.Check(writer.Flush("a/journal"), gc.IsNil)
+
+	// WriteSync returns only after commit.
+	expectPut("baz")
+	baz, err := writer.WriteSync("a/journal", []byte("baz"))
+	c.Check(err, gc.IsNil)
+
+	select {
+	case <-baz.Ready:
+	default:
+		c.Error("expected WriteSync to return a committed write")
+	}
+
+	// Stop flushes pending writes.
+	expectPut("final")
+	_, err = writer.Write("a/journal", []byte("final"))
+	c.Check(err, gc.IsNil)
+
+	writer.Stop()
+	mockClient.AssertExpectations(c)
+}
+
 var _ = gc.Suite(&WriteServiceSuite{})
+
+// recordingObserver is a WriteObserver which records observed events.
+type recordingObserver struct {
+	mu        sync.Mutex
+	spooled   []int64
+	retries   int
+	committed int64
+}
+
+func (o *recordingObserver) ObserveSpooled(name journal.Name, bytes int64) {
+	o.mu.Lock()
+	o.spooled = append(o.spooled, bytes)
+	o.mu.Unlock()
+}
+
+func (o *recordingObserver) ObserveRetry(name journal.Name, err error) {
+	o.mu.Lock()
+	o.retries++
+	o.mu.Unlock()
+}
+
+func (o *recordingObserver) ObserveCommit(name journal.Name, bytes int64, latency time.Duration) {
+	o.mu.Lock()
+	o.committed += bytes
+	o.mu.Unlock()
+}
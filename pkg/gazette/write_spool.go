@@ -0,0 +1,142 @@
+package gazette
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+// Durable spools are named as "<sequence>-<offset>-<escaped journal>", where
+// sequence orders spools by creation, and offset is the committed length of
+// spool content. As with journal.Spool, commits are made atomic by renaming
+// the spool file to reflect its new offset. Content beyond the offset is
+// uncommitted, and is ignored upon recovery.
+func durableSpoolName(seq int64, offset int64, name journal.Name) string {
+	return fmt.Sprintf("%016x-%016x-%s", seq, offset, url.QueryEscape(string(name)))
+}
+
+func parseDurableSpoolName(base string) (seq int64, offset int64, name journal.Name, err error) {
+	var fields = strings.SplitN(base, "-", 3)
+	if len(fields) != 3 {
+		err = fmt.Errorf("wrong number of fields: %s", base)
+		return
+	}
+	if seq, err = strconv.ParseInt(fields[0], 16, 64); err != nil {
+		return
+	}
+	if offset, err = strconv.ParseInt(fields[1], 16, 64); err != nil {
+		return
+	}
+	var s string
+	if s, err = url.QueryUnescape(fields[2]); err != nil {
+		return
+	}
+	name = journal.Name(s)
+	return
+}
+
+func (p *pendingWrite) durablePath(offset int64) string {
+	return filepath.Join(p.durableDir, durableSpoolName(p.seq, offset, p.journal))
+}
+
+// newDurableWrite creates a named spool of |name| within |dir|.
+func newDurableWrite(dir string, seq int64, name journal.Name) (*pendingWrite, error) {
+	var write = &pendingWrite{
+		journal:    name,
+		durableDir: dir,
+		seq:        seq,
+	}
+	var err error
+	if write.file, err = os.OpenFile(write.durablePath(0),
+		os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+		return nil, err
+	} else if err = syncDir(dir); err != nil {
+		write.file.Close()
+		return nil, err
+	}
+	return write, nil
+}
+
+// commitDurable flushes spooled content through |offset| to disk, and records
+// |offset| as committed.
+func (p *pendingWrite) commitDurable(offset int64) error {
+	if err := p.file.Sync(); err != nil {
+		return err
+	} else if err = os.Rename(p.durablePath(p.offset), p.durablePath(offset)); err != nil {
+		return err
+	}
+	return syncDir(p.durableDir)
+}
+
+// releaseDurableWrite closes and removes the spool of a committed write.
+func releaseDurableWrite(p *pendingWrite) error {
+	if err := p.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(p.durablePath(p.offset))
+}
+
+// recoverDurableWrites returns pendingWrites of spools in |dir| which were
+// never acknowledged by a broker (eg, due to a process crash), in the order
+// in which they were created.
+func recoverDurableWrites(dir string) ([]*pendingWrite, error) {
+	// ReadDir returns entries sorted by name, and therefore by sequence.
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*pendingWrite
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		seq, offset, name, err := parseDurableSpoolName(info.Name())
+		if err != nil {
+			log.WithFields(log.Fields{"path": info.Name(), "err": err}).
+				Warn("ignoring unrecognized file in write spool directory")
+			continue
+		}
+		var write = &pendingWrite{
+			journal:    name,
+			offset:     offset,
+			started:    time.Now(),
+			result:     &journal.AsyncAppend{Ready: make(chan struct{})},
+			durableDir: dir,
+			seq:        seq,
+		}
+		if offset == 0 {
+			// Spool was created, but no write ever committed to it.
+			if err = os.Remove(write.durablePath(0)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if write.file, err = os.OpenFile(write.durablePath(offset), os.O_RDWR, 0); err != nil {
+			return nil, err
+		}
+		out = append(out, write)
+	}
+	return out, nil
+}
+
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
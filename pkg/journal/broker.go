@@ -34,6 +34,8 @@ type BrokerConfig struct {
 	WriteHead int64
 	// Number of bytes written since the last spool roll.
 	writtenSinceRoll int64
+	// If set, the update only requests a spool roll, and other fields are ignored.
+	roll bool
 }
 
 // Broker is responsible for scattering journal writes to each replica, i.e.,
@@ -75,6 +77,14 @@ func (b *Broker) UpdateConfig(config BrokerConfig) {
 	b.configUpdates <- config
 }
 
+// Roll requests that the next brokered transaction begin new spools at each
+// replica, causing current spools to be persisted. The roll is ordered with
+// respect to appends: it applies to transactions of appends submitted after
+// the call.
+func (b *Broker) Roll() {
+	b.configUpdates <- BrokerConfig{roll: true}
+}
+
 // Stop shuts down the broker. It blocks until all pending config updates and
 // appends are handled.
 func (b *Broker) Stop() {
@@ -126,6 +136,11 @@ func (b *Broker) loop() {
 }
 
 func (b *Broker) onConfigUpdate(config BrokerConfig) {
+	if config.roll {
+		log.WithField("journal", b.journal).Info("rolling spools")
+		b.config.writtenSinceRoll = 0
+		return
+	}
 	log.WithFields(log.Fields{"config": config, "journal": b.journal}).
 		Debug("updated config")
 
@@ -204,12 +219,13 @@ func (b *Broker) phaseTwo(writers []WriteCommitter, op AppendOp) error {
 
 	var commitDelta int64
 	var readErr, writeErr error
-	var buf = make([]byte, 32*1024) // io.Copy's buffer size.
+	var buf = GetCopyBuffer()
+	defer PutCopyBuffer(buf)
 
 	// Consume waiting AppendOps, streaming them to writers.
 	for {
 		var readSize int64
-		readSize, readErr, writeErr = streamToWriters(writers, op.Content, buf)
+		readSize, readErr, writeErr = streamToWriters(writers, op.Content, *buf)
 
 		if readErr != nil {
 			op.Result <- AppendResult{Error: readErr}
@@ -92,6 +92,38 @@ func (s *BrokerSuite) TestBasicFixture(c *gc.C) {
 	c.Check(s.broker.config.writtenSinceRoll, gc.Equals, int64(20))
 }
 
+func (s *BrokerSuite) TestRoll(c *gc.C) {
+	s.broker.StartServingOps(12345)
+	s.serveReplicaWriters(c)
+
+	c.Check(<-s.appendResults, gc.DeepEquals, AppendResult{WriteHead: int64(12365)})
+	c.Check(<-s.appendResults, gc.DeepEquals, AppendResult{WriteHead: int64(12365)})
+
+	// Request a roll. Expect the next transaction begins new spools, though
+	// content has been written since the last roll.
+	s.broker.Roll()
+	s.broker.Append(AppendOp{
+		AppendArgs: AppendArgs{
+			Content: bytes.NewBufferString("write three"),
+			Context: context.Background(),
+		},
+		Result: s.appendResults,
+	})
+
+	var ops = [...]ReplicateOp{
+		<-s.replicateOps, <-s.replicateOps, <-s.replicateOps}
+	for i, op := range ops {
+		c.Check(op.NewSpool, gc.Equals, true)
+		c.Check(op.WriteHead, gc.Equals, int64(12365))
+
+		op.Result <- ReplicateResult{Writer: s.replicator[i]}
+	}
+	for _ = range s.replicator {
+		<-s.committed
+	}
+	c.Check(<-s.appendResults, gc.DeepEquals, AppendResult{WriteHead: int64(12376)})
+}
+
 func (s *BrokerSuite) TestSomeCommitErrorsHandling(c *gc.C) {
 	s.replicator[1].commitErr = errors.New("error!")
 	s.replicator[2].commitErr = errors.New("error!")
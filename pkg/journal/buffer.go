@@ -0,0 +1,40 @@
+package journal
+
+import (
+	"io"
+	"sync"
+)
+
+// Size of pooled buffers, matching that used by io.Copy.
+const copyBufferSize = 32 * 1024
+
+// copyBufferPool pools buffers used to stream content through the broker.
+// Pointers are pooled, as a slice would be allocated when boxed by Put.
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		var b = make([]byte, copyBufferSize)
+		return &b
+	},
+}
+
+// GetCopyBuffer returns a pooled buffer for streaming journal content (eg,
+// of an append, as it's replicated and spooled). The caller owns the buffer
+// until it's returned by PutCopyBuffer, after which it must not be referenced.
+// Note that io.Writer implementations may not retain written buffers, so a
+// buffer passed only to Write calls may be returned once they complete.
+func GetCopyBuffer() *[]byte {
+	return copyBufferPool.Get().(*[]byte)
+}
+
+// PutCopyBuffer returns a buffer obtained from GetCopyBuffer to the pool.
+func PutCopyBuffer(b *[]byte) {
+	copyBufferPool.Put(b)
+}
+
+// CopyPooled is io.Copy, using a pooled buffer rather than allocating one.
+func CopyPooled(dst io.Writer, src io.Reader) (int64, error) {
+	var b = GetCopyBuffer()
+	defer PutCopyBuffer(b)
+
+	return io.CopyBuffer(dst, src, *b)
+}
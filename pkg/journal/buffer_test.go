@@ -0,0 +1,30 @@
+package journal
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing/iotest"
+
+	gc "github.com/go-check/check"
+)
+
+type BufferSuite struct{}
+
+func (s *BufferSuite) TestCopyPooled(c *gc.C) {
+	var content = strings.Repeat("0123456789", 10000) // Spans several buffers.
+	var dst bytes.Buffer
+
+	// Wrappers hide WriterTo and ReaderFrom, forcing use of the buffer.
+	var n, err = CopyPooled(struct{ io.Writer }{&dst},
+		iotest.HalfReader(strings.NewReader(content)))
+	c.Check(err, gc.IsNil)
+	c.Check(n, gc.Equals, int64(len(content)))
+	c.Check(dst.String(), gc.Equals, content)
+
+	var b = GetCopyBuffer()
+	c.Check(*b, gc.HasLen, copyBufferSize)
+	PutCopyBuffer(b)
+}
+
+var _ = gc.Suite(&BufferSuite{})
@@ -93,11 +93,22 @@ func ParseFragment(journal Name, contentName string) (Fragment, error) {
 	return r, err
 }
 
+// ParseContentPath parses a Fragment from its |contentPath|, which is the
+// inverse of Fragment.ContentPath.
+func ParseContentPath(contentPath string) (Fragment, error) {
+	var ind = strings.LastIndexByte(contentPath, '/')
+	if ind <= 0 {
+		return Fragment{}, errors.New("expected journal name")
+	}
+	return ParseFragment(Name(contentPath[:ind]), contentPath[ind+1:])
+}
+
 // NewWalkFuncAdapter returns a filepath.WalkFunc which parses encountered files
 // as Fragments, and passes each to the provided |callback|. Prefix |rewrites| may
 // be included, as pairs of "from", "to" prefixes which are applied in order. For
 // example, NewWalkFuncAdapter(cb, "/from/", "/foo/to/", "/foo/", "/") would rewrite
-// path "/from/bar" => "/to/bar".
+// path "/from/bar" => "/to/bar". Files which don't parse as Fragments, such as
+// top-level files having no journal name, are logged and skipped.
 func NewWalkFuncAdapter(callback func(Fragment) error, rewrites ...string) filepath.WalkFunc {
 	if len(rewrites)%2 != 0 {
 		panic(fmt.Sprintf("invalid odd-length rewrites: %#v", rewrites))
@@ -116,7 +127,7 @@ func NewWalkFuncAdapter(callback func(Fragment) error, rewrites ...string) filep
 			}
 		}
 
-		fragment, err := ParseFragment(Name(path.Dir(fpath)), path.Base(fpath))
+		fragment, err := ParseContentPath(fpath)
 		if err != nil {
 			log.WithFields(log.Fields{"path": fpath, "err": err}).Warning("parsing fragment")
 			return nil
@@ -72,6 +72,26 @@ func (s *FragmentSuite) TestParsing(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "wrong format")
 }
 
+func (s *FragmentSuite) TestContentPathParsing(c *gc.C) {
+	var fragment = Fragment{
+		Journal: "a/journal/name",
+		Begin:   1234567890,
+		End:     math.MaxInt64,
+		Sum: [...]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10,
+			11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+	}
+	parsed, err := ParseContentPath(fragment.ContentPath())
+	c.Check(err, gc.IsNil)
+	c.Check(parsed, gc.DeepEquals, fragment)
+
+	_, err = ParseContentPath(fragment.ContentName())
+	c.Check(err, gc.ErrorMatches, "expected journal name")
+	_, err = ParseContentPath("/" + fragment.ContentName())
+	c.Check(err, gc.ErrorMatches, "expected journal name")
+	_, err = ParseContentPath("a/journal/name/1-2")
+	c.Check(err, gc.ErrorMatches, "wrong format")
+}
+
 func (s *FragmentSuite) TestPathWalkFuncAdapater(c *gc.C) {
 	var out []Fragment
 
@@ -100,6 +120,11 @@ func (s *FragmentSuite) TestPathWalkFuncAdapater(c *gc.C) {
 	c.Check(f("path/"+expect.ContentName(), mockFinfo{isDir: true, size: 123}, nil), gc.IsNil)
 	// As are zero-length fragments.
 	c.Check(f("path/"+expect.ContentName(), mockFinfo{size: 0}, nil), gc.IsNil)
+	// Files which don't parse as fragments are skipped, without aborting the
+	// walk. This includes top-level files, which have no journal name.
+	c.Check(f("path/not-a-fragment", mockFinfo{size: 123}, nil), gc.IsNil)
+	c.Check(f(expect.ContentName(), mockFinfo{size: 123}, nil), gc.IsNil)
+	c.Check(f("/strip-prefix/"+expect.ContentName(), mockFinfo{size: 123}, nil), gc.IsNil)
 	// And errors are passed through.
 	c.Check(f("path/"+expect.ContentName(), mockFinfo{size: 123}, errors.New("err!")), gc.ErrorMatches, "err!")
 
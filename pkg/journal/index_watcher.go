@@ -1,14 +1,59 @@
 package journal
 
 import (
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/LiveRamp/gazette/pkg/cloudstore"
+	"github.com/LiveRamp/gazette/pkg/metrics"
 )
 
-const indexWatcherPeriod = 5 * time.Minute
+const (
+	indexWatcherPeriod = 5 * time.Minute
+	// Default minimum interval between requested refreshes of an IndexWatcher,
+	// bounding the listing load of reads which repeatedly request them.
+	defaultIndexWatcherMinRefreshInterval = 10 * time.Second
+)
+
+// IndexRefreshLimiter bounds the number of IndexWatchers which may list the
+// fragment store at once. Further refreshes wait for a running one to
+// complete, rather than all watchers of a broker listing together (eg, on
+// startup). A single IndexRefreshLimiter is typically shared by all Replicas
+// of a broker.
+type IndexRefreshLimiter struct {
+	limit   int
+	running int
+	mu      sync.Mutex
+	cond    *sync.Cond
+}
+
+// NewIndexRefreshLimiter returns an IndexRefreshLimiter which admits |limit|
+// concurrent refreshes. Zero is unbounded.
+func NewIndexRefreshLimiter(limit int) *IndexRefreshLimiter {
+	var l = &IndexRefreshLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until a refresh may begin.
+func (l *IndexRefreshLimiter) acquire() {
+	l.mu.Lock()
+	for l.limit != 0 && l.running >= l.limit {
+		l.cond.Wait()
+	}
+	l.running++
+	l.mu.Unlock()
+}
+
+// release signals that a refresh has completed.
+func (l *IndexRefreshLimiter) release() {
+	l.mu.Lock()
+	l.running--
+	l.cond.Signal()
+	l.mu.Unlock()
+}
 
 // IndexWatcher monitors a journal's storage location in the cloud filesystem
 // for new fragments, by performing periodic directory listings. When new
@@ -22,6 +67,14 @@ type IndexWatcher struct {
 
 	// Channel into which discovered fragments are produced.
 	updates chan<- Fragment
+	// Signals a requested refresh.
+	refresh chan struct{}
+	// Time of the last successful refresh.
+	lastRefresh time.Time
+	// Bounds concurrent refreshes, if non-nil.
+	limiter *IndexRefreshLimiter
+	// Minimum interval between requested refreshes.
+	minRefreshInterval time.Duration
 
 	stop        chan struct{}
 	initialLoad chan struct{}
@@ -34,11 +87,29 @@ func NewIndexWatcher(journal Name, cfs cloudstore.FileSystem,
 		journal:     journal,
 		cfs:         cfs,
 		updates:     updates,
+		refresh:     make(chan struct{}, 1),
 		stop:        make(chan struct{}),
 		initialLoad: make(chan struct{}),
+
+		minRefreshInterval: defaultIndexWatcherMinRefreshInterval,
 	}
 }
 
+// SetMinRefreshInterval sets the minimum interval between refreshes requested
+// via Refresh. It must be called before StartWatchingIndex.
+func (w *IndexWatcher) SetMinRefreshInterval(interval time.Duration) *IndexWatcher {
+	w.minRefreshInterval = interval
+	return w
+}
+
+// SetRefreshLimiter sets an IndexRefreshLimiter which bounds concurrent
+// refreshes of this and other IndexWatchers sharing it. It must be called
+// before StartWatchingIndex.
+func (w *IndexWatcher) SetRefreshLimiter(limiter *IndexRefreshLimiter) *IndexWatcher {
+	w.limiter = limiter
+	return w
+}
+
 func (w *IndexWatcher) StartWatchingIndex() *IndexWatcher {
 	go w.loop()
 	return w
@@ -48,6 +119,17 @@ func (w *IndexWatcher) WaitForInitialLoad() {
 	<-w.initialLoad
 }
 
+// Refresh requests a refresh of the index ahead of the next periodic one. For
+// example, a Tail requests a refresh when a read finds a gap in its index,
+// which may be covered by a stored fragment not yet listed. Requests are
+// coalesced, and refreshes are rate-limited.
+func (w *IndexWatcher) Refresh() {
+	select {
+	case w.refresh <- struct{}{}:
+	default: // A refresh is already pending.
+	}
+}
+
 func (w *IndexWatcher) Stop() {
 	w.stop <- struct{}{}
 	<-w.stop // Blocks until loop() exits.
@@ -66,8 +148,17 @@ func (w *IndexWatcher) loop() {
 			signal = nil
 		}
 
+		var refreshed = time.Now()
+
 		select {
 		case <-ticker.C:
+		case <-w.refresh:
+			// Wait out the minimum interval since the last refresh.
+			select {
+			case <-time.After(w.minRefreshInterval - time.Since(refreshed)):
+			case <-w.stop:
+				done = true
+			}
 		case <-w.stop:
 			done = true
 		}
@@ -80,8 +171,29 @@ func (w *IndexWatcher) loop() {
 }
 
 func (w *IndexWatcher) onRefresh() error {
-	return w.cfs.Walk(w.journal.String()+"/", NewWalkFuncAdapter(func(fragment Fragment) error {
+	if w.limiter != nil {
+		w.limiter.acquire()
+	}
+
+	var started = time.Now()
+	var err = w.cfs.Walk(w.journal.String()+"/", NewWalkFuncAdapter(func(fragment Fragment) error {
 		w.updates <- fragment
 		return nil
 	}))
+
+	if w.limiter != nil {
+		w.limiter.release()
+	}
+
+	metrics.IndexRefreshDurationSeconds.Observe(time.Since(started).Seconds())
+
+	if err != nil {
+		metrics.IndexRefreshFailuresTotal.Inc()
+		return err
+	}
+	if !w.lastRefresh.IsZero() {
+		metrics.IndexRefreshIntervalSeconds.Observe(started.Sub(w.lastRefresh).Seconds())
+	}
+	w.lastRefresh = started
+	return nil
 }
@@ -0,0 +1,74 @@
+package journal
+
+import (
+	"os"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/cloudstore"
+)
+
+type IndexWatcherSuite struct{}
+
+func (s *IndexWatcherSuite) TestRefresh(c *gc.C) {
+	var cfs = cloudstore.NewTmpFileSystem()
+	defer cfs.Close()
+	c.Assert(cfs.MkdirAll("a/journal", 0750), gc.IsNil)
+
+	var updates = make(chan Fragment, 10)
+	var watcher = NewIndexWatcher("a/journal", cfs, updates).
+		SetMinRefreshInterval(0).StartWatchingIndex()
+	watcher.WaitForInitialLoad()
+	c.Check(updates, gc.HasLen, 0)
+
+	// A fragment is persisted, and a refresh requested. It's indexed without
+	// awaiting the next periodic refresh.
+	var fragment = Fragment{Journal: "a/journal", Begin: 0, End: 100}
+	var w, err = cfs.OpenFile(fragment.ContentPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	c.Assert(err, gc.IsNil)
+	_, err = w.Write(make([]byte, 100))
+	c.Assert(err, gc.IsNil)
+	c.Assert(w.Close(), gc.IsNil)
+
+	watcher.Refresh()
+	watcher.Refresh() // Coalesced with the prior request.
+
+	var indexed = <-updates
+	c.Check(indexed.Journal, gc.Equals, fragment.Journal)
+	c.Check(indexed.Begin, gc.Equals, fragment.Begin)
+	c.Check(indexed.End, gc.Equals, fragment.End)
+
+	watcher.Stop()
+}
+
+func (s *IndexWatcherSuite) TestRefreshLimiter(c *gc.C) {
+	var limiter = NewIndexRefreshLimiter(2)
+	limiter.acquire()
+	limiter.acquire()
+
+	// A third refresh waits for a running one to complete.
+	var acquired = make(chan struct{})
+	go func() {
+		limiter.acquire()
+		close(acquired)
+	}()
+
+	limiter.mu.Lock()
+	c.Check(limiter.running, gc.Equals, 2)
+	limiter.mu.Unlock()
+
+	limiter.release()
+	<-acquired
+
+	limiter.mu.Lock()
+	c.Check(limiter.running, gc.Equals, 2)
+	limiter.mu.Unlock()
+
+	// A zero limit is unbounded.
+	limiter = NewIndexRefreshLimiter(0)
+	for i := 0; i != 100; i++ {
+		limiter.acquire()
+	}
+}
+
+var _ = gc.Suite(&IndexWatcherSuite{})
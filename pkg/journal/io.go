@@ -3,7 +3,10 @@ package journal
 import (
 	"bufio"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
+	"hash"
 	"io"
 	"io/ioutil"
 	"time"
@@ -92,6 +95,7 @@ func NewRetryReaderContext(ctx context.Context, mark Mark, getter Getter) *Retry
 // a non-nil error in the following cases:
 //  * If the RetryReader context is cancelled.
 //  * If Blocking is false, and ErrNotYetAvailable is returned by the broker.
+//  * If ErrSumMismatch is returned by a read of verified fragment content.
 // All other errors are retried.
 func (rr *RetryReader) Read(p []byte) (n int, err error) {
 	for i := 0; true; i++ {
@@ -108,6 +112,9 @@ func (rr *RetryReader) Read(p []byte) (n int, err error) {
 					// This RetryReader was in non-blocking mode but has since switched
 					// to blocking. Ignore this error and retry as a blocking operation.
 				}
+			case ErrSumMismatch:
+				// Retrying would re-read the same corrupt fragment.
+				return
 			case io.EOF, context.DeadlineExceeded, context.Canceled:
 				// Suppress logging for expected errors.
 			case ErrNotFound:
@@ -238,3 +245,64 @@ func backoff(attempt int) time.Duration {
 		return 5 * time.Second
 	}
 }
+
+// ErrSumMismatch is returned by a SumVerifyingReader if fragment content
+// doesn't match the fragment's SHA1 sum.
+var ErrSumMismatch = errors.New("fragment content doesn't match its SHA1 sum")
+
+// SumVerifyingReader wraps a ReadCloser of Fragment content, beginning at
+// the Fragment's Begin offset, and verifies the content against the
+// Fragment's SHA1 sum. Verification happens upon reading through Fragment.End.
+// On a mismatch, the bytes of that final Read are withheld and ErrSumMismatch
+// is returned instead. Bytes beyond Fragment.End are passed through without
+// verification.
+type SumVerifyingReader struct {
+	io.ReadCloser
+	Fragment Fragment
+
+	offset int64
+	sha1   hash.Hash
+}
+
+func NewSumVerifyingReader(fragment Fragment, r io.ReadCloser) *SumVerifyingReader {
+	return &SumVerifyingReader{
+		ReadCloser: r,
+		Fragment:   fragment,
+		offset:     fragment.Begin,
+		sha1:       sha1.New(),
+	}
+}
+
+func (r *SumVerifyingReader) Read(p []byte) (int, error) {
+	if r.sha1 == nil {
+		return r.ReadCloser.Read(p) // Already verified.
+	}
+	var n, err = r.ReadCloser.Read(p)
+
+	var b = p[:n]
+	if rem := r.Fragment.End - r.offset; int64(len(b)) > rem {
+		b = b[:rem]
+	}
+	r.sha1.Write(b)
+	r.offset += int64(len(b))
+
+	if r.offset != r.Fragment.End {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return n, err
+	}
+
+	var sum [sha1.Size]byte
+	copy(sum[:], r.sha1.Sum(nil))
+	r.sha1 = nil
+
+	if sum != r.Fragment.Sum {
+		log.WithFields(log.Fields{
+			"fragment": r.Fragment.ContentName(),
+			"sum":      hex.EncodeToString(sum[:]),
+		}).Error("fragment SHA1 mismatch")
+		return 0, ErrSumMismatch
+	}
+	return n, err
+}
@@ -3,6 +3,7 @@ package journal
 import (
 	"bufio"
 	"context"
+	"crypto/sha1"
 	"io"
 	"io/ioutil"
 	"strings"
@@ -287,6 +288,47 @@ func (s *IOSuite) TestBufferedSeekAdjustment(c *gc.C) {
 	c.Check(rr.ReadCloser, gc.IsNil)
 }
 
+func (s *IOSuite) TestSumVerifyingReader(c *gc.C) {
+	var fragment = Fragment{Journal: "a/journal", Begin: 100, End: 110,
+		Sum: sha1.Sum([]byte("0123456789"))}
+
+	// Valid content is verified. Content beyond the fragment is passed through.
+	var r = NewSumVerifyingReader(fragment,
+		ioutil.NopCloser(iotest.HalfReader(strings.NewReader("0123456789XYZ"))))
+	var b, err = ioutil.ReadAll(r)
+	c.Check(err, gc.IsNil)
+	c.Check(string(b), gc.Equals, "0123456789XYZ")
+
+	// Bytes of the Read completing corrupt content are withheld.
+	r = NewSumVerifyingReader(fragment,
+		ioutil.NopCloser(iotest.OneByteReader(strings.NewReader("0123456780"))))
+	b, err = ioutil.ReadAll(r)
+	c.Check(err, gc.Equals, ErrSumMismatch)
+	c.Check(string(b), gc.Equals, "012345678")
+
+	// Truncated content is an error.
+	r = NewSumVerifyingReader(fragment, ioutil.NopCloser(strings.NewReader("01234")))
+	b, err = ioutil.ReadAll(r)
+	c.Check(err, gc.Equals, io.ErrUnexpectedEOF)
+	c.Check(string(b), gc.Equals, "01234")
+
+	// A RetryReader surfaces ErrSumMismatch, rather than retrying.
+	var getter = new(MockGetter)
+	var rr = NewRetryReaderContext(context.Background(), Mark{"a/journal", 100}, getter)
+
+	getter.On("Get", ReadArgs{Journal: "a/journal", Offset: 100, Blocking: true,
+		Context: context.Background()}).
+		Return(ReadResult{Offset: 100, Fragment: fragment},
+			NewSumVerifyingReader(fragment, ioutil.NopCloser(strings.NewReader("0123456780")))).Once()
+
+	var buf [16]byte
+	n, err := rr.Read(buf[:])
+	c.Check(n, gc.Equals, 0)
+	c.Check(err, gc.Equals, ErrSumMismatch)
+	c.Check(rr.Mark.Offset, gc.Equals, int64(100))
+	getter.AssertExpectations(c)
+}
+
 type closeCh chan struct{}
 
 func (c closeCh) Close() error {
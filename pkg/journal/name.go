@@ -0,0 +1,86 @@
+package journal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Maximum length of a journal Name, in bytes.
+const maxNameLength = 512
+
+// Validate returns an error if the Name is not a well-formed, path-like
+// journal name. Names consist of one or more non-empty components separated
+// by "/", with no leading or trailing "/". Components may not be "." or "..",
+// and are composed of letters, digits, and the symbols "-_.=+%".
+func (n Name) Validate() error {
+	if n == "" {
+		return fmt.Errorf("journal name is empty")
+	} else if len(n) > maxNameLength {
+		return fmt.Errorf("journal name is too long (%d > %d bytes)", len(n), maxNameLength)
+	}
+	return validateComponents(string(n))
+}
+
+// Prefixes returns each Prefix of the Name, from least to most specific. For
+// example, "a/b/c" has Prefixes "a/" and "a/b/".
+func (n Name) Prefixes() []Prefix {
+	var out []Prefix
+	for i, r := range n {
+		if r == '/' {
+			out = append(out, Prefix(n[:i+1]))
+		}
+	}
+	return out
+}
+
+// A Prefix addresses the journals beneath a hierarchical level of journal
+// Names, such as all journals under "logs/app1/". Valid prefixes end in "/",
+// and the empty Prefix addresses all journals.
+type Prefix string
+
+func (p Prefix) String() string {
+	return string(p)
+}
+
+// Validate returns an error if the Prefix is not well-formed. A non-empty
+// Prefix must be a valid Name followed by a trailing "/".
+func (p Prefix) Validate() error {
+	if p == "" {
+		return nil
+	} else if !strings.HasSuffix(string(p), "/") {
+		return fmt.Errorf("journal prefix %q must end in '/'", p)
+	} else if err := Name(p[:len(p)-1]).Validate(); err != nil {
+		return fmt.Errorf("journal prefix %q: %s", p, err)
+	}
+	return nil
+}
+
+// Matches returns whether journal |name| is beneath the Prefix.
+func (p Prefix) Matches(name Name) bool {
+	return strings.HasPrefix(string(name), string(p))
+}
+
+// validateComponents validates each "/"-separated component of |s|.
+func validateComponents(s string) error {
+	for _, c := range strings.Split(s, "/") {
+		if c == "" {
+			return fmt.Errorf("journal name %q has an empty component", s)
+		} else if c == "." || c == ".." {
+			return fmt.Errorf("journal name %q has a relative component %q", s, c)
+		}
+		for _, r := range c {
+			if !isNameRune(r) {
+				return fmt.Errorf("journal name %q has invalid character %q", s, r)
+			}
+		}
+	}
+	return nil
+}
+
+func isNameRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("-_.=+%", r)
+}
@@ -0,0 +1,64 @@
+package journal
+
+import (
+	"strings"
+
+	gc "github.com/go-check/check"
+)
+
+type NameSuite struct{}
+
+func (s *NameSuite) TestNameValidation(c *gc.C) {
+	for _, n := range []Name{
+		"a",
+		"company-journals/interesting-topic/part-1234",
+		"a/b.c/d_e=f+g%20",
+	} {
+		c.Check(n.Validate(), gc.IsNil)
+	}
+
+	for _, tc := range []struct {
+		name   Name
+		expect string
+	}{
+		{"", "journal name is empty"},
+		{Name(strings.Repeat("a", 513)), `journal name is too long \(513 > 512 bytes\)`},
+		{"/a/b", `journal name "/a/b" has an empty component`},
+		{"a/b/", `journal name "a/b/" has an empty component`},
+		{"a//b", `journal name "a//b" has an empty component`},
+		{"a/../b", `journal name "a/../b" has a relative component "\.\."`},
+		{"a/./b", `journal name "a/./b" has a relative component "\."`},
+		{"a/b c", `journal name "a/b c" has invalid character ' '`},
+		{"a/b\nc", `journal name "a/b\\nc" has invalid character '\\n'`},
+	} {
+		c.Check(tc.name.Validate(), gc.ErrorMatches, tc.expect)
+	}
+}
+
+func (s *NameSuite) TestPrefixValidation(c *gc.C) {
+	for _, p := range []Prefix{"", "a/", "logs/app1/"} {
+		c.Check(p.Validate(), gc.IsNil)
+	}
+	c.Check(Prefix("logs/app1").Validate(), gc.ErrorMatches,
+		`journal prefix "logs/app1" must end in '/'`)
+	c.Check(Prefix("logs//").Validate(), gc.ErrorMatches,
+		`journal prefix "logs//": journal name "logs/" has an empty component`)
+	c.Check(Prefix("/").Validate(), gc.ErrorMatches,
+		`journal prefix "/": journal name is empty`)
+}
+
+func (s *NameSuite) TestPrefixMatching(c *gc.C) {
+	var name = Name("logs/app1/part-000")
+
+	c.Check(name.Prefixes(), gc.DeepEquals, []Prefix{"logs/", "logs/app1/"})
+	c.Check(Name("journal").Prefixes(), gc.IsNil)
+
+	for _, p := range append(name.Prefixes(), "") {
+		c.Check(p.Matches(name), gc.Equals, true)
+	}
+	c.Check(Prefix("logs/app").Matches(name), gc.Equals, true) // Not a valid Prefix.
+	c.Check(Prefix("logs/app2/").Matches(name), gc.Equals, false)
+	c.Check(Prefix("logs/app1/part-000/").Matches(name), gc.Equals, false)
+}
+
+var _ = gc.Suite(&NameSuite{})
@@ -11,22 +11,32 @@ import (
 )
 
 var (
-	ErrExists            = errors.New("journal exists")
-	ErrNotBroker         = errors.New("not journal broker")
-	ErrNotFound          = errors.New("journal not found")
-	ErrNotReplica        = errors.New("not journal replica")
-	ErrNotYetAvailable   = errors.New("offset not yet available")
-	ErrReplicationFailed = errors.New("replication failed")
-	ErrWrongRouteToken   = errors.New("wrong route token")
-	ErrWrongWriteHead    = errors.New("wrong write head")
+	ErrAppendSumMismatch   = errors.New("append content doesn't match its SHA1 sum")
+	ErrExists              = errors.New("journal exists")
+	ErrIdleTimeout         = errors.New("read idle timeout")
+	ErrInsufficientStorage = errors.New("insufficient spool storage")
+	ErrInvalidContent      = errors.New("invalid append content")
+	ErrNotBroker           = errors.New("not journal broker")
+	ErrNotFound            = errors.New("journal not found")
+	ErrNotReplica          = errors.New("not journal replica")
+	ErrNotYetAvailable     = errors.New("offset not yet available")
+	ErrReplicationFailed   = errors.New("replication failed")
+	ErrTooManyAppends      = errors.New("too many in-flight appends")
+	ErrWrongRouteToken     = errors.New("wrong route token")
+	ErrWrongWriteHead      = errors.New("wrong write head")
 
 	protocolErrors = []error{
+		ErrAppendSumMismatch,
 		ErrExists,
+		ErrIdleTimeout,
+		ErrInsufficientStorage,
+		ErrInvalidContent,
 		ErrNotBroker,
 		ErrNotFound,
 		ErrNotReplica,
 		ErrNotYetAvailable,
 		ErrReplicationFailed,
+		ErrTooManyAppends,
 		ErrWrongRouteToken,
 		ErrWrongWriteHead,
 	}
@@ -152,6 +162,12 @@ type AppendArgs struct {
 	Content io.Reader
 	// Context which may trace, cancel or supply a deadline for the operation.
 	Context context.Context
+	// Optional identifier of the append's producer. If set, the append is sent
+	// with a checksum of |Content|, and a broker may recognize a retry of an
+	// append which already committed (see WriteAPI.SetDedupWindow). Appends
+	// of a Producer having identical Content are assumed to be retries, so
+	// Producer should be unique to each logical append.
+	Producer string
 }
 
 func (a AppendArgs) String() string {
@@ -192,6 +208,50 @@ type AsyncAppend struct {
 	Ready chan struct{}
 }
 
+// Retryability describes how a client should respond to an operation error.
+type Retryability int
+
+const (
+	// The operation should not be retried: it will fail again.
+	NotRetryable Retryability = iota
+	// The operation may be retried against the same server, after a back-off.
+	Retryable
+	// The operation may be retried immediately, against the server indicated
+	// by the error response (eg, its Location or RouteToken). Clients
+	// generally cache the indicated location (see gazette.Client).
+	RetryAfterReroute
+)
+
+func (r Retryability) String() string {
+	switch r {
+	case NotRetryable:
+		return "NotRetryable"
+	case Retryable:
+		return "Retryable"
+	case RetryAfterReroute:
+		return "RetryAfterReroute"
+	default:
+		return fmt.Sprintf("Retryability(%d)", int(r))
+	}
+}
+
+// RetryabilityOf returns the Retryability of operation error |err|. Errors
+// other than protocol errors (eg, network errors) are Retryable.
+func RetryabilityOf(err error) Retryability {
+	switch err {
+	case ErrExists, ErrNotFound, ErrInvalidContent:
+		return NotRetryable
+	case ErrNotBroker, ErrNotReplica, ErrWrongRouteToken:
+		return RetryAfterReroute
+	default:
+		// Includes ErrNotYetAvailable, ErrReplicationFailed, ErrWrongWriteHead,
+		// ErrIdleTimeout, ErrTooManyAppends, ErrInsufficientStorage, and
+		// ErrAppendSumMismatch (the append didn't commit, and its content may
+		// have been corrupted in transit).
+		return Retryable
+	}
+}
+
 // Maps Journal protocol errors into a unique HTTP status code.
 // Other errors are mapped into http.StatusInternalServerError.
 func StatusCodeForError(err error) int {
@@ -212,6 +272,16 @@ func StatusCodeForError(err error) int {
 		return http.StatusProxyAuthRequired // 407.
 	case ErrWrongWriteHead:
 		return http.StatusPreconditionFailed // 412.
+	case ErrIdleTimeout:
+		return http.StatusRequestTimeout // 408.
+	case ErrTooManyAppends:
+		return http.StatusTooManyRequests // 429.
+	case ErrInsufficientStorage:
+		return http.StatusInsufficientStorage // 507.
+	case ErrInvalidContent:
+		return http.StatusUnprocessableEntity // 422.
+	case ErrAppendSumMismatch:
+		return http.StatusExpectationFailed // 417.
 	default:
 		return http.StatusInternalServerError // 500.
 	}
@@ -251,6 +321,16 @@ func ErrorFromResponse(response *http.Response) error {
 		return ErrWrongRouteToken
 	case http.StatusPreconditionFailed: // 412.
 		return ErrWrongWriteHead
+	case http.StatusRequestTimeout: // 408.
+		return ErrIdleTimeout
+	case http.StatusTooManyRequests: // 429.
+		return ErrTooManyAppends
+	case http.StatusInsufficientStorage: // 507.
+		return ErrInsufficientStorage
+	case http.StatusUnprocessableEntity: // 422.
+		return ErrInvalidContent
+	case http.StatusExpectationFailed: // 417.
+		return ErrAppendSumMismatch
 	default:
 		if body, err := ioutil.ReadAll(response.Body); err != nil {
 			return err
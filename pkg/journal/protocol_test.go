@@ -45,4 +45,29 @@ func (s *ProtocolSuite) TestErrorsAsStatusCodes(c *gc.C) {
 	c.Check(ErrorFromResponse(&response), gc.ErrorMatches, `error! \(body\)`)
 }
 
+func (s *ProtocolSuite) TestRetryability(c *gc.C) {
+	for err, expect := range map[error]Retryability{
+		ErrExists:              NotRetryable,
+		ErrNotFound:            NotRetryable,
+		ErrInvalidContent:      NotRetryable,
+		ErrNotBroker:           RetryAfterReroute,
+		ErrNotReplica:          RetryAfterReroute,
+		ErrWrongRouteToken:     RetryAfterReroute,
+		ErrNotYetAvailable:     Retryable,
+		ErrReplicationFailed:   Retryable,
+		ErrWrongWriteHead:      Retryable,
+		ErrIdleTimeout:         Retryable,
+		ErrTooManyAppends:      Retryable,
+		ErrInsufficientStorage: Retryable,
+		ErrAppendSumMismatch:   Retryable,
+		errors.New("other"):    Retryable,
+	} {
+		c.Check(RetryabilityOf(err), gc.Equals, expect, gc.Commentf("%v", err))
+	}
+	// Each protocol error is classified.
+	c.Check(len(protocolErrors), gc.Equals, 13)
+
+	c.Check(RetryAfterReroute.String(), gc.Equals, "RetryAfterReroute")
+}
+
 var _ = gc.Suite(&ProtocolSuite{})
@@ -1,6 +1,9 @@
 package journal
 
 import (
+	"sync"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/LiveRamp/gazette/pkg/cloudstore"
@@ -25,15 +28,33 @@ type Replica struct {
 	broker *Broker
 }
 
+// Default duration for which a listing of stored fragments, made to serve
+// reads of offsets compacted out of a Tail's index, is re-used by further
+// such reads.
+const defaultCompactedListingTTL = time.Minute
+
+// NewReplica returns a Replica of |journal|. If |maxIndexedFragments| is
+// non-zero, the Replica's index is bounded to that many fragments: when
+// exceeded, the oldest persisted fragments are compacted out of the index,
+// and reads of their offsets are instead served by listing the fragment store.
+// If non-nil, |refreshes| bounds concurrent listings of the Replica's index
+// with those of other Replicas.
 func NewReplica(journal Name, localDir string, persister FragmentPersister,
-	cfs cloudstore.FileSystem) *Replica {
+	cfs cloudstore.FileSystem, maxIndexedFragments int, refreshes *IndexRefreshLimiter) *Replica {
 
 	updates := make(chan Fragment, 1)
+	index := NewIndexWatcher(journal, cfs, updates).
+		SetRefreshLimiter(refreshes).
+		StartWatchingIndex()
+	tail := NewTail(journal, updates).
+		SetIndexCompaction(maxIndexedFragments, newFragmentLister(journal, cfs).list).
+		SetIndexRefresh(index.Refresh)
+
 	r := &Replica{
 		journal: journal,
 		updates: updates,
-		index:   NewIndexWatcher(journal, cfs, updates).StartWatchingIndex(),
-		tail:    NewTail(journal, updates).StartServingOps(),
+		index:   index,
+		tail:    tail.StartServingOps(),
 		head:    NewHead(journal, localDir, persister, updates),
 		broker:  NewBroker(journal),
 	}
@@ -54,6 +75,45 @@ func NewReplica(journal Name, localDir string, persister FragmentPersister,
 	return r
 }
 
+// fragmentLister lists the stored fragments of a journal. A listing is
+// re-used for |ttl|, so that a burst of reads of compacted
+// offsets is served by a single walk of the fragment store.
+type fragmentLister struct {
+	journal Name
+	cfs     cloudstore.FileSystem
+	// Effective constant, which is swappable for testing.
+	ttl time.Duration
+
+	mu     sync.Mutex
+	set    FragmentSet
+	listed time.Time
+}
+
+func newFragmentLister(journal Name, cfs cloudstore.FileSystem) *fragmentLister {
+	return &fragmentLister{journal: journal, cfs: cfs, ttl: defaultCompactedListingTTL}
+}
+
+// list returns the current listing, walking the fragment store if the last
+// listing has expired. Concurrent callers await a single walk.
+func (l *fragmentLister) list() (FragmentSet, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.listed.IsZero() && time.Since(l.listed) < l.ttl {
+		return l.set, nil
+	}
+	var set FragmentSet
+	var err = l.cfs.Walk(l.journal.String()+"/", NewWalkFuncAdapter(func(f Fragment) error {
+		set.Add(f)
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+	l.set, l.listed = set, time.Now()
+	return set, nil
+}
+
 func (r *Replica) Append(op AppendOp) {
 	r.broker.Append(op)
 }
@@ -87,6 +147,12 @@ func (r *Replica) StartBrokeringWithPeers(routeToken RouteToken, peers []Replica
 	r.broker.UpdateConfig(config)
 }
 
+// Roll requests that the Replica, if brokering, roll the spools of all
+// replicas with its next transaction. See Broker.Roll.
+func (r *Replica) Roll() {
+	r.broker.Roll()
+}
+
 func (r *Replica) Shutdown() {
 	log.WithField("journal", r.journal).Debug("beginning journal shutdown")
 	go func() {
@@ -0,0 +1,50 @@
+package journal
+
+import (
+	"os"
+	"time"
+
+	gc "github.com/go-check/check"
+
+	"github.com/LiveRamp/gazette/pkg/cloudstore"
+)
+
+type ReplicaSuite struct{}
+
+func (s *ReplicaSuite) TestFragmentListerCachesListing(c *gc.C) {
+	var cfs = cloudstore.NewTmpFileSystem()
+	defer cfs.Close()
+	c.Assert(cfs.MkdirAll("a/journal", 0750), gc.IsNil)
+
+	var store = func(f Fragment) {
+		var w, err = cfs.OpenFile(f.ContentPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+		c.Assert(err, gc.IsNil)
+		_, err = w.Write(make([]byte, f.Size()))
+		c.Assert(err, gc.IsNil)
+		c.Assert(w.Close(), gc.IsNil)
+	}
+	store(Fragment{Journal: "a/journal", Begin: 0, End: 100})
+
+	var lister = newFragmentLister("a/journal", cfs)
+	lister.ttl = time.Hour
+
+	set, err := lister.list()
+	c.Check(err, gc.IsNil)
+	c.Check(set, gc.HasLen, 1)
+
+	// A further stored fragment isn't listed until the listing expires.
+	store(Fragment{Journal: "a/journal", Begin: 100, End: 200})
+
+	set, err = lister.list()
+	c.Check(err, gc.IsNil)
+	c.Check(set, gc.HasLen, 1)
+
+	lister.ttl = 0
+
+	set, err = lister.list()
+	c.Check(err, gc.IsNil)
+	c.Check(set, gc.HasLen, 2)
+	c.Check(set.EndOffset(), gc.Equals, int64(200))
+}
+
+var _ = gc.Suite(&ReplicaSuite{})
@@ -5,6 +5,8 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/trace"
+
+	"github.com/LiveRamp/gazette/pkg/metrics"
 )
 
 const (
@@ -20,6 +22,14 @@ type Tail struct {
 	journal   Name
 	fragments FragmentSet
 
+	// Index compaction limit, and function which lists all stored fragments.
+	maxFragments  int
+	listFragments func() (FragmentSet, error)
+	// Offset through which fragments have been compacted out of |fragments|.
+	compactedThrough int64
+	// Requests a refresh of the index from the fragment store, if set.
+	refreshIndex func()
+
 	readOps   chan ReadOp
 	updates   <-chan Fragment
 	endOffset chan int64
@@ -46,6 +56,24 @@ func NewTail(journal Name, updates <-chan Fragment) *Tail {
 	return t
 }
 
+// SetIndexCompaction bounds the Tail's index to |maxFragments|, by compacting
+// its oldest persisted fragments. Reads of compacted offsets are served from
+// a listing of stored fragments, as returned by |list|. It must be called
+// before StartServingOps.
+func (t *Tail) SetIndexCompaction(maxFragments int, list func() (FragmentSet, error)) *Tail {
+	t.maxFragments, t.listFragments = maxFragments, list
+	return t
+}
+
+// SetIndexRefresh sets a function which is called when a read finds a gap in
+// the Tail's index, to request a refresh of the index (eg, IndexWatcher.Refresh).
+// The gap may be covered by a stored fragment which isn't yet indexed. It must
+// be called before StartServingOps, and |refresh| must not block.
+func (t *Tail) SetIndexRefresh(refresh func()) *Tail {
+	t.refreshIndex = refresh
+	return t
+}
+
 func (t *Tail) StartServingOps() *Tail {
 	go t.loop()
 	return t
@@ -100,6 +128,8 @@ func (t *Tail) loop() {
 		case t.endOffset <- t.fragments.EndOffset():
 		}
 	}
+	metrics.IndexedFragments.Sub(float64(len(t.fragments)))
+
 	close(t.endOffset) // After close(), EndOffset() will thereafter return 0.
 	log.WithField("journal", t.journal).Debug("tail loop exiting")
 	close(t.stop)
@@ -111,10 +141,37 @@ func (t *Tail) onUpdate(fragment Fragment) {
 			"tail.journal": t.journal}).Error("unexpected fragment journal")
 		return
 	}
+	if fragment.End <= t.compactedThrough {
+		return // Already compacted out of the index.
+	}
+	var size = len(t.fragments)
 	t.fragments.Add(fragment)
+	t.compact()
+	metrics.IndexedFragments.Add(float64(len(t.fragments) - size))
+
 	t.wakeBlockedReads(time.Time{})
 }
 
+// compact drops the oldest fragments of the index while it's over its limit.
+// Only persisted fragments are dropped, as only they may be listed again.
+func (t *Tail) compact() {
+	if t.maxFragments == 0 || len(t.fragments) <= t.maxFragments {
+		return
+	}
+	var n int
+	for ; len(t.fragments)-n > t.maxFragments; n++ {
+		if t.fragments[n].RemoteModTime.IsZero() {
+			break // Not yet persisted.
+		}
+		t.compactedThrough = t.fragments[n].End
+	}
+	if n != 0 {
+		// Copy to release the dropped prefix of the backing array.
+		t.fragments = append(FragmentSet(nil), t.fragments[n:]...)
+		metrics.IndexCompactedFragmentsTotal.Add(float64(n))
+	}
+}
+
 func (t *Tail) onRead(op ReadOp) {
 	if op.Journal != t.journal {
 		panic("wrong journal")
@@ -125,6 +182,11 @@ func (t *Tail) onRead(op ReadOp) {
 		op.Offset = t.fragments.EndOffset()
 	}
 
+	if op.Offset < t.compactedThrough {
+		go t.readCompacted(op, t.fragments.EndOffset())
+		return
+	}
+
 	// Attempt to find a covering fragment for the read.
 	ind := t.fragments.LongestOverlappingFragment(op.Offset)
 	if ind == len(t.fragments) {
@@ -140,7 +202,11 @@ func (t *Tail) onRead(op ReadOp) {
 		// fragment persistence vs startup of new brokers.
 		op.Offset = f.Begin
 	} else {
-		// Fragment is not remote, or is too new.
+		// Fragment is not remote, or is too new. The gap may yet be covered by a
+		// persisted fragment which we haven't listed.
+		if t.refreshIndex != nil {
+			t.refreshIndex()
+		}
 		ind = -1
 	}
 
@@ -189,3 +255,23 @@ func (t *Tail) wakeBlockedReads(when time.Time) {
 		t.onRead(op)
 	}
 }
+
+// readCompacted serves |op| of an offset which was compacted out of the index,
+// by listing stored fragments. As compacted fragments are persisted and aged,
+// a read of an offset not covered by any listed fragment skips forward to the
+// next available one.
+func (t *Tail) readCompacted(op ReadOp, writeHead int64) {
+	var set, err = t.listFragments()
+	if err != nil {
+		op.Result <- ReadResult{Error: err, Offset: op.Offset, WriteHead: writeHead}
+		return
+	}
+	var ind = set.LongestOverlappingFragment(op.Offset)
+	if ind == len(set) {
+		op.Result <- ReadResult{Error: ErrNotYetAvailable, Offset: op.Offset, WriteHead: writeHead}
+		return
+	} else if set[ind].Begin > op.Offset {
+		op.Offset = set[ind].Begin
+	}
+	op.Result <- ReadResult{Offset: op.Offset, WriteHead: writeHead, Fragment: set[ind]}
+}
@@ -2,6 +2,7 @@ package journal
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	gc "github.com/go-check/check"
@@ -339,4 +340,91 @@ func (s *TailSuite) TestEndOffsetGenerator(c *gc.C) {
 	c.Check(s.tail.EndOffset(), gc.Equals, int64(475))
 }
 
+func (s *TailSuite) TestIndexCompaction(c *gc.C) {
+	var modTime = time.Unix(1234, 0)
+	var f1 = Fragment{Journal: "a/journal", Begin: 0, End: 100, RemoteModTime: modTime}
+	var f2 = Fragment{Journal: "a/journal", Begin: 100, End: 200, RemoteModTime: modTime}
+	var f3 = Fragment{Journal: "a/journal", Begin: 200, End: 300} // Local.
+	var f4 = Fragment{Journal: "a/journal", Begin: 300, End: 400} // Local.
+
+	var stored = FragmentSet{f1, f2}
+	var listFn = func() (FragmentSet, error) { return stored, nil }
+
+	// Replace the fixture Tail with one which compacts beyond two fragments.
+	close(s.updates)
+	s.tail.Stop()
+	s.updates = make(chan Fragment)
+	s.tail = NewTail("a/journal", s.updates).SetIndexCompaction(2, listFn).StartServingOps()
+
+	for _, f := range []Fragment{f1, f2, f3} {
+		s.updates <- f
+	}
+	c.Check(s.tail.EndOffset(), gc.Equals, int64(300))
+	c.Check(s.tail.fragments, gc.DeepEquals, FragmentSet{f2, f3})
+	c.Check(s.tail.compactedThrough, gc.Equals, int64(100))
+
+	// A compacted fragment isn't re-indexed by a later index refresh.
+	s.updates <- f1
+	c.Check(s.tail.EndOffset(), gc.Equals, int64(300))
+	c.Check(s.tail.fragments, gc.DeepEquals, FragmentSet{f2, f3})
+
+	// Reads of compacted offsets are served from the store listing.
+	var results = make(chan ReadResult)
+	var read = func(offset int64) ReadResult {
+		s.tail.Read(ReadOp{
+			ReadArgs: ReadArgs{Journal: "a/journal", Offset: offset, Context: context.Background()},
+			Result:   results,
+		})
+		return <-results
+	}
+	c.Check(read(50), gc.DeepEquals, ReadResult{Offset: 50, WriteHead: 300, Fragment: f1})
+
+	// Local fragments are not compacted.
+	s.updates <- f4
+	c.Check(s.tail.EndOffset(), gc.Equals, int64(400))
+	c.Check(s.tail.fragments, gc.DeepEquals, FragmentSet{f3, f4})
+	s.updates <- Fragment{Journal: "a/journal", Begin: 400, End: 500}
+	c.Check(s.tail.EndOffset(), gc.Equals, int64(500))
+	c.Check(s.tail.fragments, gc.HasLen, 3)
+
+	// If a compacted fragment was removed from the store, reads skip forward.
+	stored = FragmentSet{f2}
+	c.Check(read(50), gc.DeepEquals, ReadResult{Offset: 100, WriteHead: 500, Fragment: f2})
+	c.Check(read(150), gc.DeepEquals, ReadResult{Offset: 150, WriteHead: 500, Fragment: f2})
+
+	// Listing errors are passed through.
+	s.tail.listFragments = func() (FragmentSet, error) { return nil, errors.New("list error") }
+	c.Check(read(50).Error, gc.ErrorMatches, "list error")
+}
+
+func (s *TailSuite) TestGapRequestsIndexRefresh(c *gc.C) {
+	var refreshes = make(chan struct{}, 10)
+
+	close(s.updates)
+	s.tail.Stop()
+	s.updates = make(chan Fragment)
+	s.tail = NewTail("a/journal", s.updates).
+		SetIndexRefresh(func() { refreshes <- struct{}{} }).StartServingOps()
+
+	// A recent fragment follows a gap at [0, 100).
+	s.updates <- Fragment{Journal: "a/journal", Begin: 100, End: 200, RemoteModTime: time.Now()}
+
+	var results = make(chan ReadResult)
+	var read = func(offset int64) ReadResult {
+		s.tail.Read(ReadOp{
+			ReadArgs: ReadArgs{Journal: "a/journal", Offset: offset, Context: context.Background()},
+			Result:   results,
+		})
+		return <-results
+	}
+	// A read of the gap requests a refresh.
+	c.Check(read(50).Error, gc.Equals, ErrNotYetAvailable)
+	c.Check(refreshes, gc.HasLen, 1)
+
+	// Reads of covered offsets, or beyond the write head, do not.
+	c.Check(read(150).Error, gc.IsNil)
+	c.Check(read(250).Error, gc.Equals, ErrNotYetAvailable)
+	c.Check(refreshes, gc.HasLen, 1)
+}
+
 var _ = gc.Suite(&TailSuite{})
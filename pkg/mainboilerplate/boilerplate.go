@@ -47,12 +47,34 @@ func Initialize() {
 	RegisterSignalHandlers()
 }
 
-// initFlags parses flags from the environment and command line, in that order.
+// initFlags parses flags from the command line, the environment, and an
+// optional configuration file, in that order of precedence. Each source sets
+// only flags not set by a prior one, so that the command line is parsed once
+// and an appending flag (eg, one which may be repeated) isn't applied twice.
 // This should be one of the first things a program does to ensure the provided
-// configuration takes effect.
+// configuration takes effect. If -dumpFlags is set, the resulting
+// configuration is written to stdout and the program exits.
 func initFlags() {
-	envflag.CommandLine.Parse()
+	var flagsFile = flag.String("flagsFile", "", "Path to a configuration file of "+
+		"\"flag = value\" lines. Environment variables and command-line flags take precedence")
+	var dumpFlags = flag.Bool("dumpFlags", false, "Write the effective "+
+		"configuration to stdout, and exit")
+
 	flag.Parse()
+	envflag.CommandLine.Parse()
+
+	if *flagsFile != "" {
+		if err := ApplyConfigFile(flag.CommandLine, *flagsFile); err != nil {
+			log.WithField("err", err).Fatal("failed to apply configuration file")
+		}
+	}
+
+	if *dumpFlags {
+		if err := DumpConfig(flag.CommandLine, os.Stdout); err != nil {
+			log.WithField("err", err).Fatal("failed to dump configuration")
+		}
+		os.Exit(0)
+	}
 }
 
 // initLog configures the logger.
@@ -0,0 +1,86 @@
+package mainboilerplate
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Flags which control configuration itself, and are neither read from nor
+// written to configuration files.
+var configFlags = map[string]bool{"flagsFile": true, "dumpFlags": true}
+
+// ApplyConfigFile sets flags of |fs| from the configuration file at |path|.
+// See ApplyConfig.
+func ApplyConfigFile(fs *flag.FlagSet, path string) error {
+	var f, err = os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = ApplyConfig(fs, f); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+// ApplyConfig sets flags of |fs| from configuration read from |r|. Each line
+// of configuration is either blank, a "#" comment, or a `name = value`
+// assignment of a defined flag. Values may be double-quoted, and the
+// configuration written by DumpConfig is thus a valid (flat) TOML document.
+// Unknown flags and invalid values are errors. Flags which were already set
+// (eg, parsed from the command line) are left unchanged, so that they take
+// precedence over the configuration.
+func ApplyConfig(fs *flag.FlagSet, r io.Reader) error {
+	var scanner = bufio.NewScanner(r)
+
+	var set = make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		var line = strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var ind = strings.IndexByte(line, '=')
+		if ind == -1 {
+			return fmt.Errorf("line %d: expected `name = value`", lineNo)
+		}
+		var name = strings.TrimSpace(line[:ind])
+		var value = strings.TrimSpace(line[ind+1:])
+
+		if fs.Lookup(name) == nil || configFlags[name] {
+			return fmt.Errorf("line %d: unknown flag %q", lineNo, name)
+		}
+		if strings.HasPrefix(value, `"`) {
+			var err error
+			if value, err = strconv.Unquote(value); err != nil {
+				return fmt.Errorf("line %d: invalid quoted value of %q: %s", lineNo, name, err)
+			}
+		}
+		if set[name] {
+			continue
+		} else if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("line %d: invalid value of %q: %s", lineNo, name, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// DumpConfig writes the current value of each flag of |fs| to |w|, as
+// configuration which may be read by ApplyConfig.
+func DumpConfig(fs *flag.FlagSet, w io.Writer) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err == nil && !configFlags[f.Name] {
+			_, err = fmt.Fprintf(w, "# %s\n%s = %s\n", f.Usage, f.Name, strconv.Quote(f.Value.String()))
+		}
+	})
+	return err
+}
@@ -0,0 +1,97 @@
+package mainboilerplate
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	gc "github.com/go-check/check"
+)
+
+type ConfigSuite struct{}
+
+func (s *ConfigSuite) TestApplyAndDump(c *gc.C) {
+	var fs, str, num, dur = newTestFlagSet()
+
+	c.Check(ApplyConfig(fs, strings.NewReader(`
+# A comment.
+str = "hello = world"
+num=42
+
+dur = 1m30s
+`)), gc.IsNil)
+
+	c.Check(*str, gc.Equals, "hello = world")
+	c.Check(*num, gc.Equals, 42)
+	c.Check(*dur, gc.Equals, 90*time.Second)
+
+	// Dumped configuration round-trips.
+	var buf bytes.Buffer
+	c.Check(DumpConfig(fs, &buf), gc.IsNil)
+	c.Check(buf.String(), gc.Equals, `# A duration
+dur = "1m30s"
+# A number
+num = "42"
+# A string
+str = "hello = world"
+`)
+
+	fs, str, num, dur = newTestFlagSet()
+	c.Check(ApplyConfig(fs, &buf), gc.IsNil)
+	c.Check(*str, gc.Equals, "hello = world")
+	c.Check(*num, gc.Equals, 42)
+	c.Check(*dur, gc.Equals, 90*time.Second)
+}
+
+func (s *ConfigSuite) TestValidation(c *gc.C) {
+	var fs, _, _, _ = newTestFlagSet()
+
+	for _, tc := range []struct {
+		config, expect string
+	}{
+		{"# Comment\nstr", "line 2: expected `name = value`"},
+		{"other = 1", `line 1: unknown flag "other"`},
+		{"flagsFile = foo", `line 1: unknown flag "flagsFile"`},
+		{`str = "unterminated`, `line 1: invalid quoted value of "str": .*`},
+		{"num = nan", `line 1: invalid value of "num": .*`},
+	} {
+		c.Check(ApplyConfig(fs, strings.NewReader(tc.config)), gc.ErrorMatches, tc.expect)
+	}
+}
+
+func (s *ConfigSuite) TestCommandLinePrecedence(c *gc.C) {
+	var fs, str, num, _ = newTestFlagSet()
+	var list appendingValue
+	fs.Var(&list, "list", "A repeatable flag")
+
+	c.Check(fs.Parse([]string{"-str", "cli", "-list", "a", "-list", "b"}), gc.IsNil)
+	c.Check(ApplyConfig(fs, strings.NewReader("str = file\nnum = 42\nlist = c\n")), gc.IsNil)
+
+	// Flags set on the command line are unchanged, and not applied twice.
+	c.Check(*str, gc.Equals, "cli")
+	c.Check(list, gc.DeepEquals, appendingValue{"a", "b"})
+	c.Check(*num, gc.Equals, 42)
+}
+
+// appendingValue is a flag.Value which appends each Set value.
+type appendingValue []string
+
+func (v *appendingValue) String() string     { return strings.Join(*v, ",") }
+func (v *appendingValue) Set(s string) error { *v = append(*v, s); return nil }
+
+func newTestFlagSet() (*flag.FlagSet, *string, *int, *time.Duration) {
+	var fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	var str = fs.String("str", "", "A string")
+	var num = fs.Int("num", 0, "A number")
+	var dur = fs.Duration("dur", 0, "A duration")
+	fs.String("flagsFile", "", "Not dumped")
+	fs.Bool("dumpFlags", false, "Not dumped")
+
+	return fs, str, num, dur
+}
+
+var _ = gc.Suite(&ConfigSuite{})
+
+func Test(t *testing.T) { gc.TestingT(t) }
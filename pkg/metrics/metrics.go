@@ -7,15 +7,39 @@ import "github.com/prometheus/client_golang/prometheus"
 
 // Keys for gazette metrics.
 const (
+	AllocatorActionFailuresTotalKey   = "gazette_allocator_action_failures_total"
+	AllocatorActionsTotalKey          = "gazette_allocator_actions_total"
+	AllocatorWrittenBytesTotalKey     = "gazette_allocator_written_bytes_total"
 	CoalescedAppendsTotalKey          = "gazette_coalesced_appends_total"
 	CommittedBytesTotalKey            = "gazette_committed_bytes_total"
+	DeduplicatedAppendsTotalKey       = "gazette_deduplicated_appends_total"
 	FailedCommitsTotalKey             = "gazette_failed_commits_total"
+	IdleJournalsKey                   = "gazette_idle_journals"
+	IndexCompactedFragmentsTotalKey   = "gazette_index_compacted_fragments_total"
+	IndexedFragmentsKey               = "gazette_indexed_fragments"
+	IndexRefreshDurationSecondsKey    = "gazette_index_refresh_duration_seconds"
+	IndexRefreshFailuresTotalKey      = "gazette_index_refresh_failures_total"
+	IndexRefreshIntervalSecondsKey    = "gazette_index_refresh_interval_seconds"
 	ItemRouteDurationSecondsKey       = "gazette_item_route_duration_seconds"
+	PersisterReadOnlyKey              = "gazette_persister_read_only"
+	QuarantinedItemsKey               = "gazette_quarantined_items"
 	RecoveryLogRecoveredBytesTotalKey = "gazette_recoverylog_recovered_bytes_total"
 )
 
 // Collectors for gazette metrics.
 var (
+	AllocatorActionFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: AllocatorActionFailuresTotalKey,
+		Help: "Cumulative number of failed allocator Etcd actions, by reason (conflict or error).",
+	}, []string{"reason"})
+	AllocatorActionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: AllocatorActionsTotalKey,
+		Help: "Cumulative number of applied allocator Etcd actions, by kind.",
+	}, []string{"kind"})
+	AllocatorWrittenBytesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: AllocatorWrittenBytesTotalKey,
+		Help: "Cumulative number of bytes of member and item keys and values written by the allocator.",
+	})
 	CoalescedAppendsTotal = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: CoalescedAppendsTotalKey,
 		Help: "Number of journal append requests bundled into a single write transaction.",
@@ -24,14 +48,51 @@ var (
 		Name: CommittedBytesTotalKey,
 		Help: "Cumulative number of bytes committed.",
 	})
+	DeduplicatedAppendsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: DeduplicatedAppendsTotalKey,
+		Help: "Cumulative number of appends recognized as retries of committed appends, and not re-appended.",
+	})
 	FailedCommitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: FailedCommitsTotalKey,
 		Help: "Cumulative number of failed commits.",
 	})
+	IdleJournals = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: IdleJournalsKey,
+		Help: "Number of locally brokered journals which haven't served an append within the idle threshold.",
+	})
+	IndexCompactedFragmentsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: IndexCompactedFragmentsTotalKey,
+		Help: "Cumulative number of fragments compacted out of journal indexes.",
+	})
+	IndexedFragments = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: IndexedFragmentsKey,
+		Help: "Number of fragments indexed across all served journals.",
+	})
+	IndexRefreshDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: IndexRefreshDurationSecondsKey,
+		Help: "Duration of journal index refreshes, which list the fragment store.",
+	})
+	IndexRefreshFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: IndexRefreshFailuresTotalKey,
+		Help: "Cumulative number of failed journal index refreshes.",
+	})
+	IndexRefreshIntervalSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    IndexRefreshIntervalSecondsKey,
+		Help:    "Staleness of journal indexes: the interval between successful refreshes of an index.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 16),
+	})
 	ItemRouteDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name: ItemRouteDurationSecondsKey,
 		Help: "Benchmarking of Runner.ItemRoute calls.",
 	})
+	PersisterReadOnly = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: PersisterReadOnlyKey,
+		Help: "Whether the broker rejects appends (1) because spool persistence is behind its disk budget.",
+	})
+	QuarantinedItems = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: QuarantinedItemsKey,
+		Help: "Number of allocator items which cannot be decoded, and are not brokered.",
+	})
 	RecoveryLogRecoveredBytesTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: RecoveryLogRecoveredBytesTotalKey,
 		Help: "Cumulative number of bytes recovered.",
@@ -40,10 +101,22 @@ var (
 
 func GazetteCollectors() []prometheus.Collector {
 	return []prometheus.Collector{
+		AllocatorActionFailuresTotal,
+		AllocatorActionsTotal,
+		AllocatorWrittenBytesTotal,
 		CoalescedAppendsTotal,
 		CommittedBytesTotal,
+		DeduplicatedAppendsTotal,
 		FailedCommitsTotal,
+		IdleJournals,
+		IndexCompactedFragmentsTotal,
+		IndexedFragments,
+		IndexRefreshDurationSeconds,
+		IndexRefreshFailuresTotal,
+		IndexRefreshIntervalSeconds,
 		ItemRouteDurationSeconds,
+		PersisterReadOnly,
+		QuarantinedItems,
 		RecoveryLogRecoveredBytesTotal,
 	}
 }
@@ -105,11 +178,21 @@ func GazretentionCollectors() []prometheus.Collector {
 // Keys for gazette.Client and gazette.WriteService metrics.
 const (
 	GazetteDiscardBytesTotalKey         = "gazette_discard_bytes_total"
+	GazetteFragmentCacheReadsTotalKey   = "gazette_fragment_cache_reads_total"
+	GazetteHedgedReadsTotalKey          = "gazette_hedged_reads_total"
 	GazetteReadBytesTotalKey            = "gazette_read_bytes_total"
 	GazetteWriteBytesTotalKey           = "gazette_write_bytes_total"
 	GazetteWriteCountTotalKey           = "gazette_write_count_total"
 	GazetteWriteDurationSecondsTotalKey = "gazette_write_duration_seconds_total"
 	GazetteWriteFailureTotalKey         = "gazette_write_failure_total"
+	GazetteWriteSpooledBytesKey         = "gazette_write_spooled_bytes"
+	GazetteWriteSpooledWritesKey        = "gazette_write_spooled_writes"
+	GazetteWriteBlockedTotalKey         = "gazette_write_blocked_total"
+
+	GazetteWriteJournalBytesTotalKey      = "gazette_write_journal_bytes_total"
+	GazetteWriteJournalDurationSecondsKey = "gazette_write_journal_duration_seconds"
+	GazetteWriteJournalRetriesTotalKey    = "gazette_write_journal_retries_total"
+	GazetteWriteJournalSpooledBytesKey    = "gazette_write_journal_spooled_bytes"
 )
 
 // Collectors for gazette.Client and gazette.WriteService metrics.
@@ -119,6 +202,14 @@ var (
 		Name: GazetteDiscardBytesTotalKey,
 		Help: "Cumulative number of bytes read but discarded.",
 	})
+	GazetteFragmentCacheReadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: GazetteFragmentCacheReadsTotalKey,
+		Help: "Cumulative number of persisted fragment reads, by whether the local fragment cache was hit.",
+	}, []string{"result"})
+	GazetteHedgedReadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: GazetteHedgedReadsTotalKey,
+		Help: "Cumulative number of hedged reads, by the attempt (primary or hedge) which won.",
+	}, []string{"winner"})
 	GazetteReadBytesTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: GazetteReadBytesTotalKey,
 		Help: "Cumulative number of bytes read.",
@@ -139,6 +230,37 @@ var (
 		Name: GazetteWriteFailureTotalKey,
 		Help: "Cumulative number of write errors returned to clients.",
 	})
+	GazetteWriteSpooledBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: GazetteWriteSpooledBytesKey,
+		Help: "Number of bytes spooled but not yet committed.",
+	})
+	GazetteWriteSpooledWrites = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: GazetteWriteSpooledWritesKey,
+		Help: "Number of spooled writes not yet committed.",
+	})
+	GazetteWriteBlockedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: GazetteWriteBlockedTotalKey,
+		Help: "Cumulative number of writes which blocked or failed due to exceeded spool budgets.",
+	})
+
+	// Journal-labeled metrics are exported only if enabled by
+	// gazette.WriteService.SetJournalMetrics.
+	GazetteWriteJournalBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: GazetteWriteJournalBytesTotalKey,
+		Help: "Cumulative number of bytes written, by journal.",
+	}, []string{"journal"})
+	GazetteWriteJournalDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: GazetteWriteJournalDurationSecondsKey,
+		Help: "Latency of writes from spooling through commit, by journal.",
+	}, []string{"journal"})
+	GazetteWriteJournalRetriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: GazetteWriteJournalRetriesTotalKey,
+		Help: "Cumulative number of retried write attempts, by journal.",
+	}, []string{"journal"})
+	GazetteWriteJournalSpooledBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: GazetteWriteJournalSpooledBytesKey,
+		Help: "Number of bytes spooled but not yet committed, by journal.",
+	}, []string{"journal"})
 )
 
 // GazetteClientCollectors returns the metrics used by gazette.Client and
@@ -146,10 +268,20 @@ var (
 func GazetteClientCollectors() []prometheus.Collector {
 	return []prometheus.Collector{
 		GazetteDiscardBytesTotal,
+		GazetteFragmentCacheReadsTotal,
+		GazetteHedgedReadsTotal,
 		GazetteReadBytesTotal,
 		GazetteWriteBytesTotal,
 		GazetteWriteCountTotal,
 		GazetteWriteDurationTotal,
+		GazetteWriteFailureTotal,
+		GazetteWriteSpooledBytes,
+		GazetteWriteSpooledWrites,
+		GazetteWriteBlockedTotal,
+		GazetteWriteJournalBytesTotal,
+		GazetteWriteJournalDurationSeconds,
+		GazetteWriteJournalRetriesTotal,
+		GazetteWriteJournalSpooledBytes,
 	}
 }
 
@@ -0,0 +1,205 @@
+package topic
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"time"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+// ReadIterator decodes Envelopes of a topic's Messages from a journal reader.
+type ReadIterator struct {
+	desc *Description
+	r    markedReader
+	br   *bufio.Reader
+}
+
+// markedReader is a journal reader which tracks its Mark, such as a
+// *journal.MarkedReader or *journal.RetryReader.
+type markedReader interface {
+	io.Reader
+	AdjustedMark(*bufio.Reader) journal.Mark
+}
+
+// NewReadIterator returns a ReadIterator of |desc| Messages read from |r|,
+// which is typically a *journal.RetryReader.
+func NewReadIterator(desc *Description, r markedReader) *ReadIterator {
+	return &ReadIterator{
+		desc: desc,
+		r:    r,
+		br:   bufio.NewReader(r),
+	}
+}
+
+// Next returns the next Envelope of the journal. Envelope.Mark is the offset
+// immediately following the Message. Errors of the underlying reader or of
+// the Framing are returned, as are Message decoding errors (in which case the
+// Envelope Mark is still valid). Iteration may continue after an error, if
+// the underlying reader is able.
+func (it *ReadIterator) Next() (Envelope, error) {
+	var frame, err = it.desc.Framing.Unpack(it.br)
+	if err != nil {
+		return Envelope{Topic: it.desc, Mark: it.r.AdjustedMark(it.br)}, err
+	}
+
+	var msg = it.desc.GetMessage()
+	if err = it.desc.Framing.Unmarshal(frame, msg); err != nil {
+		if it.desc.PutMessage != nil {
+			it.desc.PutMessage(msg)
+		}
+		msg = nil
+	}
+	return Envelope{Topic: it.desc, Mark: it.r.AdjustedMark(it.br), Message: msg}, err
+}
+
+// MergeIterator merges the Envelopes of multiple ReadIterators, typically of
+// each partition of a topic, into a single sequence. Partitions are read
+// round-robin or, if a clock is provided, in order of message clock.
+//
+// A ReadIterator is exhausted once it returns io.EOF or
+// journal.ErrNotYetAvailable, and MergeIterator returns io.EOF once all are.
+// Underlying readers are thus typically non-blocking RetryReaders. A blocking
+// reader instead stalls the MergeIterator until its partition is written.
+type MergeIterator struct {
+	its   []*ReadIterator
+	clock func(Envelope) time.Time
+
+	// Next Envelope of each ReadIterator, if already read.
+	heads []*Envelope
+	// Whether each ReadIterator is exhausted.
+	done []bool
+	// Index of the ReadIterator next read in round-robin order.
+	next int
+}
+
+// NewMergeIterator returns a MergeIterator of |its|. If |clock| is nil,
+// Envelopes are returned round-robin from each of |its|. Otherwise, a Message
+// is read ahead from each of |its|, and the one having the least |clock| is
+// returned (or the first of |its|, on a tie). If partitions are each written
+// in clock order, Envelopes of the MergeIterator are as well.
+func NewMergeIterator(clock func(Envelope) time.Time, its ...*ReadIterator) *MergeIterator {
+	return &MergeIterator{
+		its:   its,
+		clock: clock,
+		heads: make([]*Envelope, len(its)),
+		done:  make([]bool, len(its)),
+	}
+}
+
+// Next returns the next Envelope of the merged ReadIterators. Errors of a
+// ReadIterator (other than of exhaustion) are returned as they're read,
+// along with the Envelope of the error. Iteration may continue after an
+// error, and the failed ReadIterator is read again by the next call.
+func (m *MergeIterator) Next() (Envelope, error) {
+	if m.clock == nil {
+		return m.nextRoundRobin()
+	}
+	return m.nextOrdered()
+}
+
+func (m *MergeIterator) nextRoundRobin() (Envelope, error) {
+	for range m.its {
+		var ind = m.next
+		m.next = (m.next + 1) % len(m.its)
+
+		if m.done[ind] {
+			continue
+		}
+		var env, err = m.its[ind].Next()
+
+		if isExhausted(err) {
+			m.done[ind] = true
+			continue
+		}
+		return env, err
+	}
+	return Envelope{}, io.EOF
+}
+
+func (m *MergeIterator) nextOrdered() (Envelope, error) {
+	var min = -1
+
+	for ind, it := range m.its {
+		if m.heads[ind] == nil && !m.done[ind] {
+			var env, err = it.Next()
+
+			if isExhausted(err) {
+				m.done[ind] = true
+				continue
+			} else if err != nil {
+				return env, err
+			}
+			m.heads[ind] = &env
+		}
+
+		if m.heads[ind] == nil {
+			continue
+		} else if min == -1 || m.clock(*m.heads[ind]).Before(m.clock(*m.heads[min])) {
+			min = ind
+		}
+	}
+
+	if min == -1 {
+		return Envelope{}, io.EOF
+	}
+	var env = *m.heads[min]
+	m.heads[min] = nil
+	return env, nil
+}
+
+// isExhausted returns whether |err| indicates a ReadIterator has no further
+// available Messages.
+func isExhausted(err error) bool {
+	return err == io.EOF || err == journal.ErrNotYetAvailable
+}
+
+// ReadRange reads and decodes up to |max| Envelopes of |desc| Messages from
+// the journal of |mark|, beginning at |mark| and ending at offset |end|. It
+// returns the decoded Envelopes and the Mark from which reading should resume.
+// A Message which straddles |end| is not returned, and is instead read by a
+// ReadRange resuming from the returned Mark. |end| must not exceed the write
+// head of the journal, as ReadRange blocks awaiting content through |end|. If
+// an error occurs, Envelopes decoded prior to the error are returned along with
+// it, and the returned Mark is that reported by ReadIterator.Next with the
+// error (for a Message decoding error, the Mark following the Message).
+func ReadRange(ctx context.Context, getter journal.Getter, desc *Description,
+	mark journal.Mark, end int64, max int) ([]Envelope, journal.Mark, error) {
+
+	var rr = journal.NewRetryReaderContext(ctx, mark, getter)
+	defer rr.Close()
+
+	var it = NewReadIterator(desc, &rangeReader{RetryReader: rr, remaining: end - mark.Offset})
+	var out []Envelope
+
+	for len(out) < max {
+		var env, err = it.Next()
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break // Reached |end|.
+		} else if err != nil {
+			return out, env.Mark, err
+		}
+		out = append(out, env)
+		mark = env.Mark
+	}
+	return out, mark, nil
+}
+
+// rangeReader reads from a RetryReader through a bounded number of bytes.
+type rangeReader struct {
+	*journal.RetryReader
+	remaining int64
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	} else if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	var n, err = r.RetryReader.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
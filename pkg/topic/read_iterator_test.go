@@ -0,0 +1,191 @@
+package topic
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"time"
+
+	gc "github.com/go-check/check"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/LiveRamp/gazette/pkg/journal"
+)
+
+type ReadIteratorSuite struct{}
+
+type iterTestMessage struct {
+	A int
+}
+
+func (s *ReadIteratorSuite) TestIteration(c *gc.C) {
+	var desc = &Description{
+		Name:       "a/topic",
+		GetMessage: func() Message { return new(iterTestMessage) },
+		Framing:    JsonFraming,
+	}
+
+	var buf, err = JsonFraming.Encode(iterTestMessage{A: 1}, nil)
+	c.Assert(err, gc.IsNil)
+	buf = append(buf, []byte("{\"A\": \"not-a-number\"}\n")...)
+	buf, err = JsonFraming.Encode(iterTestMessage{A: 3}, buf)
+	c.Assert(err, gc.IsNil)
+
+	var mr = journal.NewMarkedReader(journal.Mark{Journal: "a/topic/part-000", Offset: 100},
+		ioutil.NopCloser(bytes.NewReader(buf)))
+	var it = NewReadIterator(desc, mr)
+
+	env, err := it.Next()
+	c.Check(err, gc.IsNil)
+	c.Check(env.Message, gc.DeepEquals, &iterTestMessage{A: 1})
+	c.Check(env.Mark, gc.Equals, journal.Mark{Journal: "a/topic/part-000", Offset: 108})
+	c.Check(env.Topic, gc.Equals, desc)
+
+	// A message decoding error is returned, with the Mark of the next message.
+	env, err = it.Next()
+	c.Check(err, gc.NotNil)
+	c.Check(env.Message, gc.IsNil)
+	c.Check(env.Mark, gc.Equals, journal.Mark{Journal: "a/topic/part-000", Offset: 130})
+
+	// Iteration continues.
+	env, err = it.Next()
+	c.Check(err, gc.IsNil)
+	c.Check(env.Message, gc.DeepEquals, &iterTestMessage{A: 3})
+	c.Check(env.Mark, gc.Equals, journal.Mark{Journal: "a/topic/part-000", Offset: 138})
+
+	_, err = it.Next()
+	c.Check(err, gc.Equals, io.EOF)
+}
+
+func (s *ReadIteratorSuite) TestReadRange(c *gc.C) {
+	var desc = &Description{
+		Name:       "a/topic",
+		GetMessage: func() Message { return new(iterTestMessage) },
+		Framing:    JsonFraming,
+	}
+	var buf []byte
+	for i := 1; i <= 4; i++ {
+		buf, _ = JsonFraming.Encode(iterTestMessage{A: i}, buf)
+	}
+	buf = append(buf, []byte("{\"A\": \"not-a-number\"}\n")...)
+	buf, _ = JsonFraming.Encode(iterTestMessage{A: 6}, buf) // Through offset 138.
+
+	var getter = contentGetter(buf)
+	var ctx = context.Background()
+	var mark = journal.Mark{Journal: "a/topic/part-000", Offset: 0}
+
+	// Read a batch bounded by |max|.
+	envs, next, err := ReadRange(ctx, getter, desc, mark, int64(len(buf)), 2)
+	c.Check(err, gc.IsNil)
+	c.Check(envs, gc.HasLen, 2)
+	c.Check(envs[1].Message, gc.DeepEquals, &iterTestMessage{A: 2})
+	c.Check(next.Offset, gc.Equals, int64(16))
+
+	// Read a batch bounded by |end|, which splits the fourth message.
+	envs, next, err = ReadRange(ctx, getter, desc, next, 28, 10)
+	c.Check(err, gc.IsNil)
+	c.Check(envs, gc.HasLen, 1)
+	c.Check(envs[0].Message, gc.DeepEquals, &iterTestMessage{A: 3})
+	c.Check(next.Offset, gc.Equals, int64(24))
+
+	// A decoding error is returned with the Mark following the bad message.
+	envs, next, err = ReadRange(ctx, getter, desc, next, int64(len(buf)), 10)
+	c.Check(err, gc.NotNil)
+	c.Check(envs, gc.HasLen, 1)
+	c.Check(envs[0].Message, gc.DeepEquals, &iterTestMessage{A: 4})
+	c.Check(next.Offset, gc.Equals, int64(54))
+
+	// Reading resumes, through the end of the range.
+	envs, next, err = ReadRange(ctx, getter, desc, next, int64(len(buf)), 10)
+	c.Check(err, gc.IsNil)
+	c.Check(envs, gc.HasLen, 1)
+	c.Check(envs[0].Message, gc.DeepEquals, &iterTestMessage{A: 6})
+	c.Check(next.Offset, gc.Equals, int64(len(buf)))
+
+	// An empty range returns no Envelopes.
+	envs, next, err = ReadRange(ctx, getter, desc, next, int64(len(buf)), 10)
+	c.Check(err, gc.IsNil)
+	c.Check(envs, gc.HasLen, 0)
+	c.Check(next.Offset, gc.Equals, int64(len(buf)))
+}
+
+func (s *ReadIteratorSuite) TestMergeIterator(c *gc.C) {
+	var desc = &Description{
+		Name:       "a/topic",
+		GetMessage: func() Message { return new(iterTestMessage) },
+		Framing:    JsonFraming,
+	}
+	// partition returns a ReadIterator of Messages |values|. A negative value
+	// is written as a Message which fails to decode.
+	var partition = func(name journal.Name, values ...int) *ReadIterator {
+		var buf []byte
+		for _, v := range values {
+			if v < 0 {
+				buf = append(buf, []byte("{\"A\": \"not-a-number\"}\n")...)
+			} else {
+				buf, _ = JsonFraming.Encode(iterTestMessage{A: v}, buf)
+			}
+		}
+		return NewReadIterator(desc, journal.NewMarkedReader(journal.Mark{Journal: name},
+			ioutil.NopCloser(bytes.NewReader(buf))))
+	}
+	var clock = func(env Envelope) time.Time {
+		return time.Unix(int64(env.Message.(*iterTestMessage).A), 0)
+	}
+	// drain returns the Message values of |it|, and -1 for each error.
+	var drain = func(it *MergeIterator) (out []int) {
+		for {
+			var env, err = it.Next()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				out = append(out, -1)
+			} else {
+				out = append(out, env.Message.(*iterTestMessage).A)
+			}
+		}
+	}
+
+	// Without a clock, partitions are read round-robin until exhausted.
+	c.Check(drain(NewMergeIterator(nil,
+		partition("a/topic/part-000", 1, 4, 5, 6),
+		partition("a/topic/part-001"),
+		partition("a/topic/part-002", 2, 3),
+	)), gc.DeepEquals, []int{1, 2, 4, 3, 5, 6})
+
+	// With a clock, Messages are merged in clock order. Ties are returned in
+	// partition order, and decoding errors are returned as they're read.
+	c.Check(drain(NewMergeIterator(clock,
+		partition("a/topic/part-000", 1, 4, 5, 6),
+		partition("a/topic/part-001"),
+		partition("a/topic/part-002", 2, 4, -1, 7),
+	)), gc.DeepEquals, []int{1, 2, 4, 4, -1, 5, 6, 7})
+
+	// A partition which isn't yet available is exhausted.
+	var getter = new(journal.MockGetter)
+	getter.On("Get", mock.Anything).Return(journal.ReadResult{Error: journal.ErrNotYetAvailable},
+		ioutil.NopCloser(nil))
+
+	var rr = journal.NewRetryReaderContext(context.Background(),
+		journal.Mark{Journal: "a/topic/part-001"}, getter)
+	rr.Blocking = false
+
+	c.Check(drain(NewMergeIterator(clock,
+		partition("a/topic/part-000", 1, 2),
+		NewReadIterator(desc, rr),
+	)), gc.DeepEquals, []int{1, 2})
+
+	// An empty MergeIterator is exhausted.
+	c.Check(drain(NewMergeIterator(nil)), gc.HasLen, 0)
+}
+
+// contentGetter is a journal.Getter of fixed journal content.
+type contentGetter []byte
+
+func (g contentGetter) Get(args journal.ReadArgs) (journal.ReadResult, io.ReadCloser) {
+	return journal.ReadResult{Offset: args.Offset, WriteHead: int64(len(g))},
+		ioutil.NopCloser(bytes.NewReader(g[args.Offset:]))
+}
+
+var _ = gc.Suite(&ReadIteratorSuite{})